@@ -105,6 +105,8 @@ app.get('/org1/cadastral-map', authenticateJWT, checkOrg(['Org1']), (req, res) =
 
 // Org1 on-chain GeoJSON endpoint (gzipped)
 app.get('/api/org1/map/geojson', authenticateJWT, checkOrg(['Org1']), mapService.getOrg1CadastralGeoJSON);
+app.get('/api/map/parcels/bbox', authenticateJWT, mapService.getParcelsInBoundingBox);
+app.get('/api/map/parcels/near', authenticateJWT, mapService.getParcelsNearPoint);
 
 // MongoDB Connection
 mongoose.connect(process.env.MONGO_URI, {
@@ -152,6 +154,9 @@ app.get('/api/land-parcels/owner/:ownerID', authenticateJWT, landService.getLand
 app.get('/api/land-parcels', authenticateJWT, checkOrg(['Org1', 'Org2']), landService.getAllLandParcels);
 app.get('/api/land-parcels/:id', authenticateJWT, landService.getLandParcel);
 app.get('/api/land-parcels/:id/history', authenticateJWT, landService.getLandParcelHistory);
+app.get('/api/land-parcels/:id/lock', authenticateJWT, landService.getLandParcelLock);
+app.post('/api/land-parcels/:id/verify-geometry', authenticateJWT, landService.verifyLandGeometry);
+app.put('/api/land-parcels/:id/geometry', authenticateJWT, checkOrg(['Org1']), landService.registerLandGeometry);
 
 // Document Routes
 app.post('/api/documents', authenticateJWT, documentService.createDocument);
@@ -170,6 +175,7 @@ app.get('/api/documents', authenticateJWT, checkOrg(['Org1', 'Org2']), documentS
 app.get('/api/documents/:docID/analyze', authenticateJWT, checkOrg(['Org1', 'Org2']), documentService.analyzeDocument);
 app.get('/api/documents/:docID', authenticateJWT, documentService.getDocument);
 app.put('/api/documents/:docID', authenticateJWT, documentService.updateDocument);
+app.put('/api/documents/:docID/sub-type', authenticateJWT, documentService.setDocumentSubType);
 app.delete('/api/documents/:docID', authenticateJWT, documentService.deleteDocument);
 app.post('/api/documents/:docID/verify', authenticateJWT, checkOrg(['Org2']), documentService.verifyDocument);
 app.post('/api/documents/:docID/reject', authenticateJWT, checkOrg(['Org2']), documentService.rejectDocument);
@@ -178,18 +184,28 @@ app.post('/api/documents/:docID/reject', authenticateJWT, checkOrg(['Org2']), do
 app.post('/api/transactions/:txID/process', authenticateJWT, checkOrg(['Org2']), transactionService.processTransaction);
 app.post('/api/transactions/transfer', authenticateJWT, checkOrg(['Org3']), transactionService.createTransferRequest);
 app.post('/api/transactions/confirm', authenticateJWT, checkOrg(['Org3']), transactionService.confirmTransfer);
+app.post('/api/transactions/:txID/consent', authenticateJWT, checkOrg(['Org3']), transactionService.consentToTransaction);
 app.post('/api/transactions/split', authenticateJWT, checkOrg(['Org3']), transactionService.createSplitRequest);
 app.post('/api/transactions/merge', authenticateJWT, checkOrg(['Org3']), transactionService.createMergeRequest);
 app.post('/api/transactions/change-purpose', authenticateJWT, checkOrg(['Org3']), transactionService.createChangePurposeRequest);
 app.post('/api/transactions/reissue', authenticateJWT, checkOrg(['Org3']), transactionService.createReissueRequest);
+app.post('/api/transactions/inheritance', authenticateJWT, checkOrg(['Org2', 'Org3']), transactionService.createInheritanceRequest);
+app.post('/api/transactions/gift', authenticateJWT, checkOrg(['Org3']), transactionService.createGiftRequest);
+app.get('/api/transactions/gift/relationships', authenticateJWT, transactionService.getGiftRelationships);
 app.post('/api/transactions/:txID/approve/transfer', authenticateJWT, checkOrg(['Org1']), transactionService.approveTransferTransaction);
 app.post('/api/transactions/:txID/approve/split', authenticateJWT, checkOrg(['Org1']), transactionService.approveSplitTransaction);
+app.post('/api/transactions/:txID/validate/split', authenticateJWT, checkOrg(['Org1', 'Org2']), transactionService.validateSplitTransaction);
 app.post('/api/transactions/:txID/approve/merge', authenticateJWT, checkOrg(['Org1']), transactionService.approveMergeTransaction);
 app.post('/api/transactions/:txID/approve/change-purpose', authenticateJWT, checkOrg(['Org1']), transactionService.approveChangePurposeTransaction);
 app.post('/api/transactions/:txID/approve/reissue', authenticateJWT, checkOrg(['Org1']), transactionService.approveReissueTransaction);
+app.post('/api/transactions/:txID/approve/inheritance', authenticateJWT, checkOrg(['Org1']), transactionService.approveInheritanceTransaction);
+app.post('/api/transactions/:txID/approve/gift', authenticateJWT, checkOrg(['Org1']), transactionService.approveGiftTransaction);
 app.post('/api/transactions/:txID/reject', authenticateJWT, checkOrg(['Org1']), transactionService.rejectTransaction);
+app.post('/api/transactions/:txID/withdraw', authenticateJWT, checkOrg(['Org2', 'Org3']), transactionService.withdrawTransaction);
 app.get('/api/transactions/search', authenticateJWT, transactionService.searchTransactions);
 app.get('/api/transactions/status/:status', authenticateJWT, checkOrg(['Org1', 'Org2']), transactionService.getTransactionsByStatus);
+app.get('/api/transactions/overdue', authenticateJWT, checkOrg(['Org1', 'Org2']), transactionService.getOverdueTransactions);
+app.post('/api/transactions/sweep-expired', authenticateJWT, checkOrg(['Org1', 'Org2']), transactionService.sweepExpiredTransactions);
 app.get('/api/transactions/land-parcel/:landParcelID', authenticateJWT, transactionService.getTransactionsByLandParcel);
 app.get('/api/transactions/owner/:ownerID', authenticateJWT, transactionService.getTransactionsByOwner);
 app.get('/api/transactions/:txID/history', authenticateJWT, transactionService.getTransactionHistory);
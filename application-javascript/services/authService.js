@@ -10,6 +10,7 @@ const FabricCAServices = require('fabric-ca-client');
 const { buildCAClient, registerAndEnrollUser, enrollAdmin } = require('../enroll/CAUtil.js');
 const { buildCCPOrg1, buildCCPOrg2, buildCCPOrg3, buildWallet } = require('../enroll/AppUtil.js');
 const path = require('path');
+const { resolveSubjectRef } = require('./networkService');
 require('dotenv').config({ path: require('path').resolve(__dirname, '../.env') });
 
 const jwtSecret = process.env.JWT_SECRET;
@@ -318,8 +319,10 @@ const login = async (req, res) => {
         if (!await user.comparePassword(sanitizeInput(password))) {
             return res.status(401).json({ error: 'Thông tin đăng nhập không hợp lệ' });
         }
+        const subjectRef = await resolveSubjectRef(user.org, user.cccd);
         const tokenPayload = {
             cccd: user.cccd,
+            subjectRef,
             org: user.org,
             role: user.role,
             name: user.fullName,
@@ -331,6 +334,7 @@ const login = async (req, res) => {
             token,
             user: {
                 cccd: user.cccd,
+                subjectRef,
                 fullName: user.fullName,
                 phone: user.phone,
                 org: user.org,
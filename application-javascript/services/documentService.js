@@ -26,7 +26,7 @@ const documentService = {
     // Create document
     async createDocument(req, res) {
         try {
-            const { docID, docType, title, description, ipfsHash, fileType, fileSize, status } = req.body;
+            const { docID, docType, subType, title, description, ipfsHash, contentSha256, fileType, fileSize, status } = req.body;
             const userID = req.user.cccd;
             const org = req.user.org;
 
@@ -54,9 +54,11 @@ const documentService = {
                 'CreateDocument',
                 finalDocID,
                 docType,
+                subType || '',
                 title,
                 finalDescription,
                 ipfsHash,
+                contentSha256 || '',
                 fileType,
                 fileSize || 0,
                 documentStatus,
@@ -258,6 +260,47 @@ const documentService = {
         }
     },
 
+    // Set document sub type (uploader or Org2)
+    async setDocumentSubType(req, res) {
+        try {
+            const { docID } = req.params;
+            const { subType } = req.body;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            if (!subType) {
+                return res.status(400).json({
+                    success: false,
+                    message: 'Thiếu mã thành phần hồ sơ (subType)'
+                });
+            }
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            await contract.submitTransaction(
+                'SetDocumentSubType',
+                docID,
+                subType
+            );
+
+            res.json({
+                success: true,
+                message: 'Đã cập nhật thành phần hồ sơ của tài liệu',
+                data: {
+                    docID,
+                    subType
+                }
+            });
+        } catch (error) {
+            console.error('Error setting document sub type:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi cập nhật thành phần hồ sơ của tài liệu',
+                error: error.message
+            });
+        }
+    },
+
     // Delete document
     async deleteDocument(req, res) {
         try {
@@ -339,7 +382,7 @@ const documentService = {
     async rejectDocument(req, res) {
         try {
             const { docID } = req.params;
-            const { reason } = req.body;
+            const { reasonCode, reason } = req.body;
             const userID = req.user.cccd;
             const org = req.user.org;
 
@@ -348,7 +391,8 @@ const documentService = {
             await contract.submitTransaction(
                 'RejectDocument',
                 docID,
-                reason
+                reasonCode || 'OTHER',
+                reason || ''
             );
 
             res.json({
@@ -358,6 +402,7 @@ const documentService = {
                     docID,
                     rejected: true,
                     rejectedBy: userID,
+                    rejectionReasonCode: reasonCode || 'OTHER',
                     rejectionReason: reason,
                     rejectedAt: new Date().toISOString()
                 }
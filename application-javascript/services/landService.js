@@ -21,7 +21,7 @@ const landService = {
     // Create a new land parcel
     async createLandParcel(req, res) {
         try {
-            const { id, ownerId, location, landUsePurpose, legalStatus, area, certificateId, legalInfo, geometryCID } = req.body;
+            const { id, ownerId, location, landUsePurpose, legalStatus, area, certificateId, legalInfo, geometryCID, geometry } = req.body;
             const userID = req.user.cccd;
             const org = req.user.org;
 
@@ -41,10 +41,11 @@ const landService = {
                 }
             }
 
-            await contract.submitTransaction(
+            // CCCD chủ sử dụng chỉ được truyền qua transient map, không ghi vào block
+            await contract.submitWithTransient(
                 'CreateLandParcel',
+                { ownerId },
                 id,
-                ownerId,
                 location,
                 landUsePurpose,
                 legalStatus,
@@ -52,7 +53,8 @@ const landService = {
                 certificateId || '',
                 legalInfo || '',
                 geometryCID || '',
-                userID
+                // GeoJSON (không bắt buộc) để chaincode kiểm tra diện tích và lưu SHA-256
+                geometry ? (typeof geometry === 'string' ? geometry : JSON.stringify(geometry)) : ''
             );
 
             // Get the created land parcel to return as response data
@@ -88,7 +90,7 @@ const landService = {
     async updateLandParcel(req, res) {
         try {
             const { id } = req.params;
-            const { area, location, landUsePurpose, legalStatus, certificateId, legalInfo, geometryCID } = req.body;
+            const { area, location, landUsePurpose, legalStatus, certificateId, legalInfo, geometryCID, geometry } = req.body;
             const userID = req.user.cccd;
             const org = req.user.org;
 
@@ -129,7 +131,9 @@ const landService = {
                 finalLegalStatus,
                 finalCertificateId,
                 finalLegalInfo,
-                finalGeometryCID
+                finalGeometryCID,
+                // GeoJSON mới (không bắt buộc) để cập nhật SHA-256 và khung bao của thửa đất
+                geometry ? (typeof geometry === 'string' ? geometry : JSON.stringify(geometry)) : ''
             );
 
             // Get the updated land parcel to return as response data
@@ -157,17 +161,17 @@ const landService = {
     // Issue land certificate
     async issueLandCertificate(req, res) {
         try {
-            const { certificateID, landParcelID, ownerID, legalInfo } = req.body;
+            const { certificateID, serialNumber, landParcelID, ownerID, legalInfo } = req.body;
             const userID = req.user.cccd;
             const org = req.user.org;
 
             const { contract } = await connectToNetwork(org, userID);
 
             await contract.submitTransaction(
-                'IssueLandCertificate',
-                certificateID,
+                'IssueCertificate',
+                serialNumber,
                 landParcelID,
-                ownerID,
+                certificateID,
                 legalInfo
             );
 
@@ -355,6 +359,114 @@ const landService = {
                 error: error.message
             });
         }
+    },
+
+    // Get land parcel lock - giao dịch đang giữ khóa thửa đất
+    async getLandParcelLock(req, res) {
+        try {
+            const { id } = req.params;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            const result = await contract.evaluateTransaction(
+                'GetParcelLock',
+                id
+            );
+
+            const lock = JSON.parse(result.toString());
+            res.json({
+                success: true,
+                data: {
+                    ...lock,
+                    locked: Boolean(lock.txId)
+                }
+            });
+        } catch (error) {
+            console.error('Error getting land parcel lock:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi lấy trạng thái khóa thửa đất',
+                error: error.message
+            });
+        }
+    },
+
+    // Đăng ký hình học (SHA-256, khung bao) cho thửa đất đã có (chỉ Org1)
+    // areaOverrideReason cho phép đăng ký khi diện tích kê khai lệch với hình học (dữ liệu cũ chờ đo đạc lại)
+    async registerLandGeometry(req, res) {
+        try {
+            const { id } = req.params;
+            const { geometry, areaOverrideReason } = req.body;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            if (!geometry) {
+                return res.status(400).json({
+                    success: false,
+                    message: 'Hình học GeoJSON là bắt buộc'
+                });
+            }
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            await contract.submitTransaction(
+                'RegisterLandGeometry',
+                id,
+                typeof geometry === 'string' ? geometry : JSON.stringify(geometry),
+                areaOverrideReason || ''
+            );
+
+            res.json({
+                success: true,
+                message: 'Đã đăng ký hình học thửa đất'
+            });
+        } catch (error) {
+            console.error('Error registering land geometry:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi đăng ký hình học thửa đất',
+                error: error.message
+            });
+        }
+    },
+
+    // Đối chiếu hình học (ví dụ nội dung tải từ geometryCID) với SHA-256 và diện tích đã đăng ký
+    async verifyLandGeometry(req, res) {
+        try {
+            const { id } = req.params;
+            const { geometry } = req.body;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            if (!geometry) {
+                return res.status(400).json({
+                    success: false,
+                    message: 'Hình học GeoJSON là bắt buộc'
+                });
+            }
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            const result = await contract.evaluateTransaction(
+                'VerifyLandGeometry',
+                id,
+                typeof geometry === 'string' ? geometry : JSON.stringify(geometry)
+            );
+
+            res.json({
+                success: true,
+                data: JSON.parse(result.toString())
+            });
+        } catch (error) {
+            console.error('Error verifying land geometry:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi đối chiếu hình học thửa đất',
+                error: error.message
+            });
+        }
     }
 };
 
@@ -109,6 +109,64 @@ function transformFeatureToWGS84(feature) {
     return feature;
 }
 
+// Resolve a land's geometry (by CID, falling back to land id) and convert it to WGS84 GeoJSON Features
+function landToFeatures(land, cidMapping) {
+    const geometryCid = land.geometryCid || land.geometryCID || land.GeometryCID || '';
+    let source = null;
+    let gj = resolveGeometryByCid(geometryCid, cidMapping);
+    if (gj) {
+        source = 'cid';
+    } else {
+        // Fallback by land id
+        gj = resolveGeometryByLandId(land.id, cidMapping);
+        if (gj) source = 'id';
+    }
+    if (!gj) return { features: [], source };
+    const props = {
+        id: land.id || '',
+        ownerId: land.ownerID || land.ownerId || '',
+        usePurpose: land.landUsePurpose || land.usePurpose || '',
+        area: land.area !== undefined && land.area !== null ? land.area : null,
+        dc: (land.id && String(land.id).split('-')[0]) || '',
+        location: land.location || land.Location || '',
+        legalStatus: land.legalStatus || land.LegalStatus || '',
+        legalInfo: land.legalInfo || land.LegalInfo || ''
+    };
+    // Normalize input to an array of GeoJSON Features or raw geometries
+    let list;
+    if (Array.isArray(gj.features)) {
+        list = gj.features;
+    } else if (gj && gj.geometry && gj.geometry.type) {
+        // Case: { landId, geometry: { type, coordinates } }
+        list = [{ type: 'Feature', geometry: gj.geometry, properties: {} }];
+    } else if (gj && gj.type && gj.coordinates) {
+        // Bare geometry object
+        list = [{ type: 'Feature', geometry: gj, properties: {} }];
+    } else {
+        list = [gj];
+    }
+    const features = [];
+    for (const feat of list) {
+        if (!feat) continue;
+        const asFeature = feat.type === 'Feature' ? feat : { type: 'Feature', geometry: feat.geometry || feat, properties: {} };
+        const transformed = transformFeatureToWGS84(asFeature);
+        transformed.properties = Object.assign({}, transformed.properties || {}, props);
+        features.push(transformed);
+    }
+    return { features, source };
+}
+
+// Convert on-chain lands to a WGS84 FeatureCollection (used by spatial queries)
+function landsToFeatureCollection(lands) {
+    const cidFile = safeReadJSON(CID_MAPPING_PATH) || {};
+    const cidMapping = cidFile.cid_mapping || cidFile.mapping || cidFile || {};
+    const features = [];
+    for (const land of lands) {
+        features.push(...landToFeatures(land, cidMapping).features);
+    }
+    return { type: 'FeatureCollection', features };
+}
+
 async function buildCacheFromOnChain(org, userID) {
 	if (CACHE_BUILDING) return;
 	CACHE_BUILDING = true;
@@ -133,49 +191,11 @@ async function buildCacheFromOnChain(org, userID) {
 
         let missingCid = 0, loadedByCid = 0, loadedById = 0;
         for (const land of lands) {
-			const geometryCid = land.geometryCid || land.geometryCID || land.GeometryCID || '';
-            let gj = resolveGeometryByCid(geometryCid, cidMapping);
-            if (!gj) {
-                if (!geometryCid) missingCid++;
-                // Fallback by land id
-                gj = resolveGeometryByLandId(land.id, cidMapping);
-                if (gj) loadedById++;
-            } else {
-                loadedByCid++;
-            }
-            if (!gj) continue;
-			const props = {
-				id: land.id || '',
-				ownerId: land.ownerID || land.ownerId || '',
-				usePurpose: land.landUsePurpose || land.usePurpose || '',
-				area: land.area !== undefined && land.area !== null ? land.area : null,
-                dc: (land.id && String(land.id).split('-')[0]) || '',
-                location: land.location || land.Location || '',
-                legalStatus: land.legalStatus || land.LegalStatus || '',
-                legalInfo: land.legalInfo || land.LegalInfo || ''
-			};
-            // Normalize input to an array of GeoJSON Features or raw geometries
-            let list;
-            if (Array.isArray(gj.features)) {
-                list = gj.features;
-            } else if (gj && gj.type === 'FeatureCollection' && Array.isArray(gj.features)) {
-                list = gj.features;
-            } else if (gj && gj.geometry && gj.geometry.type) {
-                // Case: { landId, geometry: { type, coordinates } }
-                list = [{ type: 'Feature', geometry: gj.geometry, properties: {} }];
-            } else if (gj && gj.type && gj.coordinates) {
-                // Bare geometry object
-                list = [{ type: 'Feature', geometry: gj, properties: {} }];
-            } else {
-                list = [gj];
-            }
-			for (const feat of list) {
-                if (!feat) continue;
-                const asFeature = feat.type === 'Feature' ? feat : { type: 'Feature', geometry: feat.geometry || feat, properties: {} };
-                const transformed = transformFeatureToWGS84(asFeature);
-                transformed.properties = Object.assign({}, transformed.properties || {}, props);
-                features.push(transformed);
-			}
+            const { features: landFeatures, source } = landToFeatures(land, cidMapping);
+            if (source === 'cid') loadedByCid++;
+            else if (source === 'id') loadedById++;
+            if (source !== 'cid' && !(land.geometryCid || land.geometryCID || land.GeometryCID)) missingCid++;
+            features.push(...landFeatures);
 		}
 
         CACHE_GEOJSON = { type: 'FeatureCollection', features };
@@ -215,6 +235,40 @@ const mapService = {
 			console.error('[mapService] getOrg1CadastralGeoJSON error:', e.message);
 			res.status(500).json({ error: 'failed_to_serve_geojson' });
 		}
+	},
+
+	// GET /api/map/parcels/bbox?minX=&minY=&maxX=&maxY= (VN-2000 coordinates)
+	async getParcelsInBoundingBox(req, res) {
+		try {
+			const { minX, minY, maxX, maxY } = req.query;
+			if ([minX, minY, maxX, maxY].some(v => v === undefined || isNaN(parseFloat(v)))) {
+				return res.status(400).json({ success: false, message: 'minX, minY, maxX, maxY là bắt buộc' });
+			}
+			const { contract } = await connectToNetwork(req.user.org, req.user.cccd);
+			const result = await contract.evaluateTransaction('QueryLandsInBoundingBox', String(minX), String(minY), String(maxX), String(maxY));
+			const lands = result ? JSON.parse(result.toString()) : [];
+			res.json(landsToFeatureCollection(lands || []));
+		} catch (e) {
+			console.error('[mapService] getParcelsInBoundingBox error:', e.message);
+			res.status(500).json({ success: false, message: 'Lỗi khi truy vấn thửa đất theo vùng', error: e.message });
+		}
+	},
+
+	// GET /api/map/parcels/near?x=&y=&radius= (VN-2000 coordinates, metres)
+	async getParcelsNearPoint(req, res) {
+		try {
+			const { x, y, radius } = req.query;
+			if ([x, y, radius].some(v => v === undefined || isNaN(parseFloat(v)))) {
+				return res.status(400).json({ success: false, message: 'x, y, radius là bắt buộc' });
+			}
+			const { contract } = await connectToNetwork(req.user.org, req.user.cccd);
+			const result = await contract.evaluateTransaction('QueryLandsNearPoint', String(x), String(y), String(radius));
+			const lands = result ? JSON.parse(result.toString()) : [];
+			res.json(landsToFeatureCollection(lands || []));
+		} catch (e) {
+			console.error('[mapService] getParcelsNearPoint error:', e.message);
+			res.status(500).json({ success: false, message: 'Lỗi khi truy vấn thửa đất lân cận', error: e.message });
+		}
 	}
 };
 
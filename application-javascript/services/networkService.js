@@ -5,6 +5,7 @@ const { buildCCPOrg1, buildCCPOrg2, buildCCPOrg3, buildWallet } = require('../en
 
 const myChannel = 'mychannel';
 const myChaincodeName = 'land-cc';
+const privateDataOrgs = ['Org1MSP', 'Org2MSP'];
 
 async function connectToNetwork(org, cccd) {
     let ccp, walletPath;
@@ -24,10 +25,56 @@ async function connectToNetwork(org, cccd) {
     const gateway = new Gateway();
     await gateway.connect(ccp, { wallet, identity: cccd, discovery: { enabled: true, asLocalhost: true } });
     const network = await gateway.getNetwork(myChannel);
-    const contract = network.getContract(myChaincodeName);
+    const contract = wrapContract(network, network.getContract(myChaincodeName));
     return { gateway, contract };
 }
 
+// Dữ liệu cá nhân nằm trong private collection của Org1, Org2 nên mọi giao dịch
+// đều được gửi tới peer của hai tổ chức này, kể cả khi người gọi thuộc Org3
+function wrapContract(network, contract) {
+    const privateDataPeers = () => {
+        const channel = network.getChannel();
+        return privateDataOrgs.flatMap((msp) => channel.getEndorsers(msp));
+    };
+    const toTransient = (transientObj) => {
+        const transientData = {};
+        for (const [key, value] of Object.entries(transientObj || {})) {
+            if (value !== undefined && value !== null && value !== '') {
+                transientData[key] = Buffer.from(String(value));
+            }
+        }
+        return transientData;
+    };
+    return {
+        submitTransaction: (name, ...args) =>
+            contract.createTransaction(name).setEndorsingOrganizations(...privateDataOrgs).submit(...args),
+        evaluateTransaction: (name, ...args) =>
+            contract.createTransaction(name).setEndorsingPeers(privateDataPeers()).evaluate(...args),
+        submitWithTransient: (name, transientObj, ...args) =>
+            contract.createTransaction(name)
+                .setEndorsingOrganizations(...privateDataOrgs)
+                .setTransient(toTransient(transientObj))
+                .submit(...args),
+    };
+}
+
+// Mã định danh băm (subject ref) của người dùng trên chaincode. GetCallerID và mọi ownerId, uploadedBy
+// lưu trên sổ cái đều là mã này chứ không phải CCCD, nên frontend cần nó để nhận ra bản ghi của chính mình
+async function resolveSubjectRef(org, cccd) {
+    try {
+        const { gateway, contract } = await connectToNetwork(org, cccd);
+        try {
+            const result = await contract.evaluateTransaction('GetMySubjectRef');
+            return result.toString();
+        } finally {
+            gateway.disconnect();
+        }
+    } catch (error) {
+        console.error('Không lấy được mã định danh băm của người dùng:', error.message);
+        return null;
+    }
+}
+
 async function registerAdminWithFabric(org, cccd) {
     const { registerAndEnrollUser } = require('../enroll/CAUtil.js');
     let ccp, walletPath, msp;
@@ -55,4 +102,4 @@ async function registerAdminWithFabric(org, cccd) {
     await registerAndEnrollUser(caClient, wallet, msp, cccd, `${org.toLowerCase()}.department1`, []);
 }
 
-module.exports = { connectToNetwork, registerAdminWithFabric };
\ No newline at end of file
+module.exports = { connectToNetwork, resolveSubjectRef, registerAdminWithFabric };
\ No newline at end of file
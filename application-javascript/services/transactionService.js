@@ -67,7 +67,7 @@ const transactionService = {
     // Create transfer request
     async createTransferRequest(req, res) {
         try {
-            const { landParcelId, toOwnerId, documentIds, reason } = req.body;
+            const { landParcelId, toOwnerId, documentIds, reason, declaredPrice } = req.body;
             const userID = req.user.cccd;
             const org = req.user.org;
 
@@ -75,10 +75,11 @@ const transactionService = {
 
             // Tạo giao dịch với documents - chaincode sẽ tự động tạo txID và link documents
             const documentIdsStr = documentIds && Array.isArray(documentIds) ? JSON.stringify(documentIds) : "[]";
-            await contract.submitTransaction(
+            // CCCD bên nhận và giá khai báo chỉ được truyền qua transient map, không ghi vào block
+            await contract.submitWithTransient(
                 'CreateTransferRequest',
+                { toOwnerId, declaredPrice },
                 landParcelId,
-                toOwnerId,
                 documentIdsStr,
                 reason || ''
             );
@@ -95,7 +96,7 @@ const transactionService = {
                     
                     // Tìm transaction vừa tạo (có timestamp gần nhất và type TRANSFER)
                     createdTransaction = allTransactions
-                        .filter(tx => tx.type === 'TRANSFER' && tx.landParcelId === landParcelId && tx.toOwnerId === toOwnerId)
+                        .filter(tx => tx.type === 'TRANSFER' && tx.landParcelId === landParcelId)
                         .sort((a, b) => new Date(b.createdAt) - new Date(a.createdAt))[0];
                 }
             } catch (queryError) {
@@ -148,7 +149,7 @@ const transactionService = {
     // Confirm transfer (by recipient) - Accept or Reject
     async confirmTransfer(req, res) {
         try {
-            const { txID, landParcelID, toOwnerID, isAccepted, reason } = req.body;
+            const { txID, landParcelID, isAccepted, reason } = req.body;
             const userID = req.user.cccd;
             const org = req.user.org;
 
@@ -158,7 +159,6 @@ const transactionService = {
                 'ConfirmTransfer',
                 txID,
                 landParcelID,
-                toOwnerID,
                 isAccepted.toString(),
                 reason || ''
             );
@@ -197,10 +197,48 @@ const transactionService = {
         }
     },
 
+    // Consent to transaction (by co-owner) - Accept or Reject
+    async consentToTransaction(req, res) {
+        try {
+            const { txID } = req.params;
+            const { isAccepted, reason } = req.body;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            await contract.submitTransaction(
+                'ConsentToTransaction',
+                txID,
+                String(Boolean(isAccepted)),
+                reason || ''
+            );
+
+            const transactionResult = await contract.evaluateTransaction(
+                'QueryTransactionByID',
+                txID
+            );
+
+            const actionText = isAccepted ? 'đồng ý' : 'không đồng ý';
+            res.json({
+                success: true,
+                message: `Đã ghi nhận ý kiến ${actionText} của đồng sở hữu`,
+                data: JSON.parse(transactionResult.toString())
+            });
+        } catch (error) {
+            console.error('Error consenting to transaction:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi ghi nhận ý kiến đồng sở hữu',
+                error: error.message
+            });
+        }
+    },
+
     // Create split request - theo luồng chaincode mới
     async createSplitRequest(req, res) {
         try {
-            const { landParcelID, documentIds, reason } = req.body;
+            const { landParcelID, documentIds, reason, proposedParcels } = req.body;
             const userID = req.user.cccd;
             const org = req.user.org;
 
@@ -214,13 +252,15 @@ const transactionService = {
 
             const { contract } = await connectToNetwork(org, userID);
 
-            // Theo chaincode mới: CreateSplitRequest(landParcelID, documentIdsStr, reason)
+            // Theo chaincode mới: CreateSplitRequest(landParcelID, documentIdsStr, reason, proposedParcelsStr)
             const documentIdsStr = documentIds && Array.isArray(documentIds) ? JSON.stringify(documentIds) : "[]";
+            const proposedParcelsStr = proposedParcels && Array.isArray(proposedParcels) ? JSON.stringify(proposedParcels) : '';
             await contract.submitTransaction(
                 'CreateSplitRequest',
                 landParcelID,
                 documentIdsStr,
-                reason || ''
+                reason || '',
+                proposedParcelsStr
             );
 
             // Tìm giao dịch vừa tạo
@@ -277,7 +317,7 @@ const transactionService = {
     // Create merge request - theo luồng chaincode mới
     async createMergeRequest(req, res) {
         try {
-            const { parcelIDs, documentIds, reason } = req.body;
+            const { parcelIDs, documentIds, reason, selectedLandId, targetPurpose, geometries } = req.body;
             const userID = req.user.cccd;
             const org = req.user.org;
 
@@ -289,16 +329,27 @@ const transactionService = {
                 });
             }
 
+            // Chaincode kiểm tra các thửa liền kề theo hình học GeoJSON của từng thửa
+            if (!geometries || typeof geometries !== 'object' || parcelIDs.some(id => !geometries[id])) {
+                return res.status(400).json({
+                    success: false,
+                    message: 'Cần hình học (GeoJSON) của tất cả các thửa đất để kiểm tra liền kề'
+                });
+            }
+
             const { contract } = await connectToNetwork(org, userID);
 
-            // Theo chaincode mới: CreateMergeRequest(parcelIDsStr, documentIdsStr, reason)
+            // Theo chaincode mới: CreateMergeRequest(parcelIDsStr, documentIdsStr, reason, selectedLandID, targetPurpose, geometriesStr)
             const parcelIDsStr = JSON.stringify(parcelIDs);
             const documentIdsStr = documentIds && Array.isArray(documentIds) ? JSON.stringify(documentIds) : "[]";
             await contract.submitTransaction(
                 'CreateMergeRequest',
                 parcelIDsStr,
                 documentIdsStr,
-                reason || ''
+                reason || '',
+                selectedLandId || '',
+                targetPurpose || '',
+                JSON.stringify(geometries)
             );
 
             // Tìm giao dịch vừa tạo
@@ -490,6 +541,217 @@ const transactionService = {
     },
 
 
+    // Create inheritance request - người thừa kế (Org3) hoặc Org2
+    async createInheritanceRequest(req, res) {
+        try {
+            const { landParcelID, deceasedOwnerId, heirs, documentIds, reason } = req.body;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            if (!landParcelID || !deceasedOwnerId || !Array.isArray(heirs) || heirs.length === 0) {
+                return res.status(400).json({
+                    success: false,
+                    message: 'Mã thửa đất, CCCD người để lại di sản và danh sách người thừa kế là bắt buộc'
+                });
+            }
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            // CCCD người để lại di sản và người thừa kế chỉ được truyền qua transient map, không ghi vào block
+            const documentIdsStr = documentIds && Array.isArray(documentIds) ? JSON.stringify(documentIds) : "[]";
+            await contract.submitWithTransient(
+                'CreateInheritanceRequest',
+                { deceasedOwnerId, heirs: JSON.stringify(heirs) },
+                landParcelID,
+                documentIdsStr,
+                reason || ''
+            );
+
+            // Tìm giao dịch vừa tạo
+            let createdTransaction = null;
+            try {
+                const allTransactionsResult = await contract.evaluateTransaction(
+                    'QueryTransactionsByOwner',
+                    userID
+                );
+                if (allTransactionsResult) {
+                    const allTransactions = JSON.parse(allTransactionsResult.toString());
+
+                    createdTransaction = allTransactions
+                        .filter(tx => tx.type === 'INHERITANCE' && tx.landParcelId === landParcelID)
+                        .sort((a, b) => new Date(b.createdAt) - new Date(a.createdAt))[0];
+                }
+            } catch (queryError) {
+                console.warn('Could not find created transaction:', queryError.message);
+            }
+
+            res.json({
+                success: true,
+                message: `Yêu cầu đăng ký thừa kế đã được tạo thành công${documentIds?.length > 0 ? ` với ${documentIds.length} tài liệu đính kèm` : ''}`,
+                data: createdTransaction || { success: true }
+            });
+        } catch (error) {
+            console.error('Error creating inheritance request:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi tạo yêu cầu đăng ký thừa kế',
+                error: error.message
+            });
+        }
+    },
+
+    // Approve inheritance transaction
+    async approveInheritanceTransaction(req, res) {
+        try {
+            const { txID } = req.params;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            await contract.submitTransaction(
+                'ApproveInheritanceTransaction',
+                txID
+            );
+
+            const transactionResult = await contract.evaluateTransaction(
+                'QueryTransactionByID',
+                txID
+            );
+
+            res.json({
+                success: true,
+                message: 'Giao dịch thừa kế đã được phê duyệt thành công',
+                data: JSON.parse(transactionResult.toString())
+            });
+        } catch (error) {
+            console.error('Error approving inheritance transaction:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi phê duyệt giao dịch thừa kế',
+                error: error.message
+            });
+        }
+    },
+
+    // Create gift request - bên tặng cho (Org3)
+    async createGiftRequest(req, res) {
+        try {
+            const { landParcelId, toOwnerId, relationship, taxExempt, documentIds, reason } = req.body;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            if (!landParcelId || !toOwnerId || !relationship) {
+                return res.status(400).json({
+                    success: false,
+                    message: 'Mã thửa đất, CCCD người nhận và quan hệ tặng cho là bắt buộc'
+                });
+            }
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            // CCCD bên nhận chỉ được truyền qua transient map, không ghi vào block
+            const documentIdsStr = documentIds && Array.isArray(documentIds) ? JSON.stringify(documentIds) : "[]";
+            await contract.submitWithTransient(
+                'CreateGiftRequest',
+                { toOwnerId },
+                landParcelId,
+                relationship,
+                taxExempt ? 'true' : 'false',
+                documentIdsStr,
+                reason || ''
+            );
+
+            // Tìm giao dịch vừa tạo
+            let createdTransaction = null;
+            try {
+                const allTransactionsResult = await contract.evaluateTransaction(
+                    'QueryTransactionsByOwner',
+                    userID
+                );
+                if (allTransactionsResult) {
+                    const allTransactions = JSON.parse(allTransactionsResult.toString());
+
+                    createdTransaction = allTransactions
+                        .filter(tx => tx.type === 'GIFT' && tx.landParcelId === landParcelId)
+                        .sort((a, b) => new Date(b.createdAt) - new Date(a.createdAt))[0];
+                }
+            } catch (queryError) {
+                console.warn('Could not find created transaction:', queryError.message);
+            }
+
+            res.json({
+                success: true,
+                message: `Yêu cầu tặng cho đã được tạo thành công${documentIds?.length > 0 ? ` với ${documentIds.length} tài liệu đính kèm` : ''}`,
+                data: createdTransaction || { success: true }
+            });
+        } catch (error) {
+            console.error('Error creating gift request:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi tạo yêu cầu tặng cho',
+                error: error.message
+            });
+        }
+    },
+
+    // Approve gift transaction
+    async approveGiftTransaction(req, res) {
+        try {
+            const { txID } = req.params;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            await contract.submitTransaction(
+                'ApproveGiftTransaction',
+                txID
+            );
+
+            const transactionResult = await contract.evaluateTransaction(
+                'QueryTransactionByID',
+                txID
+            );
+
+            res.json({
+                success: true,
+                message: 'Giao dịch tặng cho đã được phê duyệt thành công',
+                data: JSON.parse(transactionResult.toString())
+            });
+        } catch (error) {
+            console.error('Error approving gift transaction:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi phê duyệt giao dịch tặng cho',
+                error: error.message
+            });
+        }
+    },
+
+    // Get gift relationships catalog
+    async getGiftRelationships(req, res) {
+        try {
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            const { contract } = await connectToNetwork(org, userID);
+            const result = await contract.evaluateTransaction('GetGiftRelationships');
+
+            res.json({
+                success: true,
+                data: JSON.parse(result.toString())
+            });
+        } catch (error) {
+            console.error('Error getting gift relationships:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi truy vấn danh mục quan hệ tặng cho',
+                error: error.message
+            });
+        }
+    },
+
     // Approve transfer transaction
     async approveTransferTransaction(req, res) {
         try {
@@ -652,6 +914,19 @@ const transactionService = {
             });
         } catch (error) {
             console.error('Error approving split transaction:', error);
+            // Chaincode trả về báo cáo thẩm định dạng JSON khi kết quả tách thửa không hợp lệ
+            const reportMatch = /kết quả tách thửa không hợp lệ \(\d+ vấn đề\): (\{.*\})/.exec(error.message || '');
+            if (reportMatch) {
+                try {
+                    return res.status(400).json({
+                        success: false,
+                        message: 'Kết quả tách thửa không hợp lệ',
+                        data: JSON.parse(reportMatch[1])
+                    });
+                } catch (parseError) {
+                    // Không giải mã được báo cáo, trả về lỗi gốc
+                }
+            }
             res.status(500).json({
                 success: false,
                 message: 'Lỗi khi phê duyệt giao dịch tách thửa',
@@ -660,6 +935,46 @@ const transactionService = {
         }
     },
 
+    // Thẩm định trước kết quả tách thửa, trả về báo cáo liệt kê toàn bộ vấn đề
+    async validateSplitTransaction(req, res) {
+        try {
+            const { txID } = req.params;
+            const { landID, newParcels } = req.body;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            if (!landID) {
+                return res.status(400).json({
+                    success: false,
+                    message: 'Mã thửa đất gốc là bắt buộc'
+                });
+            }
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            // Danh sách trống thì chaincode dùng các thửa đề xuất trong yêu cầu
+            const newParcelsStr = Array.isArray(newParcels) && newParcels.length > 0 ? JSON.stringify(newParcels) : '';
+            const result = await contract.evaluateTransaction(
+                'ValidateSplitTransaction',
+                txID,
+                landID,
+                newParcelsStr
+            );
+
+            res.json({
+                success: true,
+                data: JSON.parse(result.toString())
+            });
+        } catch (error) {
+            console.error('Error validating split transaction:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi thẩm định kết quả tách thửa',
+                error: error.message
+            });
+        }
+    },
+
     // Phê duyệt gộp thửa với thông tin thửa đất gộp
     async approveMergeTransaction(req, res) {
         try {
@@ -761,7 +1076,7 @@ const transactionService = {
     async approveReissueTransaction(req, res) {
         try {
             const { txID } = req.params;
-            const { newCertificateID } = req.body;
+            const { newCertificateID, serialNumber } = req.body;
             const userID = req.user.cccd;
             const org = req.user.org;
 
@@ -777,7 +1092,8 @@ const transactionService = {
             await contract.submitTransaction(
                 'ApproveReissueTransaction',
                 txID,
-                newCertificateID
+                newCertificateID,
+                serialNumber || ''
             );
 
             // Get the updated transaction to return as response data
@@ -838,6 +1154,42 @@ const transactionService = {
         }
     },
 
+    // Withdraw transaction - người tạo yêu cầu rút hồ sơ
+    async withdrawTransaction(req, res) {
+        try {
+            const { txID } = req.params;
+            const { reason } = req.body;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            await contract.submitTransaction(
+                'WithdrawTransaction',
+                txID,
+                reason || ''
+            );
+
+            const transactionResult = await contract.evaluateTransaction(
+                'QueryTransactionByID',
+                txID
+            );
+
+            res.json({
+                success: true,
+                message: 'Đã rút hồ sơ giao dịch thành công',
+                data: JSON.parse(transactionResult.toString())
+            });
+        } catch (error) {
+            console.error('Error withdrawing transaction:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi rút hồ sơ giao dịch',
+                error: error.message
+            });
+        }
+    },
+
     // Get a specific transaction by ID
     async getTransaction(req, res) {
         try {
@@ -993,6 +1345,66 @@ const transactionService = {
         }
     },
 
+    // Get overdue transactions, lọc theo tổ chức phải xử lý bước hiện tại (query ?org=Org2MSP&pageSize=50&bookmark=...)
+    async getOverdueTransactions(req, res) {
+        try {
+            const { org: responsibleOrg, pageSize, bookmark } = req.query;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            const result = await contract.evaluateTransaction(
+                'QueryOverdueTransactions',
+                responsibleOrg || '',
+                String(pageSize || 0),
+                bookmark || ''
+            );
+
+            res.json({
+                success: true,
+                data: JSON.parse(result.toString())
+            });
+        } catch (error) {
+            console.error('Error getting overdue transactions:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi lấy giao dịch quá hạn',
+                error: error.message
+            });
+        }
+    },
+
+    // Sweep expired transactions - hủy giao dịch chuyển nhượng, tặng cho quá hạn xác nhận
+    async sweepExpiredTransactions(req, res) {
+        try {
+            const { limit } = req.body;
+            const userID = req.user.cccd;
+            const org = req.user.org;
+
+            const { contract } = await connectToNetwork(org, userID);
+
+            const result = await contract.submitTransaction(
+                'SweepExpiredTransactions',
+                String(limit || 0)
+            );
+            const expired = JSON.parse(result.toString() || '[]');
+
+            res.json({
+                success: true,
+                message: `Đã hủy ${expired.length} giao dịch quá hạn xác nhận`,
+                data: expired
+            });
+        } catch (error) {
+            console.error('Error sweeping expired transactions:', error);
+            res.status(500).json({
+                success: false,
+                message: 'Lỗi khi hủy giao dịch quá hạn',
+                error: error.message
+            });
+        }
+    },
+
     // Get transactions by owner
     async getTransactionsByOwner(req, res) {
         try {
@@ -2,6 +2,7 @@
 const User = require('../models/User');
 const { sanitizeInput, validatePhone, validateOrg } = require('../enroll/validation.js');
 const notificationService = require('./notificationService');
+const { resolveSubjectRef } = require('./networkService');
 
 const getUsers = async (req, res) => {
     const { limit = 50, offset = 0, org: filterOrg, role } = req.query;
@@ -35,7 +36,8 @@ const getProfile = async (req, res) => {
         if (!user) {
             return res.status(404).json({ error: 'Không tìm thấy người dùng' });
         }
-        res.json({ user });
+        const subjectRef = await resolveSubjectRef(req.user.org, req.user.cccd);
+        res.json({ user: { ...user.toObject(), subjectRef } });
     } catch (error) {
         res.status(500).json({ error: `Lấy hồ sơ thất bại: ${error.message}` });
     }
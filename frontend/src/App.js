@@ -66,6 +66,7 @@ function App() {
               if (userId && org) {
                 setUser({
                   userId: String(userId), // Ensure it's a string
+                  subjectRef: payload.subjectRef || null,
                   org: String(org),
                   role: String(role),
                   name: String(name),
@@ -109,6 +110,7 @@ function App() {
     if (user && user.userId && user.org) {
       const validatedUser = {
         userId: String(user.userId),
+        subjectRef: user.subjectRef || null,
         org: String(user.org),
         role: String(user.role || 'user'),
         name: String(user.name || user.username || 'User'),
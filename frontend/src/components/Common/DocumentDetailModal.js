@@ -366,18 +366,14 @@ const DocumentDetailModal = ({
               }}>
                 <div style={{ background: '#ffe6e6', padding: '8px 12px', borderRadius: 4 }}>
                   <Text type="secondary" style={{ fontSize: 12 }}>
-                    Bởi: {(() => {
-                      const rejectionMatch = document?.description?.match(/\[REJECTED: (.*?)\s*\|\s*By: (.*?)\s*\|\s*At: (.*?)\]/);
-                      return rejectionMatch ? rejectionMatch[2] : 'N/A';
-                    })()}
+                    Bởi: {document.verifiedBy || 'N/A'}
                   </Text>
                 </div>
                 <div style={{ background: '#ffe6e6', padding: '8px 12px', borderRadius: 4 }}>
                   <Text type="secondary" style={{ fontSize: 12 }}>
-                    Lúc: {(() => {
-                      const rejectionMatch = document?.description?.match(/\[REJECTED: (.*?)\s*\|\s*By: (.*?)\s*\|\s*At: (.*?)\]/);
-                      return rejectionMatch ? new Date(rejectionMatch[3]).toLocaleString('vi-VN') : 'N/A';
-                    })()}
+                    Lúc: {document.verifiedAt && document.verifiedAt !== '0001-01-01T00:00:00Z'
+                      ? new Date(document.verifiedAt).toLocaleString('vi-VN')
+                      : 'N/A'}
                   </Text>
                 </div>
               </div>
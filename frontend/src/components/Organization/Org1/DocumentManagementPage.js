@@ -4,6 +4,7 @@ import { EditOutlined, SearchOutlined, ReloadOutlined, EyeOutlined, DeleteOutlin
 import documentService from '../../../services/documentService';
 import ipfsService from '../../../services/ipfs';
 import { useAuth } from '../../../hooks/useAuth';
+import authService from '../../../services/auth';
 import OnlineDocumentViewer from '../../Common/OnlineDocumentViewer';
 
 const { confirm } = Modal;
@@ -150,6 +151,7 @@ const DocumentManagementPage = () => {
         title: values.title,
         description: values.description,
         ipfsHash: ipfsHash,
+        contentSha256: await ipfsService.computeFileSHA256(selectedFile),
         fileType: selectedFile.type || selectedFile.name.split('.').pop().toUpperCase(),
         fileSize: selectedFile.size,
         status: 'VERIFIED' // Org1 tạo tài liệu sẽ tự động được xác thực
@@ -350,14 +352,14 @@ const DocumentManagementPage = () => {
           <Tooltip title="Xem chi tiết">
             <Button icon={<EyeOutlined />} onClick={() => openDetail(record)} />
           </Tooltip>
-          <Tooltip title={record.uploadedBy !== user?.userId ? "Chỉ người upload mới được sửa" : "Sửa"}>
+          <Tooltip title={!authService.isCurrentSubject(record.uploadedBy, user) ? "Chỉ người upload mới được sửa" : "Sửa"}>
             <Button 
               icon={<EditOutlined />} 
               onClick={() => {
                 console.log('Edit button clicked:', { 
                   recordUploadedBy: record.uploadedBy, 
                   currentUserId: user?.userId,
-                  canEdit: record.uploadedBy === user?.userId 
+                  canEdit: authService.isCurrentSubject(record.uploadedBy, user) 
                 });
                 setSelected(record);
                 editForm.setFieldsValue({
@@ -366,16 +368,16 @@ const DocumentManagementPage = () => {
                 });
                 setEditOpen(true);
               }}
-              disabled={record.uploadedBy !== user?.userId}
+              disabled={!authService.isCurrentSubject(record.uploadedBy, user)}
             />
           </Tooltip>
 
-          <Tooltip title={record.uploadedBy !== user?.userId ? "Chỉ người upload mới được xóa" : "Xóa"}>
+          <Tooltip title={!authService.isCurrentSubject(record.uploadedBy, user) ? "Chỉ người upload mới được xóa" : "Xóa"}>
             <Button 
               icon={<DeleteOutlined />} 
               danger 
               onClick={() => handleDelete(record)}
-              disabled={record.uploadedBy !== user?.userId}
+              disabled={!authService.isCurrentSubject(record.uploadedBy, user)}
             />
           </Tooltip>
         </Space>
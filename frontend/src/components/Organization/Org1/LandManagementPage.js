@@ -250,6 +250,7 @@ const LandManagementPage = () => {
         description: certificateMetadata.description,
         ipfsHash: uploadResult.fileHash,
         metadataHash: uploadResult.metadataHash,
+        contentSha256: await ipfsService.computeFileSHA256(certificateFile),
         fileType: certificateFile.type || certificateFile.name.split('.').pop().toUpperCase(),
         fileSize: certificateFile.size,
         status: 'VERIFIED'
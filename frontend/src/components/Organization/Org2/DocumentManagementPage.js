@@ -6,6 +6,7 @@ import ipfsService from '../../../services/ipfs';
 import documentAnalysisService from '../../../services/documentAnalysisService';
 import OnlineDocumentViewer from '../../Common/OnlineDocumentViewer';
 import { useAuth } from '../../../hooks/useAuth';
+import authService from '../../../services/auth';
 import DocumentDetailModal from '../../Common/DocumentDetailModal'; // Import the component
 const { confirm } = Modal;
 const { Option } = Select;
@@ -123,6 +124,7 @@ const DocumentManagementPage = () => {
         title: values.title,
         description: values.description,
         ipfsHash,
+        contentSha256: await ipfsService.computeFileSHA256(selectedFile),
         fileType: selectedFile.type || selectedFile.name.split('.').pop().toUpperCase(),
         fileSize: selectedFile.size,
         status: 'PENDING',
@@ -313,7 +315,7 @@ const DocumentManagementPage = () => {
             <Tooltip title='Xem chi tiết'>
               <Button icon={<FileTextOutlined />} onClick={() => openDetail(record)} />
             </Tooltip>
-            <Tooltip title={record.uploadedBy !== user?.userId ? 'Chỉ người upload mới được sửa' : 'Sửa'}>
+            <Tooltip title={!authService.isCurrentSubject(record.uploadedBy, user) ? 'Chỉ người upload mới được sửa' : 'Sửa'}>
               <Button
                 icon={<EditOutlined />}
                 onClick={() => {
@@ -324,15 +326,15 @@ const DocumentManagementPage = () => {
                   });
                   setEditOpen(true);
                 }}
-                disabled={record.uploadedBy !== user?.userId}
+                disabled={!authService.isCurrentSubject(record.uploadedBy, user)}
               />
             </Tooltip>
-            <Tooltip title={record.uploadedBy !== user?.userId ? 'Chỉ người upload mới được xóa' : 'Xóa'}>
+            <Tooltip title={!authService.isCurrentSubject(record.uploadedBy, user) ? 'Chỉ người upload mới được xóa' : 'Xóa'}>
               <Button
                 icon={<DeleteOutlined />}
                 danger
                 onClick={() => handleDelete(record)}
-                disabled={record.uploadedBy !== user?.userId}
+                disabled={!authService.isCurrentSubject(record.uploadedBy, user)}
               />
             </Tooltip>
           </Space>
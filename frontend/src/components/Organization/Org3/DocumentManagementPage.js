@@ -5,6 +5,7 @@ import documentService from '../../../services/documentService';
 import ipfsService from '../../../services/ipfs';
 import OnlineDocumentViewer from '../../Common/OnlineDocumentViewer';
 import { useAuth } from '../../../hooks/useAuth';
+import authService from '../../../services/auth';
 
 const { confirm } = Modal;
 
@@ -253,6 +254,7 @@ const DocumentManagementPage = () => {
         title: values.title,
         description: values.description,
         ipfsHash: ipfsHash,
+        contentSha256: await ipfsService.computeFileSHA256(selectedFile),
         fileType: selectedFile.type || selectedFile.name.split('.').pop().toUpperCase(),
         fileSize: selectedFile.size,
         status: 'PENDING' // Org3 tạo tài liệu sẽ chờ xác thực
@@ -338,7 +340,7 @@ const DocumentManagementPage = () => {
             }} />
           </Tooltip>
 
-          <Tooltip title={record.uploadedBy !== user?.userId ? "Chỉ người upload mới được sửa" : "Sửa"}>
+          <Tooltip title={!authService.isCurrentSubject(record.uploadedBy, user) ? "Chỉ người upload mới được sửa" : "Sửa"}>
             <Button 
               icon={<EditOutlined />} 
               onClick={() => {
@@ -349,15 +351,15 @@ const DocumentManagementPage = () => {
                 });
                 setEditOpen(true);
               }}
-              disabled={record.uploadedBy !== user?.userId}
+              disabled={!authService.isCurrentSubject(record.uploadedBy, user)}
             />
           </Tooltip>
-          <Tooltip title={record.uploadedBy !== user?.userId ? "Chỉ người upload mới được xóa" : "Xóa"}>
+          <Tooltip title={!authService.isCurrentSubject(record.uploadedBy, user) ? "Chỉ người upload mới được xóa" : "Xóa"}>
             <Button 
               icon={<DeleteOutlined />} 
               danger 
               onClick={() => handleDelete(record)}
-              disabled={record.uploadedBy !== user?.userId}
+              disabled={!authService.isCurrentSubject(record.uploadedBy, user)}
             />
           </Tooltip>
         </Space>
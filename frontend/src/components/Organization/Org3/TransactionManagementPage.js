@@ -593,7 +593,7 @@ const TransactionManagementPage = () => {
     // 3. User hiện tại là người nhận chuyển nhượng (toOwnerId)
     return transaction.status === 'APPROVED' && 
            transaction.type === 'TRANSFER' &&
-           user?.userId === transaction.toOwnerId;
+           authService.isCurrentSubject(transaction.toOwnerId, user);
   };
 
   const canSupplement = (transaction) => {
@@ -610,7 +610,7 @@ const TransactionManagementPage = () => {
           key: 'fromOwnerId',
           render: (v) => {
             const currentUser = authService.getCurrentUser();
-            return v === currentUser?.userId ? 
+            return authService.isCurrentSubject(v, currentUser) ? 
               <Tag color="blue">Bạn</Tag> : 
               <code>{v}</code>
           }
@@ -1624,8 +1624,8 @@ const TransactionManagementPage = () => {
             {/* Accept/Reject Transfer buttons for recipients */}
             {(() => {
               const currentUser = authService.getCurrentUser();
-              const isRecipient = selected.type === 'TRANSFER' && 
-                                selected.toOwnerId === currentUser?.userId && 
+              const isRecipient = ['TRANSFER', 'GIFT'].includes(selected.type) && 
+                                authService.isCurrentSubject(selected.toOwnerId, currentUser) && 
                                 selected.status === 'PENDING';
               
               if (isRecipient) {
@@ -1661,7 +1661,6 @@ const TransactionManagementPage = () => {
                               await transactionService.confirmTransfer({
                                 txID: selected.txId,
                                 landParcelID: selected.landParcelId,
-                                toOwnerID: selected.toOwnerId,
                                 isAccepted: true,
                                 reason: ''
                               });
@@ -1690,7 +1689,6 @@ const TransactionManagementPage = () => {
                               await transactionService.confirmTransfer({
                                 txID: selected.txId,
                                 landParcelID: selected.landParcelId,
-                                toOwnerID: selected.toOwnerId,
                                 isAccepted: false,
                                 reason: 'Từ chối chuyển nhượng'
                               });
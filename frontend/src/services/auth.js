@@ -25,6 +25,7 @@ const authService = {
           const user = {
             userId: u.cccd,
             cccd: u.cccd,
+            subjectRef: u.subjectRef || null,
             org: u.org,
             role: u.role,
             name: normalizeVietnameseName(u.fullName),
@@ -37,6 +38,7 @@ const authService = {
             const user = {
               userId: payload.cccd,
               cccd: payload.cccd,
+              subjectRef: payload.subjectRef || null,
               org: payload.org,
               role: payload.role || 'user',
               name: normalizeVietnameseName(payload.name || 'User'),
@@ -188,6 +190,13 @@ const authService = {
     }
   },
 
+  // Check whether an on-chain identifier (ownerId, uploadedBy, ...) belongs to the current user
+  // The chaincode stores the salted subject reference, not the raw CCCD
+  isCurrentSubject(id, user = this.getCurrentUser()) {
+    if (!id || !user) return false;
+    return id === user.subjectRef || id === user.cccd;
+  },
+
   // Get auth token
   getAuthToken() {
     return localStorage.getItem('jwt_token');
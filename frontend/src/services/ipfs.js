@@ -253,7 +253,17 @@ export async function checkIPFSHashAccessibility(ipfsHash) {
   }
 }
 
+// Compute the SHA-256 digest (hex) of a file so the ledger can anchor its exact content
+export async function computeFileSHA256(file) {
+  const buffer = await file.arrayBuffer();
+  const digest = await window.crypto.subtle.digest('SHA-256', buffer);
+  return Array.from(new Uint8Array(digest))
+    .map((b) => b.toString(16).padStart(2, '0'))
+    .join('');
+}
+
 const ipfsService = {
+  computeFileSHA256,
   uploadFileToPinata,
   uploadJSONToPinata,
   uploadFileToIPFS,
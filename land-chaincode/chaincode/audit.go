@@ -0,0 +1,267 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// AuditLogKeyPrefix namespace của composite key cho nhật ký kiểm toán
+const AuditLogKeyPrefix = "AUDIT"
+
+// AuditLogRecordType giá trị trường recordType dùng để lọc nhật ký trong truy vấn CouchDB
+const AuditLogRecordType = "AUDIT_LOG"
+
+// AuditLogEntry định nghĩa một bản ghi nhật ký kiểm toán
+type AuditLogEntry struct {
+	LogID         string            `json:"logId"`         // Mã bản ghi nhật ký
+	RecordType    string            `json:"recordType"`    // Luôn là AUDIT_LOG
+	Action        string            `json:"action"`        // Hành động (CREATE_LAND_PARCEL, APPROVE_TRANSFER, ...)
+	ActorID       string            `json:"actorId"`       // Mã định danh người thực hiện
+	ActorMSP      string            `json:"actorMsp"`      // MSP của người thực hiện
+	EntityType    string            `json:"entityType"`    // Loại thực thể tác động (LAND, DOC, TX)
+	EntityID      string            `json:"entityId"`      // Mã thực thể tác động
+	TxID          string            `json:"txId"`          // Mã giao dịch Fabric
+	Message       string            `json:"message"`       // Mô tả dễ đọc
+	Details       map[string]string `json:"details"`       // Chi tiết có cấu trúc
+	Timestamp     time.Time         `json:"timestamp"`     // Thời gian thực hiện
+	TimestampNano int64             `json:"timestampNano"` // Thời gian dạng Unix nano, dùng cho truy vấn theo khoảng
+}
+
+// AuditLogPage một trang kết quả truy vấn nhật ký kiểm toán
+type AuditLogPage struct {
+	Records      []*AuditLogEntry `json:"records"`      // Danh sách bản ghi
+	Bookmark     string           `json:"bookmark"`     // Bookmark cho trang tiếp theo
+	FetchedCount int32            `json:"fetchedCount"` // Số bản ghi trong trang
+}
+
+// AuditLogKey tạo composite key sắp xếp theo thời gian cho nhật ký kiểm toán
+func AuditLogKey(ctx contractapi.TransactionContextInterface, entry *AuditLogEntry) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(AuditLogKeyPrefix, []string{
+		fmt.Sprintf("%020d", entry.TimestampNano),
+		entry.TxID,
+		entry.Action,
+		entry.EntityID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("lỗi khi tạo khóa nhật ký %s: %v", entry.LogID, err)
+	}
+	return key, nil
+}
+
+// RecordAuditLog ghi một bản ghi nhật ký kiểm toán cho hành động của người dùng
+func RecordAuditLog(ctx contractapi.TransactionContextInterface, action, actorID, entityType, entityID, message string, details map[string]string) error {
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	// MSP có thể không xác định được với lời gọi hệ thống
+	actorMSP, _ := GetCallerOrgMSP(ctx)
+	if details == nil {
+		details = map[string]string{}
+	}
+
+	txID := ctx.GetStub().GetTxID()
+	entry := &AuditLogEntry{
+		LogID:         fmt.Sprintf("%s_%s_%d", txID, action, txTime.UnixNano()),
+		RecordType:    AuditLogRecordType,
+		Action:        action,
+		ActorID:       actorID,
+		ActorMSP:      actorMSP,
+		EntityType:    entityType,
+		EntityID:      entityID,
+		TxID:          txID,
+		Message:       message,
+		Details:       details,
+		Timestamp:     txTime,
+		TimestampNano: timestampNano(txTime),
+	}
+	return putAuditLogEntry(ctx, entry)
+}
+
+// transactionAuditDetails chi tiết có cấu trúc của giao dịch dùng cho nhật ký
+func transactionAuditDetails(tx *Transaction) map[string]string {
+	return map[string]string{
+		"txType":       tx.Type,
+		"status":       tx.Status,
+		"landParcelId": tx.LandParcelID,
+		"fromOwnerId":  tx.FromOwnerID,
+		"toOwnerId":    tx.ToOwnerID,
+	}
+}
+
+// putAuditLogEntry lưu bản ghi nhật ký kiểm toán
+func putAuditLogEntry(ctx contractapi.TransactionContextInterface, entry *AuditLogEntry) error {
+	logJSON, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa nhật ký: %v", err)
+	}
+	key, err := AuditLogKey(ctx, entry)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, logJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu nhật ký %s: %v", entry.LogID, err)
+	}
+	return nil
+}
+
+// migrateLegacyLog chuyển bản ghi LOG cũ (lưu dạng Transaction) sang AuditLogEntry
+func migrateLegacyLog(ctx contractapi.TransactionContextInterface, key string, value []byte) error {
+	var legacy Transaction
+	if err := json.Unmarshal(value, &legacy); err != nil {
+		return fmt.Errorf("lỗi khi giải mã nhật ký cũ %s: %v", key, err)
+	}
+
+	// Khóa cũ có dạng LOG_<fabricTxID>_<ACTION>_<unixNano>
+	action, fabricTxID := "LEGACY_LOG", ""
+	rest := strings.TrimPrefix(legacy.TxID, "LOG_")
+	if len(rest) > 65 && rest[64] == '_' {
+		fabricTxID = rest[:64]
+		rest = rest[65:]
+		if idx := strings.LastIndex(rest, "_"); idx > 0 {
+			action = rest[:idx]
+		}
+	}
+
+	entry := &AuditLogEntry{
+		LogID:         legacy.TxID,
+		RecordType:    AuditLogRecordType,
+		Action:        action,
+		ActorID:       legacy.UserID,
+		TxID:          fabricTxID,
+		Message:       legacy.Details,
+		Details:       map[string]string{"legacyKey": key},
+		Timestamp:     legacy.CreatedAt,
+		TimestampNano: timestampNano(legacy.CreatedAt),
+	}
+	return putAuditLogEntry(ctx, entry)
+}
+
+// timestampNano chuyển thời gian thành Unix nano làm khóa sắp xếp
+// Nhật ký cũ không có CreatedAt (thời gian rỗng) được đưa về 0 thay vì giá trị âm
+func timestampNano(t time.Time) int64 {
+	if t.IsZero() || t.UnixNano() < 0 {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// parseDateBoundary chuyển chuỗi ngày (RFC3339 hoặc YYYY-MM-DD) thành Unix nano
+// Với endOfDay = true, ngày dạng YYYY-MM-DD được hiểu là hết ngày đó
+func parseDateBoundary(value string, endOfDay bool) (int64, error) {
+	t, err := parseDate(value)
+	if err != nil {
+		return 0, err
+	}
+	if endOfDay && len(value) == len("2006-01-02") {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t.UnixNano(), nil
+}
+
+// QueryAuditLogs - Truy vấn nhật ký kiểm toán theo người thực hiện, hành động, thực thể và khoảng thời gian (phân trang)
+// Org3 chỉ xem được nhật ký của chính mình
+func (s *LandRegistryChaincode) QueryAuditLogs(ctx contractapi.TransactionContextInterface, actorID, action, entityID, fromDate, toDate string, pageSize int32, bookmark string) (*AuditLogPage, error) {
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if actorID != "" {
+		actorID, err = resolveSubjectRef(ctx, actorID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if mspID == "Org3MSP" {
+		if actorID != "" && actorID != userID {
+			return nil, fmt.Errorf("người dùng %s không có quyền xem nhật ký của %s", userID, actorID)
+		}
+		actorID = userID
+	}
+	pageSize = normalizePageSize(pageSize)
+
+	selector := map[string]interface{}{
+		"recordType": AuditLogRecordType,
+	}
+	if actorID != "" {
+		selector["actorId"] = actorID
+	}
+	if action != "" {
+		selector["action"] = action
+	}
+	if entityID != "" {
+		selector["entityId"] = entityID
+	}
+	timeRange := map[string]interface{}{}
+	if fromDate != "" {
+		from, err := parseDateBoundary(fromDate, false)
+		if err != nil {
+			return nil, err
+		}
+		timeRange["$gte"] = from
+	}
+	if toDate != "" {
+		to, err := parseDateBoundary(toDate, true)
+		if err != nil {
+			return nil, err
+		}
+		timeRange["$lte"] = to
+	}
+	if len(timeRange) > 0 {
+		selector["timestampNano"] = timeRange
+	}
+
+	var resultsIterator shim.StateQueryIteratorInterface
+	var metadata *peer.QueryResponseMetadata
+	if len(selector) == 1 {
+		// Không có bộ lọc: duyệt theo thứ tự thời gian của composite key
+		resultsIterator, metadata, err = ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(AuditLogKeyPrefix, []string{}, pageSize, bookmark)
+	} else {
+		queryBytes, marshalErr := json.Marshal(map[string]interface{}{
+			"selector": selector,
+			"sort":     []map[string]string{{"recordType": "asc"}, {"timestampNano": "asc"}},
+		})
+		if marshalErr != nil {
+			return nil, fmt.Errorf("lỗi khi tạo truy vấn nhật ký: %v", marshalErr)
+		}
+		resultsIterator, metadata, err = ctx.GetStub().GetQueryResultWithPagination(string(queryBytes), pageSize, bookmark)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn nhật ký: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	page := &AuditLogPage{
+		Records:      []*AuditLogEntry{},
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+	}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi đọc nhật ký: %v", err)
+		}
+		if KeyNamespace(ctx, response.Key) != AuditLogKeyPrefix {
+			continue
+		}
+		var entry AuditLogEntry
+		if err := json.Unmarshal(response.Value, &entry); err != nil {
+			return nil, fmt.Errorf("lỗi khi giải mã nhật ký: %v", err)
+		}
+		if entry.Details == nil {
+			entry.Details = map[string]string{}
+		}
+		page.Records = append(page.Records, &entry)
+	}
+	return page, nil
+}
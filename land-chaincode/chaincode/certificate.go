@@ -0,0 +1,380 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Trạng thái giấy chứng nhận
+const (
+	CertificateStatusActive     = "ACTIVE"     // Đang có hiệu lực
+	CertificateStatusRevoked    = "REVOKED"    // Đã thu hồi
+	CertificateStatusSuperseded = "SUPERSEDED" // Đã được thay thế bởi GCN mới
+)
+
+// getCertificate đọc giấy chứng nhận theo mã (trả về nil nếu không tồn tại)
+func getCertificate(ctx contractapi.TransactionContextInterface, certificateID string) (*Certificate, error) {
+	data, err := GetCertificateState(ctx, certificateID)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn giấy chứng nhận %s: %v", certificateID, err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	var cert Certificate
+	if err := json.Unmarshal(data, &cert); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã giấy chứng nhận %s: %v", certificateID, err)
+	}
+	return &cert, nil
+}
+
+// putCertificate lưu giấy chứng nhận cùng các chỉ mục theo số seri và thửa đất
+func putCertificate(ctx contractapi.TransactionContextInterface, cert *Certificate) error {
+	certJSON, err := json.Marshal(cert)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa giấy chứng nhận: %v", err)
+	}
+	if err := PutCertificateState(ctx, cert.CertificateID, certJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu giấy chứng nhận %s: %v", cert.CertificateID, err)
+	}
+
+	landIndexKey, err := ctx.GetStub().CreateCompositeKey(CertificateLandIndex, []string{cert.LandParcelID, cert.CertificateID})
+	if err != nil {
+		return fmt.Errorf("lỗi khi tạo chỉ mục thửa đất cho GCN %s: %v", cert.CertificateID, err)
+	}
+	if err := ctx.GetStub().PutState(landIndexKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("lỗi khi lưu chỉ mục thửa đất cho GCN %s: %v", cert.CertificateID, err)
+	}
+
+	if cert.SerialNumber != "" {
+		serialKey, err := ctx.GetStub().CreateCompositeKey(CertificateSerialIndex, []string{cert.SerialNumber})
+		if err != nil {
+			return fmt.Errorf("lỗi khi tạo chỉ mục số seri %s: %v", cert.SerialNumber, err)
+		}
+		if err := ctx.GetStub().PutState(serialKey, []byte(cert.CertificateID)); err != nil {
+			return fmt.Errorf("lỗi khi lưu chỉ mục số seri %s: %v", cert.SerialNumber, err)
+		}
+	}
+	return nil
+}
+
+// getCertificateIDBySerial tra cứu mã GCN theo số seri (rỗng nếu chưa được sử dụng)
+func getCertificateIDBySerial(ctx contractapi.TransactionContextInterface, serialNumber string) (string, error) {
+	serialKey, err := ctx.GetStub().CreateCompositeKey(CertificateSerialIndex, []string{serialNumber})
+	if err != nil {
+		return "", fmt.Errorf("lỗi khi tạo chỉ mục số seri %s: %v", serialNumber, err)
+	}
+	data, err := ctx.GetStub().GetState(serialKey)
+	if err != nil {
+		return "", fmt.Errorf("lỗi khi tra cứu số seri %s: %v", serialNumber, err)
+	}
+	return string(data), nil
+}
+
+// getCertificatesByLand liệt kê tất cả GCN từng cấp cho thửa đất
+func getCertificatesByLand(ctx contractapi.TransactionContextInterface, landParcelID string) ([]*Certificate, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(CertificateLandIndex, []string{landParcelID})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn GCN của thửa đất %s: %v", landParcelID, err)
+	}
+	defer resultsIterator.Close()
+
+	certificates := []*Certificate{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi đọc chỉ mục GCN: %v", err)
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil || len(attributes) != 2 {
+			continue
+		}
+		cert, err := getCertificate(ctx, attributes[1])
+		if err != nil {
+			return nil, err
+		}
+		if cert != nil {
+			certificates = append(certificates, cert)
+		}
+	}
+	return certificates, nil
+}
+
+// retireLandCertificates chuyển các GCN đang hiệu lực của thửa đất sang REVOKED hoặc SUPERSEDED
+// GCN cấp trước khi có bản ghi Certificate (chỉ lưu trên Land) được ghi nhận lại để không mất lịch sử
+func retireLandCertificates(ctx contractapi.TransactionContextInterface, land *Land, status, reason, supersededBy, userID string, txTime time.Time) error {
+	certificates, err := getCertificatesByLand(ctx, land.ID)
+	if err != nil {
+		return err
+	}
+
+	tracked := land.CertificateID == ""
+	for _, cert := range certificates {
+		if cert.CertificateID == land.CertificateID {
+			tracked = true
+		}
+		if cert.Status != CertificateStatusActive {
+			continue
+		}
+		cert.Status = status
+		cert.RevokeReason = reason
+		cert.RevokedBy = userID
+		cert.RevokedAt = txTime
+		cert.SupersededBy = supersededBy
+		cert.UpdatedAt = txTime
+		if err := putCertificate(ctx, cert); err != nil {
+			return err
+		}
+	}
+
+	if !tracked {
+		existing, err := getCertificate(ctx, land.CertificateID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			legacy := &Certificate{
+				CertificateID: land.CertificateID,
+				LandParcelID:  land.ID,
+				HolderID:      land.OwnerID,
+				IssueDate:     land.IssueDate,
+				LegalInfo:     land.LegalInfo,
+				Status:        status,
+				RevokeReason:  reason,
+				RevokedBy:     userID,
+				RevokedAt:     txTime,
+				SupersededBy:  supersededBy,
+				CreatedAt:     txTime,
+				UpdatedAt:     txTime,
+			}
+			// Trước đây mã GCN trên thửa đất thường là IPFS hash của bản PDF
+			if ValidateIPFSHash(land.CertificateID) == nil {
+				legacy.PdfCID = land.CertificateID
+			}
+			if err := putCertificate(ctx, legacy); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// revokeLandCertificates thu hồi các GCN đang hiệu lực của thửa đất khi có biến động
+func revokeLandCertificates(ctx contractapi.TransactionContextInterface, land *Land, reason, userID string, txTime time.Time) error {
+	return retireLandCertificates(ctx, land, CertificateStatusRevoked, reason, "", userID, txTime)
+}
+
+// issueCertificate tạo GCN mới cho thửa đất, thay thế GCN đang hiệu lực và cập nhật thông tin GCN trên thửa đất
+// Hàm không lưu thửa đất, người gọi chịu trách nhiệm lưu lại land
+func issueCertificate(ctx contractapi.TransactionContextInterface, land *Land, serialNumber, pdfCID, legalInfo, userID string, txTime time.Time) (*Certificate, error) {
+	if err := ValidateIPFSHash(pdfCID); err != nil {
+		return nil, fmt.Errorf("CID bản PDF giấy chứng nhận không hợp lệ: %v", err)
+	}
+
+	certificateID := fmt.Sprintf("GCN_%s_%d", land.ID, txTime.Unix())
+	existing, err := getCertificate(ctx, certificateID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("giấy chứng nhận %s đã tồn tại", certificateID)
+	}
+	// Số seri không bắt buộc với cấp đổi tự động, khi đó dùng mã GCN làm số seri
+	serialNumber = strings.TrimSpace(serialNumber)
+	if serialNumber == "" {
+		serialNumber = certificateID
+	}
+	usedBy, err := getCertificateIDBySerial(ctx, serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if usedBy != "" {
+		return nil, fmt.Errorf("số seri %s đã được sử dụng cho giấy chứng nhận %s", serialNumber, usedBy)
+	}
+
+	if err := retireLandCertificates(ctx, land, CertificateStatusSuperseded, fmt.Sprintf("Được thay thế bởi GCN %s", certificateID), certificateID, userID, txTime); err != nil {
+		return nil, err
+	}
+
+	cert := &Certificate{
+		CertificateID: certificateID,
+		SerialNumber:  serialNumber,
+		LandParcelID:  land.ID,
+		HolderID:      land.OwnerID,
+		IssueDate:     txTime,
+		IssuedBy:      userID,
+		PdfCID:        pdfCID,
+		LegalInfo:     legalInfo,
+		Status:        CertificateStatusActive,
+		CreatedAt:     txTime,
+		UpdatedAt:     txTime,
+	}
+	if err := putCertificate(ctx, cert); err != nil {
+		return nil, err
+	}
+
+	land.CertificateID = certificateID
+	land.IssueDate = txTime
+	land.LegalInfo = legalInfo
+	land.UpdatedAt = txTime
+	return cert, nil
+}
+
+// ========================================
+// CERTIFICATE MANAGEMENT FUNCTIONS
+// ========================================
+
+// IssueCertificate - Cấp giấy chứng nhận quyền sử dụng đất cho thửa đất (chỉ Org1)
+func (s *LandRegistryChaincode) IssueCertificate(ctx contractapi.TransactionContextInterface, serialNumber, landParcelID, pdfCID, legalInfo string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(serialNumber) == "" {
+		return fmt.Errorf("số seri giấy chứng nhận không được để trống")
+	}
+	if strings.TrimSpace(legalInfo) == "" {
+		return fmt.Errorf("thông tin pháp lý không được để trống")
+	}
+	land, err := s.QueryLandByID(ctx, landParcelID)
+	if err != nil {
+		return err
+	}
+	if err := VerifyLandLegalStatus(ctx, landParcelID, []string{"Đang tranh chấp", "Đang thế chấp"}); err != nil {
+		return err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	cert, err := issueCertificate(ctx, land, serialNumber, pdfCID, legalInfo, userID, txTime)
+	if err != nil {
+		return err
+	}
+	landJSON, err := json.Marshal(land)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
+	}
+	if err := PutLandState(ctx, landParcelID, landJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
+	}
+	return RecordAuditLog(ctx, "ISSUE_CERTIFICATE", userID, CertificateKeyPrefix, cert.CertificateID,
+		fmt.Sprintf("Cấp GCN %s (seri %s) cho thửa đất %s", cert.CertificateID, cert.SerialNumber, landParcelID),
+		map[string]string{"landParcelId": landParcelID, "serialNumber": cert.SerialNumber, "holderId": cert.HolderID})
+}
+
+// RevokeCertificate - Thu hồi giấy chứng nhận đang có hiệu lực (chỉ Org1)
+func (s *LandRegistryChaincode) RevokeCertificate(ctx contractapi.TransactionContextInterface, certificateID, reason string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("lý do thu hồi không được để trống")
+	}
+	cert, err := getCertificate(ctx, certificateID)
+	if err != nil {
+		return err
+	}
+	if cert == nil {
+		return fmt.Errorf("giấy chứng nhận %s không tồn tại", certificateID)
+	}
+	if cert.Status != CertificateStatusActive {
+		return fmt.Errorf("giấy chứng nhận %s không còn hiệu lực (trạng thái %s)", certificateID, cert.Status)
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	cert.Status = CertificateStatusRevoked
+	cert.RevokeReason = reason
+	cert.RevokedBy = userID
+	cert.RevokedAt = txTime
+	cert.UpdatedAt = txTime
+	if err := putCertificate(ctx, cert); err != nil {
+		return err
+	}
+
+	// Gỡ GCN khỏi thửa đất nếu đây là GCN hiện hành
+	land, err := s.QueryLandByID(ctx, cert.LandParcelID)
+	if err != nil {
+		return err
+	}
+	if land.CertificateID == certificateID {
+		land.CertificateID = ""
+		land.IssueDate = time.Time{}
+		land.LegalInfo = fmt.Sprintf("Giấy chứng nhận đã bị thu hồi: %s", reason)
+		land.UpdatedAt = txTime
+		landJSON, err := json.Marshal(land)
+		if err != nil {
+			return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
+		}
+		if err := PutLandState(ctx, land.ID, landJSON); err != nil {
+			return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
+		}
+	}
+	return RecordAuditLog(ctx, "REVOKE_CERTIFICATE", userID, CertificateKeyPrefix, certificateID,
+		fmt.Sprintf("Thu hồi GCN %s của thửa đất %s", certificateID, cert.LandParcelID),
+		map[string]string{"landParcelId": cert.LandParcelID, "reason": reason})
+}
+
+// QueryCertificateByID - Truy vấn giấy chứng nhận theo mã
+func (s *LandRegistryChaincode) QueryCertificateByID(ctx contractapi.TransactionContextInterface, certificateID string) (*Certificate, error) {
+	cert, err := getCertificate(ctx, certificateID)
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, fmt.Errorf("giấy chứng nhận %s không tồn tại", certificateID)
+	}
+	// Org3 chỉ xem được GCN của mình hoặc của thửa đất mình đang sở hữu
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if mspID == "Org3MSP" {
+		userID, err := GetCallerID(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if cert.HolderID != userID {
+			if _, err := s.QueryLandByID(ctx, cert.LandParcelID); err != nil {
+				return nil, fmt.Errorf("người dùng %s không có quyền xem giấy chứng nhận %s", userID, certificateID)
+			}
+		}
+	}
+	return cert, nil
+}
+
+// QueryCertificateBySerial - Truy vấn giấy chứng nhận theo số seri
+func (s *LandRegistryChaincode) QueryCertificateBySerial(ctx contractapi.TransactionContextInterface, serialNumber string) (*Certificate, error) {
+	certificateID, err := getCertificateIDBySerial(ctx, serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	if certificateID == "" {
+		return nil, fmt.Errorf("không tìm thấy giấy chứng nhận có số seri %s", serialNumber)
+	}
+	return s.QueryCertificateByID(ctx, certificateID)
+}
+
+// QueryCertificatesByLand - Truy vấn lịch sử giấy chứng nhận của thửa đất
+func (s *LandRegistryChaincode) QueryCertificatesByLand(ctx contractapi.TransactionContextInterface, landParcelID string) ([]*Certificate, error) {
+	// QueryLandByID kiểm tra quyền truy cập của Org3
+	if _, err := s.QueryLandByID(ctx, landParcelID); err != nil {
+		return nil, err
+	}
+	return getCertificatesByLand(ctx, landParcelID)
+}
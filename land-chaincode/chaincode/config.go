@@ -0,0 +1,205 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// chaincodeConfigID mã bản ghi cấu hình duy nhất của chaincode
+const chaincodeConfigID = "CHAINCODE"
+
+// DefaultLenderMSP MSP mặc định của tổ chức tín dụng khi chưa cấu hình
+const DefaultLenderMSP = "Org4MSP"
+
+// DefaultAreaTolerance sai số diện tích cho phép mặc định (m²) khi tách, hợp thửa
+const DefaultAreaTolerance = 0.1
+
+// DefaultGeometryAreaTolerancePct sai số tương đối mặc định (%) giữa diện tích kê khai và diện tích tính từ hình học
+const DefaultGeometryAreaTolerancePct = 1.0
+
+// ChaincodeConfig cấu hình vận hành lưu trên sổ cái, do Org1 quản lý
+type ChaincodeConfig struct {
+	LenderMSPs               []string  `json:"lenderMsps"`               // Các MSP được phép đăng ký/giải chấp thế chấp
+	AreaTolerance            float64   `json:"areaTolerance"`            // Sai số diện tích cho phép (m²) giữa thửa gốc và các thửa sau tách, hợp
+	GeometryAreaTolerancePct float64   `json:"geometryAreaTolerancePct"` // Sai số tương đối (%) giữa diện tích kê khai và diện tích tính từ hình học
+	UpdatedBy                string    `json:"updatedBy"`                // Mã định danh người cập nhật gần nhất
+	UpdatedAt                time.Time `json:"updatedAt"`                // Thời gian cập nhật gần nhất
+}
+
+// GetChaincodeConfig đọc cấu hình chaincode, trả về cấu hình mặc định nếu chưa thiết lập
+func GetChaincodeConfig(ctx contractapi.TransactionContextInterface) (*ChaincodeConfig, error) {
+	key, err := createEntityKey(ctx, ConfigKeyPrefix, chaincodeConfigID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi đọc cấu hình chaincode: %v", err)
+	}
+	config := &ChaincodeConfig{LenderMSPs: []string{DefaultLenderMSP}, AreaTolerance: DefaultAreaTolerance, GeometryAreaTolerancePct: DefaultGeometryAreaTolerancePct}
+	if data == nil {
+		return config, nil
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã cấu hình chaincode: %v", err)
+	}
+	if config.LenderMSPs == nil {
+		config.LenderMSPs = []string{}
+	}
+	return config, nil
+}
+
+// putChaincodeConfig lưu cấu hình chaincode
+func putChaincodeConfig(ctx contractapi.TransactionContextInterface, config *ChaincodeConfig) error {
+	key, err := createEntityKey(ctx, ConfigKeyPrefix, chaincodeConfigID)
+	if err != nil {
+		return err
+	}
+	configJSON, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa cấu hình chaincode: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, configJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu cấu hình chaincode: %v", err)
+	}
+	return nil
+}
+
+// CheckLenderOrganization kiểm tra người gọi thuộc tổ chức tín dụng đã cấu hình, trả về MSP của người gọi
+func CheckLenderOrganization(ctx contractapi.TransactionContextInterface) (string, error) {
+	config, err := GetChaincodeConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !containsString(config.LenderMSPs, mspID) {
+		return "", fmt.Errorf("tổ chức %s không phải tổ chức tín dụng được phép nhận thế chấp", mspID)
+	}
+	return mspID, nil
+}
+
+// SetLenderOrganizations - Cấu hình danh sách MSP của tổ chức tín dụng (chỉ Org1)
+func (s *LandRegistryChaincode) SetLenderOrganizations(ctx contractapi.TransactionContextInterface, mspIDsStr string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	var mspIDs []string
+	if err := json.Unmarshal([]byte(mspIDsStr), &mspIDs); err != nil {
+		return fmt.Errorf("lỗi khi giải mã danh sách MSP: %v", err)
+	}
+	lenderMSPs := []string{}
+	for _, mspID := range mspIDs {
+		mspID = strings.TrimSpace(mspID)
+		if mspID == "" || containsString(lenderMSPs, mspID) {
+			continue
+		}
+		// Các tổ chức vận hành hệ thống không được kiêm nhiệm vai trò nhận thế chấp
+		if containsString([]string{"Org1MSP", "Org2MSP", "Org3MSP"}, mspID) {
+			return fmt.Errorf("tổ chức %s không thể được cấu hình là tổ chức tín dụng", mspID)
+		}
+		lenderMSPs = append(lenderMSPs, mspID)
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	config, err := GetChaincodeConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.LenderMSPs = lenderMSPs
+	config.UpdatedBy = userID
+	config.UpdatedAt = txTime
+	if err := putChaincodeConfig(ctx, config); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "SET_LENDER_ORGANIZATIONS", userID, ConfigKeyPrefix, chaincodeConfigID,
+		fmt.Sprintf("Cấu hình tổ chức tín dụng: %v", lenderMSPs), map[string]string{"lenderMsps": strings.Join(lenderMSPs, ",")})
+}
+
+// SetAreaTolerance - Cấu hình sai số diện tích cho phép (m²) khi tách, hợp thửa (chỉ Org1)
+func (s *LandRegistryChaincode) SetAreaTolerance(ctx contractapi.TransactionContextInterface, toleranceStr string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	tolerance, err := parseFloat(toleranceStr)
+	if err != nil {
+		return fmt.Errorf("lỗi khi chuyển đổi sai số diện tích: %v", err)
+	}
+	if tolerance < 0 {
+		return fmt.Errorf("sai số diện tích không được âm")
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	config, err := GetChaincodeConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.AreaTolerance = tolerance
+	config.UpdatedBy = userID
+	config.UpdatedAt = txTime
+	if err := putChaincodeConfig(ctx, config); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "SET_AREA_TOLERANCE", userID, ConfigKeyPrefix, chaincodeConfigID,
+		fmt.Sprintf("Cấu hình sai số diện tích: %s m²", toleranceStr), map[string]string{"areaTolerance": toleranceStr})
+}
+
+// SetGeometryAreaTolerance - Cấu hình sai số tương đối (%) giữa diện tích kê khai và diện tích tính từ hình học (chỉ Org1)
+func (s *LandRegistryChaincode) SetGeometryAreaTolerance(ctx contractapi.TransactionContextInterface, tolerancePctStr string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	tolerancePct, err := parseFloat(tolerancePctStr)
+	if err != nil {
+		return fmt.Errorf("lỗi khi chuyển đổi sai số diện tích: %v", err)
+	}
+	if tolerancePct < 0 || tolerancePct > 100 {
+		return fmt.Errorf("sai số tương đối phải nằm trong khoảng 0-100%%")
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	config, err := GetChaincodeConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.GeometryAreaTolerancePct = tolerancePct
+	config.UpdatedBy = userID
+	config.UpdatedAt = txTime
+	if err := putChaincodeConfig(ctx, config); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "SET_GEOMETRY_AREA_TOLERANCE", userID, ConfigKeyPrefix, chaincodeConfigID,
+		fmt.Sprintf("Cấu hình sai số diện tích theo hình học: %s%%", tolerancePctStr), map[string]string{"geometryAreaTolerancePct": tolerancePctStr})
+}
+
+// GetChaincodeConfiguration - Truy vấn cấu hình chaincode hiện hành
+func (s *LandRegistryChaincode) GetChaincodeConfiguration(ctx contractapi.TransactionContextInterface) (*ChaincodeConfig, error) {
+	return GetChaincodeConfig(ctx)
+}
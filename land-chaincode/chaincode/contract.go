@@ -3,6 +3,8 @@ package chaincode
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 	"time"
 
@@ -32,10 +34,17 @@ func (s *LandRegistryChaincode) Init(ctx contractapi.TransactionContextInterface
 // LAND PARCEL MANAGEMENT FUNCTIONS
 
 // CreateLandParcel - Tạo thửa đất mới
-func (s *LandRegistryChaincode) CreateLandParcel(ctx contractapi.TransactionContextInterface, id, ownerID, location, landUsePurpose, legalStatus, area, certificateID, legalInfo, geometryCID string, userID string) error {
+// CCCD chủ sử dụng được truyền qua transient map (khóa "ownerId"), dữ liệu công khai chỉ lưu mã định danh băm
+// Thửa đất đồng sở hữu truyền thêm danh sách "coOwners" (xem SetLandCoOwners)
+// geometry (không bắt buộc): GeoJSON Polygon/MultiPolygon tọa độ VN-2000, dùng kiểm tra diện tích kê khai và lưu SHA-256
+func (s *LandRegistryChaincode) CreateLandParcel(ctx contractapi.TransactionContextInterface, id, location, landUsePurpose, legalStatus, area, certificateID, legalInfo, geometryCID, geometry string) error {
 	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
 		return err
 	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
 	areaFloat, err := parseFloat(area)
 	if err != nil {
 		return fmt.Errorf("lỗi khi chuyển đổi diện tích: %v", err)
@@ -44,6 +53,15 @@ func (s *LandRegistryChaincode) CreateLandParcel(ctx contractapi.TransactionCont
 	if err != nil {
 		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
 	}
+	ownerID, err := transientSubjectRef(ctx, "ownerId", userID, txTime, true)
+	if err != nil {
+		return fmt.Errorf("CCCD chủ sử dụng không hợp lệ: %v", err)
+	}
+	// Đồng sở hữu (không bắt buộc) được truyền qua transient map (khóa "coOwners")
+	coOwners, err := transientCoOwners(ctx, "coOwners", userID, txTime, false)
+	if err != nil {
+		return err
+	}
 
 	// Validate certificate information - khi có trạng thái pháp lý thì phải có đầy đủ thông tin GCN
 	// Trừ các trạng thái đặc biệt: "", "Đang tranh chấp", "Đang thế chấp"
@@ -53,12 +71,25 @@ func (s *LandRegistryChaincode) CreateLandParcel(ctx contractapi.TransactionCont
 		}
 	}
 
+	// Mục đích sử dụng phải có trong danh mục và đang được sử dụng
+	if err := ValidateLandUsePurpose(ctx, landUsePurpose); err != nil {
+		return err
+	}
+
 	// Validate geometry CID nếu được cung cấp
 	if geometryCID != "" {
 		if err := ValidateIPFSHash(geometryCID); err != nil {
 			return fmt.Errorf("geometry CID không hợp lệ: %v", err)
 		}
 	}
+	// Diện tích kê khai phải khớp với diện tích tính từ hình học (nếu được cung cấp)
+	var parsedGeometry *ParcelGeometry
+	if strings.TrimSpace(geometry) != "" {
+		parsedGeometry, err = checkDeclaredArea(ctx, []byte(geometry), id, areaFloat)
+		if err != nil {
+			return fmt.Errorf("hình học thửa đất %s không hợp lệ: %v", id, err)
+		}
+	}
 
 	land := Land{
 		ID:             id,
@@ -73,6 +104,23 @@ func (s *LandRegistryChaincode) CreateLandParcel(ctx contractapi.TransactionCont
 		CreatedAt:      txTime,
 		UpdatedAt:      txTime,
 	}
+	if parsedGeometry != nil {
+		land.GeometryHash = parsedGeometry.Hash
+		land.BBox = parsedGeometry.BBox
+	}
+	if len(coOwners) > 0 {
+		hasOwner := false
+		for _, coOwner := range coOwners {
+			hasOwner = hasOwner || coOwner.OwnerID == ownerID
+		}
+		if !hasOwner {
+			return fmt.Errorf("chủ sử dụng phải có tên trong danh sách đồng sở hữu")
+		}
+		// Chủ sử dụng theo "ownerId" là người đại diện của thửa đất
+		if len(coOwners) > 1 {
+			land.CoOwners = coOwners
+		}
+	}
 
 	// Set IssueDate and LegalInfo khi có trạng thái pháp lý hoặc certificateID
 	// Trừ các trạng thái đặc biệt: "", "Đang tranh chấp", "Đang thế chấp"
@@ -91,14 +139,18 @@ func (s *LandRegistryChaincode) CreateLandParcel(ctx contractapi.TransactionCont
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
 	}
-	if err := ctx.GetStub().PutState(id, landJSON); err != nil {
+	if err := PutLandState(ctx, id, landJSON); err != nil {
 		return fmt.Errorf("lỗi khi lưu thửa đất: %v", err)
 	}
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "CREATE_LAND_PARCEL", userID, fmt.Sprintf("Tạo thửa đất %s", id))
+	if err := updateLandSpatialIndex(ctx, id, nil, land.BBox); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "CREATE_LAND_PARCEL", userID, LandKeyPrefix, id, fmt.Sprintf("Tạo thửa đất %s", id), nil)
 }
 
 // UpdateLandParcel - Cập nhật thông tin thửa đất
-func (s *LandRegistryChaincode) UpdateLandParcel(ctx contractapi.TransactionContextInterface, id, area, location, landUsePurpose, legalStatus, certificateID, legalInfo, geometryCID string) error {
+// geometry (không bắt buộc): GeoJSON mới của thửa đất, dùng kiểm tra diện tích và cập nhật SHA-256, khung bao
+func (s *LandRegistryChaincode) UpdateLandParcel(ctx contractapi.TransactionContextInterface, id, area, location, landUsePurpose, legalStatus, certificateID, legalInfo, geometryCID, geometry string) error {
 	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
 		return err
 	}
@@ -113,6 +165,10 @@ func (s *LandRegistryChaincode) UpdateLandParcel(ctx contractapi.TransactionCont
 	if err := VerifyLandLegalStatus(ctx, id, []string{"Đang tranh chấp", "Đang thế chấp"}); err != nil {
 		return err
 	}
+	// Không chỉnh sửa thửa đất đang có giao dịch xử lý
+	if err := VerifyParcelNotLocked(ctx, id); err != nil {
+		return err
+	}
 	areaFloat, err := parseFloat(area)
 	if err != nil {
 		return fmt.Errorf("lỗi khi chuyển đổi diện tích: %v", err)
@@ -130,6 +186,13 @@ func (s *LandRegistryChaincode) UpdateLandParcel(ctx contractapi.TransactionCont
 		}
 	}
 
+	// Chỉ kiểm tra danh mục khi mục đích sử dụng thay đổi, mã cũ đã ngừng sử dụng vẫn được giữ nguyên
+	if landUsePurpose != existingLand.LandUsePurpose {
+		if err := ValidateLandUsePurpose(ctx, landUsePurpose); err != nil {
+			return err
+		}
+	}
+
 	// Xử lý geometry CID
 	if geometryCID != "" {
 		if err := ValidateIPFSHash(geometryCID); err != nil {
@@ -143,6 +206,8 @@ func (s *LandRegistryChaincode) UpdateLandParcel(ctx contractapi.TransactionCont
 	updatedLand := Land{
 		ID:             id,
 		OwnerID:        existingLand.OwnerID,
+		CoOwners:       existingLand.CoOwners,
+		LeaseIDs:       existingLand.LeaseIDs,
 		Area:           areaFloat,
 		Location:       location,
 		LandUsePurpose: landUsePurpose,
@@ -152,6 +217,19 @@ func (s *LandRegistryChaincode) UpdateLandParcel(ctx contractapi.TransactionCont
 		CreatedAt:      existingLand.CreatedAt,
 		UpdatedAt:      txTime,
 	}
+	if strings.TrimSpace(geometry) != "" {
+		parsedGeometry, err := checkDeclaredArea(ctx, []byte(geometry), id, areaFloat)
+		if err != nil {
+			return fmt.Errorf("hình học thửa đất %s không hợp lệ: %v", id, err)
+		}
+		updatedLand.GeometryHash = parsedGeometry.Hash
+		updatedLand.BBox = parsedGeometry.BBox
+	} else if geometryCID == existingLand.GeometryCID {
+		// SHA-256 và khung bao chỉ còn giá trị khi geometry CID không đổi
+		updatedLand.GeometryHash = existingLand.GeometryHash
+		updatedLand.BBox = existingLand.BBox
+		updatedLand.AreaOverride = existingLand.AreaOverride
+	}
 
 	// Handle certificate information updates
 	// Trừ các trạng thái đặc biệt: "", "Đang tranh chấp", "Đang thế chấp"
@@ -178,10 +256,13 @@ func (s *LandRegistryChaincode) UpdateLandParcel(ctx contractapi.TransactionCont
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
 	}
-	if err := ctx.GetStub().PutState(id, landJSON); err != nil {
+	if err := PutLandState(ctx, id, landJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
 	}
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "UPDATE_LAND_PARCEL", userID, fmt.Sprintf("Cập nhật thửa đất %s", id))
+	if err := updateLandSpatialIndex(ctx, id, existingLand.BBox, updatedLand.BBox); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "UPDATE_LAND_PARCEL", userID, LandKeyPrefix, id, fmt.Sprintf("Cập nhật thửa đất %s", id), nil)
 }
 
 // IssueLandCertificate - Hàm này đã bị xóa vì không còn cần thiết
@@ -192,7 +273,7 @@ func (s *LandRegistryChaincode) UpdateLandParcel(ctx contractapi.TransactionCont
 // ========================================
 
 // CreateDocument - Tạo tài liệu mới
-func (s *LandRegistryChaincode) CreateDocument(ctx contractapi.TransactionContextInterface, docID, docType, title, description, ipfsHash, fileType string, fileSize int64, status string, verifiedBy string) error {
+func (s *LandRegistryChaincode) CreateDocument(ctx contractapi.TransactionContextInterface, docID, docType, subType, title, description, ipfsHash, contentSHA256, fileType string, fileSize int64, status string, verifiedBy string) error {
 	userID, err := GetCallerID(ctx)
 	if err != nil {
 		return err
@@ -202,12 +283,26 @@ func (s *LandRegistryChaincode) CreateDocument(ctx contractapi.TransactionContex
 	if err := ValidateDocumentType(docType); err != nil {
 		return fmt.Errorf("loại tài liệu không hợp lệ: %v", err)
 	}
+	// Mã thành phần hồ sơ là tùy chọn, nếu có phải thuộc danh mục và khớp loại tài liệu
+	if subType != "" {
+		if err := ValidateDocumentSubType(docType, subType); err != nil {
+			return err
+		}
+	}
 
 	// Kiểm tra tính hợp lệ của IPFS hash
 	if err := ValidateIPFSHash(ipfsHash); err != nil {
 		return fmt.Errorf("hash IPFS không hợp lệ: %v", err)
 	}
 
+	// SHA-256 và kích thước file được ghi nhận để đối chiếu file tải về từ gateway sau này
+	if err := ValidateSHA256(contentSHA256); err != nil {
+		return err
+	}
+	if fileSize <= 0 {
+		return fmt.Errorf("kích thước file phải lớn hơn 0")
+	}
+
 	// Kiểm tra xem tài liệu đã tồn tại chưa
 	existingDoc, err := s.GetDocument(ctx, docID)
 	if err == nil && existingDoc != nil {
@@ -224,24 +319,37 @@ func (s *LandRegistryChaincode) CreateDocument(ctx contractapi.TransactionContex
 	if !IsValidDocumentStatus(status) {
 		return fmt.Errorf("trạng thái tài liệu không hợp lệ: %s", status)
 	}
+	// Chỉ Org2 (đơn vị thẩm định) được tạo tài liệu ở trạng thái khác PENDING, người xác thực là chính người gọi
+	if status != "PENDING" {
+		if err := CheckOrganization(ctx, []string{"Org2MSP"}); err != nil {
+			return fmt.Errorf("tài liệu mới phải ở trạng thái PENDING: %v", err)
+		}
+		verifiedBy = userID
+	} else {
+		verifiedBy = ""
+	}
 
 	// Tạo tài liệu mới
 	doc := &Document{
-		DocID:       docID,
-		Type:        docType,
-		Title:       title,
-		Description: description,
-		IPFSHash:    ipfsHash,
-		FileSize:    fileSize,
-		FileType:    fileType,
-		UploadedBy:  userID,
-		Status:      status,
-		VerifiedBy:  verifiedBy,
-		CreatedAt:   txTime,
-		UpdatedAt:   txTime,
+		DocID:         docID,
+		Type:          docType,
+		SubType:       subType,
+		Title:         title,
+		Description:   description,
+		IPFSHash:      ipfsHash,
+		ContentSHA256: NormalizeSHA256(contentSHA256),
+		FileSize:      fileSize,
+		FileType:      fileType,
+		UploadedBy:    userID,
+		Status:        status,
+		VerifiedBy:    verifiedBy,
+		CreatedAt:     txTime,
+		UpdatedAt:     txTime,
+		Version:       1,
 	}
 
 	// Set verified time if verified
+	// IntegrityVerifiedAt chỉ được ghi nhận khi Org2 đối chiếu nội dung qua VerifyDocument
 	if status == "VERIFIED" {
 		doc.VerifiedAt = txTime
 	}
@@ -251,11 +359,11 @@ func (s *LandRegistryChaincode) CreateDocument(ctx contractapi.TransactionContex
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa tài liệu: %v", err)
 	}
-	if err := ctx.GetStub().PutState(docID, docJSON); err != nil {
+	if err := PutDocumentState(ctx, docID, docJSON); err != nil {
 		return fmt.Errorf("lỗi khi lưu tài liệu: %v", err)
 	}
 
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "CREATE_DOCUMENT", userID, fmt.Sprintf("Tạo tài liệu %s", title))
+	return RecordAuditLog(ctx, "CREATE_DOCUMENT", userID, DocumentKeyPrefix, docID, fmt.Sprintf("Tạo tài liệu %s", title), nil)
 }
 
 // UpdateDocument - Cập nhật thông tin tài liệu
@@ -292,11 +400,11 @@ func (s *LandRegistryChaincode) UpdateDocument(ctx contractapi.TransactionContex
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa tài liệu: %v", err)
 	}
-	if err := ctx.GetStub().PutState(docID, docJSON); err != nil {
+	if err := PutDocumentState(ctx, docID, docJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật tài liệu: %v", err)
 	}
 
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "UPDATE_DOCUMENT", userID, fmt.Sprintf("Cập nhật tài liệu %s", docID))
+	return RecordAuditLog(ctx, "UPDATE_DOCUMENT", userID, DocumentKeyPrefix, docID, fmt.Sprintf("Cập nhật tài liệu %s", docID), nil)
 }
 
 // DeleteDocument - Xóa tài liệu
@@ -329,7 +437,7 @@ func (s *LandRegistryChaincode) DeleteDocument(ctx contractapi.TransactionContex
 	var linkedLandIDs []string
 	for landIterator.HasNext() {
 		landResponse, err := landIterator.Next()
-		if err != nil {
+		if err != nil || KeyNamespace(ctx, landResponse.Key) != LandKeyPrefix {
 			continue
 		}
 		var land Land
@@ -338,7 +446,7 @@ func (s *LandRegistryChaincode) DeleteDocument(ctx contractapi.TransactionContex
 		}
 	}
 	
-	txQueryString := fmt.Sprintf(`{"selector":{"documentIds":{"$elemMatch":{"$eq":"%s"}},"type":{"$ne":"LOG"}}}`, docID)
+	txQueryString := fmt.Sprintf(`{"selector":{"documentIds":{"$elemMatch":{"$eq":"%s"}}}}`, docID)
 	txIterator, err := ctx.GetStub().GetQueryResult(txQueryString)
 	if err != nil {
 		return fmt.Errorf("lỗi khi kiểm tra liên kết giao dịch: %v", err)
@@ -348,7 +456,7 @@ func (s *LandRegistryChaincode) DeleteDocument(ctx contractapi.TransactionContex
 	var linkedTxIDs []string
 	for txIterator.HasNext() {
 		txResponse, err := txIterator.Next()
-		if err != nil {
+		if err != nil || KeyNamespace(ctx, txResponse.Key) != TransactionKeyPrefix {
 			continue
 		}
 		var tx Transaction
@@ -370,12 +478,15 @@ func (s *LandRegistryChaincode) DeleteDocument(ctx contractapi.TransactionContex
 		return fmt.Errorf(errorMsg)
 	}
 
-	// Xóa tài liệu
-	if err := ctx.GetStub().DelState(docID); err != nil {
+	// Xóa tài liệu cùng các phiên bản cũ
+	if err := deleteDocumentVersions(ctx, docID); err != nil {
+		return err
+	}
+	if err := DelDocumentState(ctx, docID); err != nil {
 		return fmt.Errorf("lỗi khi xóa tài liệu: %v", err)
 	}
 
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "DELETE_DOCUMENT", userID, fmt.Sprintf("Xóa tài liệu %s", docID))
+	return RecordAuditLog(ctx, "DELETE_DOCUMENT", userID, DocumentKeyPrefix, docID, fmt.Sprintf("Xóa tài liệu %s", docID), nil)
 }
 
 // VerifyDocument - Chứng thực tài liệu (chỉ Org2)
@@ -409,21 +520,26 @@ func (s *LandRegistryChaincode) VerifyDocument(ctx contractapi.TransactionContex
 
 	// Chứng thực tài liệu
 	SetDocumentVerified(doc, userID, txTime)
+	// Org2 xác thực trên file đã đối chiếu với SHA-256 được ghi nhận
+	if doc.ContentSHA256 != "" {
+		doc.IntegrityVerifiedAt = txTime
+	}
+	appendDocumentReview(doc, DocumentReviewVerify, "", "", userID, txTime)
 
 	// Lưu tài liệu
 	docJSON, err := json.Marshal(doc)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa tài liệu: %v", err)
 	}
-	if err := ctx.GetStub().PutState(docID, docJSON); err != nil {
+	if err := PutDocumentState(ctx, docID, docJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật tài liệu: %v", err)
 	}
 
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "VERIFY_DOCUMENT", userID, fmt.Sprintf("Chứng thực tài liệu %s", docID))
+	return RecordAuditLog(ctx, "VERIFY_DOCUMENT", userID, DocumentKeyPrefix, docID, fmt.Sprintf("Chứng thực tài liệu %s", docID), nil)
 }
 
 // RejectDocument - Từ chối tài liệu (chỉ Org2)
-func (s *LandRegistryChaincode) RejectDocument(ctx contractapi.TransactionContextInterface, docID, reason string) error {
+func (s *LandRegistryChaincode) RejectDocument(ctx contractapi.TransactionContextInterface, docID, reasonCode, reason string) error {
 	// Chỉ Org2 mới được từ chối tài liệu
 	if err := CheckOrganization(ctx, []string{"Org2MSP"}); err != nil {
 		return err
@@ -450,24 +566,27 @@ func (s *LandRegistryChaincode) RejectDocument(ctx contractapi.TransactionContex
 	if !CanRejectDocument(doc) {
 		return fmt.Errorf("tài liệu %s không thể bị từ chối (trạng thái: %s)", docID, doc.Status)
 	}
+	if err := ValidateRejectionReasonCode(reasonCode); err != nil {
+		return err
+	}
+	if reasonCode == "OTHER" && strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("phải ghi rõ lý do khi từ chối với mã OTHER")
+	}
 
-	// Từ chối tài liệu
+	// Từ chối tài liệu, lý do được lưu có cấu trúc trong lịch sử thẩm định
 	SetDocumentRejected(doc, userID, txTime)
-
-	// Lưu thông tin từ chối vào Description với format chuẩn để dễ nhận biết
-	rejectionInfo := fmt.Sprintf(" [REJECTED: %s | By: %s | At: %s]", reason, userID, txTime.Format("2006-01-02 15:04:05"))
-	doc.Description = doc.Description + rejectionInfo
+	appendDocumentReview(doc, DocumentReviewReject, reasonCode, reason, userID, txTime)
 
 	// Lưu tài liệu
 	docJSON, err := json.Marshal(doc)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa tài liệu: %v", err)
 	}
-	if err := ctx.GetStub().PutState(docID, docJSON); err != nil {
+	if err := PutDocumentState(ctx, docID, docJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật tài liệu: %v", err)
 	}
 
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "REJECT_DOCUMENT", userID, fmt.Sprintf("Từ chối tài liệu %s: %s", docID, reason))
+	return RecordAuditLog(ctx, "REJECT_DOCUMENT", userID, DocumentKeyPrefix, docID, fmt.Sprintf("Từ chối tài liệu %s: %s", docID, reason), map[string]string{"reasonCode": reasonCode, "reason": reason})
 }
 
 // LinkDocumentToLand - Link existing documents to land parcel after verification (supports multiple documents)
@@ -551,7 +670,7 @@ func (s *LandRegistryChaincode) LinkDocumentToLand(ctx contractapi.TransactionCo
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
 	}
-	if err := ctx.GetStub().PutState(landParcelID, landJSON); err != nil {
+	if err := PutLandState(ctx, landParcelID, landJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
 	}
 
@@ -561,7 +680,7 @@ func (s *LandRegistryChaincode) LinkDocumentToLand(ctx contractapi.TransactionCo
 		logMessage += fmt.Sprintf(". Một số lỗi: %v", errors)
 	}
 
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "LINK_DOCUMENTS_TO_LAND", userID, logMessage)
+	return RecordAuditLog(ctx, "LINK_DOCUMENTS_TO_LAND", userID, LandKeyPrefix, landParcelID, logMessage, nil)
 }
 
 // LinkDocumentToTransaction - Link existing documents to transaction (supports multiple documents)
@@ -605,8 +724,9 @@ func (s *LandRegistryChaincode) LinkDocumentToTransaction(ctx contractapi.Transa
 	}
 
 	// Kiểm tra giao dịch có ở trạng thái SUPPLEMENT_REQUESTED không (theo UC-18 cần yêu cầu bổ sung từ Org2)
-	if tx.Status != "SUPPLEMENT_REQUESTED" {
-		return fmt.Errorf("chỉ có thể liên kết tài liệu bổ sung khi giao dịch ở trạng thái yêu cầu bổ sung")
+	transition, err := ResolveTransition(ctx, tx, ActionSubmitSupplement)
+	if err != nil {
+		return fmt.Errorf("chỉ có thể liên kết tài liệu bổ sung khi giao dịch ở trạng thái yêu cầu bổ sung: %v", err)
 	}
 
 	var linkedDocs []string
@@ -654,15 +774,18 @@ func (s *LandRegistryChaincode) LinkDocumentToTransaction(ctx contractapi.Transa
 		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
 	}
 
-	// Cập nhật giao dịch - đặt lại status về PENDING để Org2 xử lý lại
+	// Cập nhật giao dịch - đưa hồ sơ về bước thẩm định để Org2 xử lý lại
 	tx.UpdatedAt = txTime
-	tx.Status = "PENDING"
+	tx.Status = transition.To
+	if err := enterTransactionStage(ctx, tx); err != nil {
+		return err
+	}
 
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(transactionID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, transactionID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
 	}
 
@@ -672,7 +795,7 @@ func (s *LandRegistryChaincode) LinkDocumentToTransaction(ctx contractapi.Transa
 		logMessage += fmt.Sprintf(". Một số lỗi: %v", errors)
 	}
 
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "LINK_SUPPLEMENT_DOCUMENTS_TO_TRANSACTION", userID, logMessage)
+	return RecordAuditLog(ctx, "LINK_SUPPLEMENT_DOCUMENTS_TO_TRANSACTION", userID, TransactionKeyPrefix, transactionID, logMessage, transactionAuditDetails(tx))
 }
 
 // ========================================
@@ -680,7 +803,8 @@ func (s *LandRegistryChaincode) LinkDocumentToTransaction(ctx contractapi.Transa
 // ========================================
 
 // CreateSplitRequest - Tạo yêu cầu tách thửa (auto-generate txID)
-func (s *LandRegistryChaincode) CreateSplitRequest(ctx contractapi.TransactionContextInterface, landParcelID, documentIdsStr, reason string) error {
+// proposedParcelsStr: JSON array các thửa đề xuất [{"id","area","geometryCid"}], có thể để trống
+func (s *LandRegistryChaincode) CreateSplitRequest(ctx contractapi.TransactionContextInterface, landParcelID, documentIdsStr, reason, proposedParcelsStr string) error {
 	if err := CheckOrganization(ctx, []string{"Org3MSP"}); err != nil {
 		return err
 	}
@@ -694,6 +818,13 @@ func (s *LandRegistryChaincode) CreateSplitRequest(ctx contractapi.TransactionCo
 	if err := VerifyLandLegalStatus(ctx, landParcelID, []string{"Đang tranh chấp", "Đang thế chấp"}); err != nil {
 		return err
 	}
+	// Thửa đất bị đóng băng khi đang có tranh chấp
+	if err := VerifyNoOpenDispute(ctx, landParcelID); err != nil {
+		return err
+	}
+	if err := VerifyNoActiveMortgage(ctx, landParcelID); err != nil {
+		return err
+	}
 	txTime, err := GetTxTimestampAsTime(ctx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
@@ -705,8 +836,20 @@ func (s *LandRegistryChaincode) CreateSplitRequest(ctx contractapi.TransactionCo
 			return fmt.Errorf("lỗi khi giải mã danh sách document IDs: %v", err)
 		}
 	}
+	// Parse các thửa đất đề xuất nếu có
+	var proposedParcels []ProposedParcel
+	if proposedParcelsStr != "" {
+		if err := json.Unmarshal([]byte(proposedParcelsStr), &proposedParcels); err != nil {
+			return fmt.Errorf("lỗi khi giải mã danh sách thửa đất đề xuất: %v", err)
+		}
+		for _, parcel := range proposedParcels {
+			if strings.TrimSpace(parcel.ID) == "" || parcel.Area <= 0 {
+				return fmt.Errorf("thửa đất đề xuất phải có mã và diện tích lớn hơn 0")
+			}
+		}
+	}
 	// Tự động tạo txID với timestamp
-	txID := fmt.Sprintf("TACH_THUA_%d_%s_%s", txTime.Unix(), callerID, landParcelID)
+	txID := fmt.Sprintf("TACH_THUA_%d_%s_%s", txTime.Unix(), shortSubjectRef(callerID), landParcelID)
 	// Tạo Details với lý do
 	details := fmt.Sprintf("Tách thửa đất %s", landParcelID)
 	if reason != "" {
@@ -719,25 +862,40 @@ func (s *LandRegistryChaincode) CreateSplitRequest(ctx contractapi.TransactionCo
 		ParcelIDs:    []string{}, // Để trống, sẽ cập nhật khi approve
 		FromOwnerID:  callerID,
 		ToOwnerID:    callerID,
-		Status:       "PENDING",
+		Status:       TxStatusPending,
 		Details:      details,
+		Payload:      &TransactionPayload{Split: &SplitPayload{ProposedParcels: proposedParcels}},
 		UserID:       callerID,
 		DocumentIDs:  documentIDs,
 		CreatedAt:    txTime,
 		UpdatedAt:    txTime,
 	}
+	// Các đồng sở hữu khác phải đồng ý trước khi hồ sơ được thẩm định
+	if err := initTransactionConsents(ctx, &tx); err != nil {
+		return err
+	}
+	if err := scheduleStageDeadline(ctx, &tx); err != nil {
+		return err
+	}
+	// Khóa thửa đất để ngăn các yêu cầu xung đột cho đến khi giao dịch kết thúc
+	if err := acquireParcelLocks(ctx, &tx); err != nil {
+		return err
+	}
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi lưu giao dịch: %v", err)
 	}
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "CREATE_SPLIT_REQUEST", callerID, fmt.Sprintf("Tạo yêu cầu tách thửa %s", txID))
+	return RecordAuditLog(ctx, "CREATE_SPLIT_REQUEST", callerID, TransactionKeyPrefix, txID, fmt.Sprintf("Tạo yêu cầu tách thửa %s", txID), transactionAuditDetails(&tx))
 }
 
 // CreateMergeRequest - Tạo yêu cầu hợp thửa (auto-generate txID)
-func (s *LandRegistryChaincode) CreateMergeRequest(ctx contractapi.TransactionContextInterface, parcelIDsStr, documentIdsStr, reason string) error {
+// selectedLandID: thửa đất được giữ lại làm thửa hợp nhất, mặc định là thửa đầu tiên
+// targetPurpose: mục đích sử dụng của thửa hợp nhất, bắt buộc khi các thửa khác mục đích sử dụng (hợp thửa kèm chuyển mục đích)
+// geometriesStr: JSON object mã thửa -> GeoJSON của từng thửa, dùng kiểm tra các thửa liền kề
+func (s *LandRegistryChaincode) CreateMergeRequest(ctx contractapi.TransactionContextInterface, parcelIDsStr, documentIdsStr, reason, selectedLandID, targetPurpose, geometriesStr string) error {
 	if err := CheckOrganization(ctx, []string{"Org3MSP"}); err != nil {
 		return err
 	}
@@ -749,20 +907,58 @@ func (s *LandRegistryChaincode) CreateMergeRequest(ctx contractapi.TransactionCo
 	if err := json.Unmarshal([]byte(parcelIDsStr), &parcelIDs); err != nil {
 		return fmt.Errorf("lỗi khi giải mã danh sách parcelIDs: %v", err)
 	}
+	parcelIDs, err = normalizeMergeParcelIDs(parcelIDs)
+	if err != nil {
+		return err
+	}
 	var totalArea float64
+	var baseLand *Land
+	var lands []*Land
 	for _, parcelID := range parcelIDs {
-		parcelID = strings.TrimSpace(parcelID)
 		if err := VerifyLandOwnership(ctx, parcelID, callerID); err != nil {
 			return err
 		}
 		if err := VerifyLandLegalStatus(ctx, parcelID, []string{"Đang tranh chấp", "Đang thế chấp"}); err != nil {
 			return err
 		}
+		// Thửa đất bị đóng băng khi đang có tranh chấp
+		if err := VerifyNoOpenDispute(ctx, parcelID); err != nil {
+			return err
+		}
+		if err := VerifyNoActiveMortgage(ctx, parcelID); err != nil {
+			return err
+		}
 		land, err := s.QueryLandByID(ctx, parcelID)
 		if err != nil {
 			return fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", parcelID, err)
 		}
+		if baseLand == nil {
+			baseLand = land
+		} else if !sameOwnership(baseLand, land) {
+			return fmt.Errorf("thửa đất %s không cùng chủ sử dụng và phần sở hữu với thửa đất %s", parcelID, baseLand.ID)
+		}
 		totalArea += land.Area
+		lands = append(lands, land)
+	}
+	// Các thửa phải cùng mục đích sử dụng, hoặc chỉ định mục đích sử dụng chung sau hợp thửa
+	targetPurpose, purposeChange, err := resolveMergePurpose(ctx, lands, targetPurpose)
+	if err != nil {
+		return err
+	}
+	// Các thửa phải liền kề nhau, kiểm tra theo hình học của từng thửa
+	geometries, err := parseMergeGeometries(geometriesStr)
+	if err != nil {
+		return err
+	}
+	if _, err := verifyMergeGeometries(lands, geometries); err != nil {
+		return err
+	}
+	// Xác định thửa đất được giữ lại
+	selectedLandID = strings.TrimSpace(selectedLandID)
+	if selectedLandID == "" {
+		selectedLandID = parcelIDs[0]
+	} else if !containsString(parcelIDs, selectedLandID) {
+		return fmt.Errorf("selectedLandID %s không nằm trong danh sách thửa đất %v", selectedLandID, parcelIDs)
 	}
 	txTime, err := GetTxTimestampAsTime(ctx)
 	if err != nil {
@@ -776,9 +972,12 @@ func (s *LandRegistryChaincode) CreateMergeRequest(ctx contractapi.TransactionCo
 		}
 	}
 	// Tự động tạo txID với timestamp
-	txID := fmt.Sprintf("HOP_THUA_%d_%s_%v", txTime.Unix(), callerID, parcelIDs)
+	txID := fmt.Sprintf("HOP_THUA_%d_%s_%v", txTime.Unix(), shortSubjectRef(callerID), parcelIDs)
 	// Tạo Details với lý do
 	details := fmt.Sprintf("Hợp nhất các thửa đất %v", parcelIDs)
+	if purposeChange {
+		details = fmt.Sprintf("%s, chuyển mục đích sử dụng sang %s", details, targetPurpose)
+	}
 	if reason != "" {
 		details = fmt.Sprintf("%s. Lý do: %s", details, reason)
 	}
@@ -789,25 +988,44 @@ func (s *LandRegistryChaincode) CreateMergeRequest(ctx contractapi.TransactionCo
 		ParcelIDs:    parcelIDs,
 		FromOwnerID:  callerID,
 		ToOwnerID:    callerID,
-		Status:       "PENDING",
+		Status:       TxStatusPending,
 		Details:      details,
-		UserID:       callerID,
-		DocumentIDs:  documentIDs,
-		CreatedAt:    txTime,
-		UpdatedAt:    txTime,
+		Payload: &TransactionPayload{Merge: &MergePayload{
+			SelectedLandID:   selectedLandID,
+			TargetPurpose:    targetPurpose,
+			PurposeChange:    purposeChange,
+			ParcelGeometries: geometries,
+		}},
+		UserID:      callerID,
+		DocumentIDs: documentIDs,
+		CreatedAt:   txTime,
+		UpdatedAt:   txTime,
+	}
+	// Các đồng sở hữu khác phải đồng ý trước khi hồ sơ được thẩm định
+	if err := initTransactionConsents(ctx, &tx); err != nil {
+		return err
+	}
+	if err := scheduleStageDeadline(ctx, &tx); err != nil {
+		return err
+	}
+	// Khóa thửa đất để ngăn các yêu cầu xung đột cho đến khi giao dịch kết thúc
+	if err := acquireParcelLocks(ctx, &tx); err != nil {
+		return err
 	}
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi lưu giao dịch: %v", err)
 	}
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "CREATE_MERGE_REQUEST", callerID, fmt.Sprintf("Tạo yêu cầu hợp thửa %s", txID))
+	return RecordAuditLog(ctx, "CREATE_MERGE_REQUEST", callerID, TransactionKeyPrefix, txID, fmt.Sprintf("Tạo yêu cầu hợp thửa %s", txID), transactionAuditDetails(&tx))
 }
 
 // CreateTransferRequest - Tạo yêu cầu chuyển nhượng (auto-generate txID)
-func (s *LandRegistryChaincode) CreateTransferRequest(ctx contractapi.TransactionContextInterface, landParcelID, toOwnerID, documentIdsStr, reason string) error {
+// declaredPrice: giá chuyển nhượng kê khai (VNĐ), có thể để trống
+// CCCD người nhận ("toOwnerId") và giá kê khai ("declaredPrice") được truyền qua transient map
+func (s *LandRegistryChaincode) CreateTransferRequest(ctx contractapi.TransactionContextInterface, landParcelID, documentIdsStr, reason string) error {
 	if err := CheckOrganization(ctx, []string{"Org3MSP"}); err != nil {
 		return err
 	}
@@ -821,10 +1039,38 @@ func (s *LandRegistryChaincode) CreateTransferRequest(ctx contractapi.Transactio
 	if err := VerifyLandLegalStatus(ctx, landParcelID, []string{"Đang tranh chấp", "Đang thế chấp"}); err != nil {
 		return err
 	}
+	// Thửa đất bị đóng băng khi đang có tranh chấp
+	if err := VerifyNoOpenDispute(ctx, landParcelID); err != nil {
+		return err
+	}
+	if err := VerifyNoActiveMortgage(ctx, landParcelID); err != nil {
+		return err
+	}
 	txTime, err := GetTxTimestampAsTime(ctx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
 	}
+	toOwnerID, err := transientSubjectRef(ctx, "toOwnerId", callerID, txTime, true)
+	if err != nil {
+		return fmt.Errorf("CCCD người nhận chuyển nhượng không hợp lệ: %v", err)
+	}
+	if toOwnerID == callerID {
+		return fmt.Errorf("không thể chuyển nhượng thửa đất cho chính mình")
+	}
+	declaredPrice, err := getTransientValue(ctx, "declaredPrice", false)
+	if err != nil {
+		return err
+	}
+	var priceFloat float64
+	if declaredPrice != "" {
+		priceFloat, err = parseFloat(declaredPrice)
+		if err != nil {
+			return fmt.Errorf("lỗi khi chuyển đổi giá chuyển nhượng: %v", err)
+		}
+		if priceFloat < 0 {
+			return fmt.Errorf("giá chuyển nhượng không được âm")
+		}
+	}
 
 	// Parse document IDs if provided
 	var documentIDs []string
@@ -835,7 +1081,7 @@ func (s *LandRegistryChaincode) CreateTransferRequest(ctx contractapi.Transactio
 	}
 
 	// Tự động tạo txID với timestamp
-	txID := fmt.Sprintf("CHUYEN_NHUONG_%d_%s_%s", txTime.Unix(), callerID, landParcelID)
+	txID := fmt.Sprintf("CHUYEN_NHUONG_%d_%s_%s", txTime.Unix(), shortSubjectRef(callerID), landParcelID)
 
 	// Tạo Details với lý do
 	details := fmt.Sprintf("Chuyển nhượng thửa đất %s từ %s sang %s", landParcelID, callerID, toOwnerID)
@@ -850,21 +1096,40 @@ func (s *LandRegistryChaincode) CreateTransferRequest(ctx contractapi.Transactio
 		ParcelIDs:    []string{}, // Khởi tạo empty slice thay vì nil
 		FromOwnerID:  callerID,
 		ToOwnerID:    toOwnerID,
-		Status:       "PENDING",
+		Status:       TxStatusPending,
 		Details:      details,
+		Payload:      &TransactionPayload{Transfer: &TransferPayload{RecipientID: toOwnerID}},
 		UserID:       callerID,    // Tự động điền người thực hiện
 		DocumentIDs:  documentIDs, // Sử dụng documentIDs được parse
 		CreatedAt:    txTime,
 		UpdatedAt:    txTime,
 	}
+	// Các đồng sở hữu khác phải đồng ý trước khi hồ sơ được thẩm định
+	if err := initTransactionConsents(ctx, &tx); err != nil {
+		return err
+	}
+	if err := scheduleStageDeadline(ctx, &tx); err != nil {
+		return err
+	}
+	// Khóa thửa đất để ngăn các yêu cầu xung đột cho đến khi giao dịch kết thúc
+	if err := acquireParcelLocks(ctx, &tx); err != nil {
+		return err
+	}
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi lưu giao dịch: %v", err)
 	}
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "CREATE_TRANSFER_REQUEST", callerID, fmt.Sprintf("Tạo yêu cầu chuyển nhượng %s", txID))
+	// Giá kê khai chỉ lưu trong private data
+	if declaredPrice != "" {
+		privateDetails := &TransactionPrivateDetails{TxID: txID, DeclaredPrice: priceFloat, UpdatedBy: callerID, UpdatedAt: txTime}
+		if err := putTransactionPrivateDetails(ctx, privateDetails); err != nil {
+			return err
+		}
+	}
+	return RecordAuditLog(ctx, "CREATE_TRANSFER_REQUEST", callerID, TransactionKeyPrefix, txID, fmt.Sprintf("Tạo yêu cầu chuyển nhượng %s", txID), transactionAuditDetails(&tx))
 }
 
 // CreateChangePurposeRequest - Tạo yêu cầu thay đổi mục đích sử dụng (auto-generate txID)
@@ -882,7 +1147,24 @@ func (s *LandRegistryChaincode) CreateChangePurposeRequest(ctx contractapi.Trans
 	if err := VerifyLandLegalStatus(ctx, landParcelID, []string{"Đang tranh chấp", "Đang thế chấp"}); err != nil {
 		return err
 	}
-	// Removed ValidateLandUsePurpose validation as requested
+	// Thửa đất bị đóng băng khi đang có tranh chấp
+	if err := VerifyNoOpenDispute(ctx, landParcelID); err != nil {
+		return err
+	}
+	newPurpose = strings.TrimSpace(newPurpose)
+	if newPurpose == "" {
+		return fmt.Errorf("mục đích sử dụng mới không được để trống")
+	}
+	if err := ValidateLandUsePurpose(ctx, newPurpose); err != nil {
+		return err
+	}
+	land, err := s.QueryLandByID(ctx, landParcelID)
+	if err != nil {
+		return fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", landParcelID, err)
+	}
+	if land.LandUsePurpose == newPurpose {
+		return fmt.Errorf("thửa đất %s đã có mục đích sử dụng %s", landParcelID, newPurpose)
+	}
 	txTime, err := GetTxTimestampAsTime(ctx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
@@ -897,7 +1179,7 @@ func (s *LandRegistryChaincode) CreateChangePurposeRequest(ctx contractapi.Trans
 	}
 
 	// Tự động tạo txID với timestamp
-	txID := fmt.Sprintf("DOI_MUC_DICH_%d_%s_%s", txTime.Unix(), callerID, landParcelID)
+	txID := fmt.Sprintf("DOI_MUC_DICH_%d_%s_%s", txTime.Unix(), shortSubjectRef(callerID), landParcelID)
 
 	// Tạo Details với lý do
 	details := fmt.Sprintf("Thay đổi mục đích sử dụng đất %s sang %s", landParcelID, newPurpose)
@@ -912,21 +1194,33 @@ func (s *LandRegistryChaincode) CreateChangePurposeRequest(ctx contractapi.Trans
 		ParcelIDs:    []string{}, // Khởi tạo empty slice thay vì nil
 		FromOwnerID:  callerID,
 		ToOwnerID:    callerID,
-		Status:       "PENDING",
+		Status:       TxStatusPending,
 		Details:      details,
+		Payload:      &TransactionPayload{ChangePurpose: &ChangePurposePayload{CurrentPurpose: land.LandUsePurpose, NewPurpose: newPurpose}},
 		UserID:       callerID,    // Tự động điền người thực hiện
 		DocumentIDs:  documentIDs, // Sử dụng documentIDs được parse
 		CreatedAt:    txTime,
 		UpdatedAt:    txTime,
 	}
+	// Các đồng sở hữu khác phải đồng ý trước khi hồ sơ được thẩm định
+	if err := initTransactionConsents(ctx, &tx); err != nil {
+		return err
+	}
+	if err := scheduleStageDeadline(ctx, &tx); err != nil {
+		return err
+	}
+	// Khóa thửa đất để ngăn các yêu cầu xung đột cho đến khi giao dịch kết thúc
+	if err := acquireParcelLocks(ctx, &tx); err != nil {
+		return err
+	}
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi lưu giao dịch: %v", err)
 	}
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "CREATE_CHANGE_PURPOSE_REQUEST", callerID, fmt.Sprintf("Tạo yêu cầu thay đổi mục đích sử dụng %s", txID))
+	return RecordAuditLog(ctx, "CREATE_CHANGE_PURPOSE_REQUEST", callerID, TransactionKeyPrefix, txID, fmt.Sprintf("Tạo yêu cầu thay đổi mục đích sử dụng %s", txID), transactionAuditDetails(&tx))
 }
 
 // CreateReissueRequest - Tạo yêu cầu cấp lại giấy chứng nhận (auto-generate txID)
@@ -944,6 +1238,10 @@ func (s *LandRegistryChaincode) CreateReissueRequest(ctx contractapi.Transaction
 	if err := VerifyLandLegalStatus(ctx, landParcelID, []string{"Đang tranh chấp", "Đang thế chấp"}); err != nil {
 		return err
 	}
+	// Thửa đất bị đóng băng khi đang có tranh chấp
+	if err := VerifyNoOpenDispute(ctx, landParcelID); err != nil {
+		return err
+	}
 	txTime, err := GetTxTimestampAsTime(ctx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
@@ -958,7 +1256,7 @@ func (s *LandRegistryChaincode) CreateReissueRequest(ctx contractapi.Transaction
 	}
 
 	// Tự động tạo txID với timestamp
-	txID := fmt.Sprintf("CAP_LAI_GCN_%d_%s_%s", txTime.Unix(), callerID, landParcelID)
+	txID := fmt.Sprintf("CAP_LAI_GCN_%d_%s_%s", txTime.Unix(), shortSubjectRef(callerID), landParcelID)
 
 	// Tạo Details với lý do
 	details := fmt.Sprintf("Yêu cầu cấp lại GCN cho thửa đất %s", landParcelID)
@@ -973,25 +1271,37 @@ func (s *LandRegistryChaincode) CreateReissueRequest(ctx contractapi.Transaction
 		ParcelIDs:    []string{}, // Khởi tạo empty slice thay vì nil
 		FromOwnerID:  callerID,
 		ToOwnerID:    callerID,
-		Status:       "PENDING",
+		Status:       TxStatusPending,
 		Details:      details,
+		Payload:      &TransactionPayload{Reissue: &ReissuePayload{Reason: reason}},
 		UserID:       callerID,    // Tự động điền người thực hiện
 		DocumentIDs:  documentIDs, // Sử dụng documentIDs được parse
 		CreatedAt:    txTime,
 		UpdatedAt:    txTime,
 	}
+	// Các đồng sở hữu khác phải đồng ý trước khi hồ sơ được thẩm định
+	if err := initTransactionConsents(ctx, &tx); err != nil {
+		return err
+	}
+	if err := scheduleStageDeadline(ctx, &tx); err != nil {
+		return err
+	}
+	// Khóa thửa đất để ngăn các yêu cầu xung đột cho đến khi giao dịch kết thúc
+	if err := acquireParcelLocks(ctx, &tx); err != nil {
+		return err
+	}
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi lưu giao dịch: %v", err)
 	}
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "CREATE_REISSUE_REQUEST", callerID, fmt.Sprintf("Tạo yêu cầu cấp lại GCN %s", txID))
+	return RecordAuditLog(ctx, "CREATE_REISSUE_REQUEST", callerID, TransactionKeyPrefix, txID, fmt.Sprintf("Tạo yêu cầu cấp lại GCN %s", txID), transactionAuditDetails(&tx))
 }
 
-// ConfirmTransfer - Xác nhận hoặc từ chối chuyển nhượng (bởi người nhận)
-func (s *LandRegistryChaincode) ConfirmTransfer(ctx contractapi.TransactionContextInterface, txID, landParcelID, toOwnerID, isAcceptedStr, reason string) error {
+// ConfirmTransfer - Xác nhận hoặc từ chối chuyển nhượng, tặng cho (bởi người nhận)
+func (s *LandRegistryChaincode) ConfirmTransfer(ctx contractapi.TransactionContextInterface, txID, landParcelID, isAcceptedStr, reason string) error {
 	if err := CheckOrganization(ctx, []string{"Org3MSP"}); err != nil {
 		return err
 	}
@@ -1003,18 +1313,26 @@ func (s *LandRegistryChaincode) ConfirmTransfer(ctx contractapi.TransactionConte
 	if err != nil {
 		return err
 	}
-	if tx.Type != "TRANSFER" {
-		return fmt.Errorf("giao dịch %s không phải là chuyển nhượng", txID)
+	if !recipientConfirmTxTypes[tx.Type] {
+		return fmt.Errorf("giao dịch %s không phải là chuyển nhượng hoặc tặng cho", txID)
 	}
-	if tx.ToOwnerID != userID {
-		return fmt.Errorf("người dùng %s không phải là người nhận chuyển nhượng", userID)
+	if landParcelID != tx.LandParcelID {
+		return fmt.Errorf("giao dịch %s không thuộc thửa đất %s", txID, landParcelID)
 	}
-	if tx.Status != "PENDING" {
-		return fmt.Errorf("giao dịch %s không ở trạng thái PENDING để xác nhận", txID)
+	txLabel := "chuyển nhượng"
+	if tx.Type == "GIFT" {
+		txLabel = "tặng cho"
 	}
 
 	// Parse isAccepted
 	isAccepted := isAcceptedStr == "true"
+	action := ActionConfirm
+	if !isAccepted {
+		action = ActionDecline
+	}
+	if err := ApplyTransition(ctx, tx, action); err != nil {
+		return err
+	}
 
 	txTime, err := GetTxTimestampAsTime(ctx)
 	if err != nil {
@@ -1023,15 +1341,13 @@ func (s *LandRegistryChaincode) ConfirmTransfer(ctx contractapi.TransactionConte
 
 	var actionLog string
 	if isAccepted {
-		tx.Status = "CONFIRMED"
-		tx.Details = fmt.Sprintf("%s; Người nhận đã chấp nhận chuyển nhượng", tx.Details)
+		tx.Details = fmt.Sprintf("%s; Người nhận đã chấp nhận %s", tx.Details, txLabel)
 		actionLog = "CONFIRM_TRANSFER_ACCEPTED"
 	} else {
-		tx.Status = "REJECTED"
 		if reason != "" {
-			tx.Details = fmt.Sprintf("%s; Người nhận từ chối chuyển nhượng - Lý do: %s", tx.Details, reason)
+			tx.Details = fmt.Sprintf("%s; Người nhận từ chối %s - Lý do: %s", tx.Details, txLabel, reason)
 		} else {
-			tx.Details = fmt.Sprintf("%s; Người nhận từ chối chuyển nhượng", tx.Details)
+			tx.Details = fmt.Sprintf("%s; Người nhận từ chối %s", tx.Details, txLabel)
 		}
 		actionLog = "CONFIRM_TRANSFER_REJECTED"
 	}
@@ -1041,7 +1357,7 @@ func (s *LandRegistryChaincode) ConfirmTransfer(ctx contractapi.TransactionConte
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
 	}
 
@@ -1049,7 +1365,7 @@ func (s *LandRegistryChaincode) ConfirmTransfer(ctx contractapi.TransactionConte
 	if !isAccepted {
 		actionText = "từ chối"
 	}
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), actionLog, userID, fmt.Sprintf("Người nhận %s chuyển nhượng %s", actionText, txID))
+	return RecordAuditLog(ctx, actionLog, userID, TransactionKeyPrefix, txID, fmt.Sprintf("Người nhận %s %s %s", actionText, txLabel, txID), transactionAuditDetails(tx))
 }
 
 // ========================================
@@ -1069,17 +1385,21 @@ func (s *LandRegistryChaincode) ProcessTransaction(ctx contractapi.TransactionCo
 	if err != nil {
 		return err
 	}
-	// Kiểm tra trạng thái dựa trên loại giao dịch
-	if tx.Type == "TRANSFER" {
-		// Giao dịch TRANSFER cần có xác nhận từ người nhận trước
-		if tx.Status != "CONFIRMED" {
-			return fmt.Errorf("giao dịch chuyển nhượng %s chưa được người nhận xác nhận", txID)
-		}
-	} else {
-		// Các loại giao dịch khác (SPLIT, MERGE, CHANGE_PURPOSE, REISSUE) xử lý trực tiếp từ PENDING
-		if tx.Status != "PENDING" {
-			return fmt.Errorf("giao dịch %s không ở trạng thái chờ xử lý", txID)
-		}
+	// Kiểm tra trạng thái theo bảng chuyển trạng thái (TRANSFER cần người nhận xác nhận trước)
+	var action string
+	switch decision {
+	case "APPROVE":
+		action = ActionVerify
+	case "SUPPLEMENT":
+		action = ActionRequestSupplement
+	case "REJECT":
+		action = ActionRejectDossier
+	default:
+		return fmt.Errorf("quyết định không hợp lệ. Sử dụng: APPROVE, SUPPLEMENT, hoặc REJECT")
+	}
+	transition, err := ResolveTransition(ctx, tx, action)
+	if err != nil {
+		return err
 	}
 
 	// Kiểm tra trạng thái tài liệu hiện tại (không tự động xác minh)
@@ -1126,8 +1446,19 @@ func (s *LandRegistryChaincode) ProcessTransaction(ctx contractapi.TransactionCo
 		if len(pendingDocs) > 0 {
 			return fmt.Errorf("không thể thẩm định đạt yêu cầu vì còn tài liệu chưa được xác thực: %v", pendingDocs)
 		}
+		// Hồ sơ phải đủ thành phần bắt buộc theo chính sách của loại giao dịch
+		missingDocs, err := CheckRequiredDocuments(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if len(missingDocs) > 0 {
+			return fmt.Errorf("không thể thẩm định đạt yêu cầu vì hồ sơ còn thiếu: %s", strings.Join(missingDocs, "; "))
+		}
 		// Chuyển sang VERIFIED thay vì FORWARDED - loại bỏ bước chuyển tiếp thủ công
-		tx.Status = "VERIFIED"
+		tx.Status = transition.To
+		if err := enterTransactionStage(ctx, tx); err != nil {
+			return err
+		}
 		statusDetails = fmt.Sprintf("Hồ sơ đạt yêu cầu.")
 		if len(verifiedDocs) > 0 {
 			statusDetails += fmt.Sprintf(" Tài liệu đã xác thực: %v.", verifiedDocs)
@@ -1140,7 +1471,10 @@ func (s *LandRegistryChaincode) ProcessTransaction(ctx contractapi.TransactionCo
 
 	case "SUPPLEMENT":
 		// Yêu cầu bổ sung
-		tx.Status = "SUPPLEMENT_REQUESTED"
+		tx.Status = transition.To
+		if err := enterTransactionStage(ctx, tx); err != nil {
+			return err
+		}
 		statusDetails = fmt.Sprintf("Yêu cầu bổ sung tài liệu.")
 		if len(verifiedDocs) > 0 {
 			statusDetails += fmt.Sprintf(" Tài liệu đã xác thực: %v.", verifiedDocs)
@@ -1158,34 +1492,34 @@ func (s *LandRegistryChaincode) ProcessTransaction(ctx contractapi.TransactionCo
 
 	case "REJECT":
 		// Từ chối hồ sơ
-		tx.Status = "REJECTED"
-		statusDetails = fmt.Sprintf("Hồ sơ bị từ chối.")
 		if reason == "" {
 			return fmt.Errorf("phải có lý do khi từ chối hồ sơ")
 		}
+		tx.Status = transition.To
+		if err := enterTransactionStage(ctx, tx); err != nil {
+			return err
+		}
+		statusDetails = fmt.Sprintf("Hồ sơ bị từ chối.")
 		statusDetails += fmt.Sprintf(" Lý do: %s", reason)
 		if len(rejectedDocs) > 0 {
 			statusDetails += fmt.Sprintf(" Tài liệu không hợp lệ: %v", rejectedDocs)
 		}
 		logAction = "Từ chối hồ sơ"
-
-	default:
-		return fmt.Errorf("quyết định không hợp lệ. Sử dụng: APPROVE, SUPPLEMENT, hoặc REJECT")
 	}
 
-    // Cập nhật thông tin giao dịch, giữ nguyên chi tiết ban đầu để Org1 có thể trích xuất tham số
-    if strings.TrimSpace(tx.Details) != "" {
-        tx.Details = fmt.Sprintf("%s. %s", tx.Details, statusDetails)
-    } else {
-        tx.Details = statusDetails
-    }
+	// Cập nhật ghi chú giao dịch, tham số nghiệp vụ nằm trong Payload
+	if strings.TrimSpace(tx.Details) != "" {
+		tx.Details = fmt.Sprintf("%s. %s", tx.Details, statusDetails)
+	} else {
+		tx.Details = statusDetails
+	}
 	tx.UpdatedAt = txTime
 
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
 	}
 
@@ -1195,7 +1529,7 @@ func (s *LandRegistryChaincode) ProcessTransaction(ctx contractapi.TransactionCo
 		logDetails += fmt.Sprintf(" Lỗi tài liệu: %v", docErrors)
 	}
 
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "PROCESS_TRANSACTION_DOSSIER", userID, logDetails)
+	return RecordAuditLog(ctx, "PROCESS_TRANSACTION_DOSSIER", userID, TransactionKeyPrefix, txID, logDetails, transactionAuditDetails(tx))
 }
 
 
@@ -1216,63 +1550,89 @@ func (s *LandRegistryChaincode) ApproveTransferTransaction(ctx contractapi.Trans
 	if err != nil {
 		return err
 	}
-	if tx.Status != "VERIFIED" {
-		return fmt.Errorf("giao dịch %s không ở trạng thái VERIFIED", txID)
-	}
 	if tx.Type != "TRANSFER" {
 		return fmt.Errorf("giao dịch %s không phải là chuyển nhượng", txID)
 	}
+	transition, err := ResolveTransition(ctx, tx, ActionApprove)
+	if err != nil {
+		return err
+	}
 
 	land, err := s.QueryLandByID(ctx, tx.LandParcelID)
 	if err != nil {
 		return fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", tx.LandParcelID, err)
 	}
-	if land.OwnerID != tx.FromOwnerID {
+	if !IsLandOwner(land, tx.FromOwnerID) {
 		return fmt.Errorf("người dùng %s không sở hữu thửa đất %s", tx.FromOwnerID, tx.LandParcelID)
 	}
+	// Thửa đất bị đóng băng khi đang có tranh chấp
+	if err := VerifyNoOpenDispute(ctx, tx.LandParcelID); err != nil {
+		return err
+	}
+	if err := VerifyNoActiveMortgage(ctx, tx.LandParcelID); err != nil {
+		return err
+	}
+	// Thửa đất đang cho thuê không được chuyển quyền cho đến khi hợp đồng thuê chấm dứt
+	if err := VerifyNoActiveLease(ctx, tx.LandParcelID); err != nil {
+		return err
+	}
 
 	txTime, err := GetTxTimestampAsTime(ctx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
 	}
 
-	// Vô hiệu hóa giấy chứng nhận cũ (do chủ sử dụng thay đổi)
+	// Thu hồi giấy chứng nhận cũ (do chủ sử dụng thay đổi), lịch sử GCN được giữ lại
 	if land.CertificateID != "" {
-		land.CertificateID = ""      // Xóa giấy chứng nhận cũ
-		land.IssueDate = time.Time{} // Reset ngày cấp
-		land.LegalInfo = "Giấy chứng nhận đã vô hiệu do chuyển nhượng quyền sử dụng đất"
+		legalInfo := "Giấy chứng nhận đã vô hiệu do chuyển nhượng quyền sử dụng đất"
+		if err := revokeLandCertificates(ctx, land, legalInfo, userID, txTime); err != nil {
+			return err
+		}
+		land.CertificateID = ""
+		land.IssueDate = time.Time{}
+		land.LegalInfo = legalInfo
 	}
 
-	// Cập nhật chủ sử dụng thửa đất
-	land.OwnerID = tx.ToOwnerID
+	// Cập nhật chủ sử dụng thửa đất theo người nhận trong payload
+	recipientID := tx.ToOwnerID
+	if tx.Payload != nil && tx.Payload.Transfer != nil && tx.Payload.Transfer.RecipientID != "" {
+		recipientID = tx.Payload.Transfer.RecipientID
+	}
+	land.OwnerID = recipientID
+	// Toàn bộ thửa đất (kể cả phần của các đồng sở hữu đã đồng ý) chuyển cho người nhận
+	land.CoOwners = nil
 	land.UpdatedAt = txTime
 	landJSON, err := json.Marshal(land)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
 	}
-	if err := ctx.GetStub().PutState(tx.LandParcelID, landJSON); err != nil {
+	if err := PutLandState(ctx, tx.LandParcelID, landJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
 	}
 
 	// Cập nhật trạng thái giao dịch
-	tx.Status = "APPROVED"
+	tx.Status = transition.To
+	if err := enterTransactionStage(ctx, tx); err != nil {
+		return err
+	}
 	tx.Details = fmt.Sprintf("%s; Đã phê duyệt chuyển nhượng", tx.Details)
 	tx.UpdatedAt = txTime
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
 	}
 
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "APPROVE_TRANSFER", userID, fmt.Sprintf("Phê duyệt chuyển nhượng %s", txID))
+	return RecordAuditLog(ctx, "APPROVE_TRANSFER", userID, TransactionKeyPrefix, txID, fmt.Sprintf("Phê duyệt chuyển nhượng %s", txID), transactionAuditDetails(tx))
 }
 
 
 
 // ApproveReissueTransaction - Phê duyệt giao dịch cấp đổi giấy chứng nhận với IPFS hash mới
-func (s *LandRegistryChaincode) ApproveReissueTransaction(ctx contractapi.TransactionContextInterface, txID string, newCertificateID string) error {
+// GCN cũ chuyển sang SUPERSEDED, serialNumber có thể để trống (khi đó dùng mã GCN mới)
+func (s *LandRegistryChaincode) ApproveReissueTransaction(ctx contractapi.TransactionContextInterface, txID string, newCertificateID string, serialNumber string) error {
 	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
 		return err
 	}
@@ -1284,12 +1644,13 @@ func (s *LandRegistryChaincode) ApproveReissueTransaction(ctx contractapi.Transa
 	if err != nil {
 		return err
 	}
-	if tx.Status != "VERIFIED" {
-		return fmt.Errorf("giao dịch %s không ở trạng thái VERIFIED", txID)
-	}
 	if tx.Type != "REISSUE" {
 		return fmt.Errorf("giao dịch %s không phải là cấp đổi giấy chứng nhận", txID)
 	}
+	transition, err := ResolveTransition(ctx, tx, ActionApprove)
+	if err != nil {
+		return err
+	}
 
 	// Validate newCertificateID as IPFS hash
 	if newCertificateID == "" {
@@ -1303,7 +1664,7 @@ func (s *LandRegistryChaincode) ApproveReissueTransaction(ctx contractapi.Transa
 	if err != nil {
 		return fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", tx.LandParcelID, err)
 	}
-	if land.OwnerID != tx.FromOwnerID {
+	if !IsLandOwner(land, tx.FromOwnerID) {
 		return fmt.Errorf("người dùng %s không sở hữu thửa đất %s", tx.FromOwnerID, tx.LandParcelID)
 	}
 
@@ -1311,33 +1672,48 @@ func (s *LandRegistryChaincode) ApproveReissueTransaction(ctx contractapi.Transa
 		return err
 	}
 
-	// Sử dụng UpdateLandParcel để cập nhật GCN và thông tin pháp lý
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	// Cấp GCN mới thay thế GCN hiện hành của thửa đất
 	legalInfo := fmt.Sprintf("Cấp đổi GCN cho thửa đất %s", tx.LandParcelID)
-	err = s.UpdateLandParcel(ctx, tx.LandParcelID, fmt.Sprintf("%.2f", land.Area), land.Location, land.LandUsePurpose, land.LegalStatus, newCertificateID, legalInfo, land.GeometryCID)
+	cert, err := issueCertificate(ctx, land, serialNumber, newCertificateID, legalInfo, userID, txTime)
 	if err != nil {
+		return err
+	}
+	landJSON, err := json.Marshal(land)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
+	}
+	if err := PutLandState(ctx, tx.LandParcelID, landJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
 	}
 
 	// Cập nhật trạng thái giao dịch
-	tx.Status = "APPROVED"
-	tx.Details = fmt.Sprintf("%s; Đã phê duyệt cấp đổi giấy chứng nhận với IPFS hash: %s", tx.Details, newCertificateID)
-	txTime, err := GetTxTimestampAsTime(ctx)
-	if err != nil {
-		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	tx.Status = transition.To
+	if err := enterTransactionStage(ctx, tx); err != nil {
+		return err
+	}
+	tx.Details = fmt.Sprintf("%s; Đã phê duyệt cấp đổi giấy chứng nhận %s với IPFS hash: %s", tx.Details, cert.CertificateID, newCertificateID)
+	if tx.Payload != nil && tx.Payload.Reissue != nil && tx.Payload.Reissue.Reason != "" {
+		tx.Details = fmt.Sprintf("%s (lý do cấp lại: %s)", tx.Details, tx.Payload.Reissue.Reason)
 	}
 	tx.UpdatedAt = txTime
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
 	}
 
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "APPROVE_REISSUE", userID, fmt.Sprintf("Phê duyệt cấp đổi GCN cho thửa đất %s với IPFS hash: %s", tx.LandParcelID, newCertificateID))
+	return RecordAuditLog(ctx, "APPROVE_REISSUE", userID, TransactionKeyPrefix, txID, fmt.Sprintf("Phê duyệt cấp đổi GCN cho thửa đất %s với IPFS hash: %s", tx.LandParcelID, newCertificateID), transactionAuditDetails(tx))
 }
 
-// ApproveSplitTransaction approves a split transaction, updating the original land first if its ID matches, then creating new parcels, invalidating all certificates
+// ApproveSplitTransaction approves a split transaction: validates all new parcels first (unique IDs, area conserved within the configured tolerance),
+// then updates the original land if its ID matches, creates new parcels and invalidates all certificates
 func (s *LandRegistryChaincode) ApproveSplitTransaction(ctx contractapi.TransactionContextInterface, txID, landID, newParcelsStr string) error {
 	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
 		return err
@@ -1350,88 +1726,97 @@ func (s *LandRegistryChaincode) ApproveSplitTransaction(ctx contractapi.Transact
 	if err != nil {
 		return err
 	}
-	if tx.Status != "VERIFIED" {
-		return fmt.Errorf("giao dịch %s không ở trạng thái VERIFIED", txID)
-	}
 	if tx.Type != "SPLIT" {
 		return fmt.Errorf("giao dịch %s không phải là tách thửa", txID)
 	}
+	transition, err := ResolveTransition(ctx, tx, ActionApprove)
+	if err != nil {
+		return err
+	}
 	originalLand, err := s.QueryLandByID(ctx, landID)
 	if err != nil {
 		return fmt.Errorf("lỗi khi truy vấn thửa đất gốc %s: %v", landID, err)
 	}
-	if originalLand.OwnerID != tx.FromOwnerID {
-		return fmt.Errorf("người dùng %s không sở hữu thửa đất %s", tx.FromOwnerID, landID)
+	newParcels, err := splitParcelsFromInput(tx, newParcelsStr)
+	if err != nil {
+		return err
 	}
-	var newParcels []Land
-	if err := json.Unmarshal([]byte(newParcelsStr), &newParcels); err != nil {
-		return fmt.Errorf("lỗi khi giải mã danh sách thửa đất mới: %v", err)
+	// Giai đoạn 1: thẩm định toàn bộ kết quả tách thửa, chưa ghi sổ cái
+	report, err := validateSplitParcels(ctx, tx, originalLand, newParcels)
+	if err != nil {
+		return err
 	}
-	if len(newParcels) == 0 {
-		return fmt.Errorf("danh sách thửa đất mới trống")
+	if !report.Valid {
+		return splitValidationError(report)
 	}
 	txTime, err := GetTxTimestampAsTime(ctx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
 	}
-	var totalArea float64
+	// Giai đoạn 2: ghi sổ cái
+	// Thu hồi GCN của thửa đất gốc, các thửa mới sẽ được cấp GCN sau
+	if err := revokeLandCertificates(ctx, originalLand, "Giấy chứng nhận đã vô hiệu do tách thửa đất", userID, txTime); err != nil {
+		return err
+	}
 	var newLandIDs []string
 	var updatedOriginal bool
-	// Step 1: Validate and process all parcels
-	for _, newLand := range newParcels {
-		// Determine if this is an update (ID matches original) or create new
-		isUpdate := newLand.ID == landID
-		
-		// Validate with appropriate flag
-		if err := ValidateLand(ctx, newLand, isUpdate); err != nil {
-			return fmt.Errorf("thửa đất mới %s không hợp lệ: %v", newLand.ID, err)
+	for _, parcel := range newParcels {
+		// Thửa đất mới chỉ nhận các trường của parcelInput, các trường còn lại lấy từ thửa đất gốc hoặc để trống
+		newLand := Land{
+			ID:           strings.TrimSpace(parcel.ID),
+			Area:         parcel.Area,
+			DocumentIDs:  parcel.DocumentIDs,
+			GeometryCID:  parcel.GeometryCID,
+			GeometryHash: parcel.GeometryHash,
+			BBox:         parcel.BBox,
+		}
+		if newLand.DocumentIDs == nil {
+			newLand.DocumentIDs = []string{}
 		}
-		
-		totalArea += newLand.Area
+		isUpdate := newLand.ID == landID
 		newLand.CreatedAt = txTime
 		newLand.UpdatedAt = txTime
-		newLand.OwnerID = tx.FromOwnerID // Inherit owner
+		// Kế thừa chủ sử dụng và phần sở hữu của các đồng sở hữu từ thửa đất gốc
+		newLand.OwnerID = originalLand.OwnerID
+		newLand.CoOwners = originalLand.CoOwners
 		// Kế thừa mục đích sử dụng và vị trí từ thửa đất gốc
 		newLand.LandUsePurpose = originalLand.LandUsePurpose
 		newLand.Location = originalLand.Location
-		
-		// Xử lý geometry CID cho thửa đất mới
-		if newLand.GeometryCID != "" {
-			// Validate geometry CID nếu được cung cấp từ giao diện
-			if err := ValidateIPFSHash(newLand.GeometryCID); err != nil {
-				return fmt.Errorf("geometry CID không hợp lệ cho thửa đất %s: %v", newLand.ID, err)
-			}
-		} else if isUpdate {
-			// Nếu là cập nhật thửa đất gốc và không có geometry CID mới, giữ nguyên
+		// Nếu là cập nhật thửa đất gốc và không có geometry CID mới, giữ nguyên
+		if newLand.GeometryCID == "" && isUpdate {
 			newLand.GeometryCID = originalLand.GeometryCID
 		}
-		
+
 		// Invalidate certificate for all parcels
 		newLand.CertificateID = ""
 		newLand.IssueDate = time.Time{}
 		newLand.LegalInfo = "Giấy chứng nhận sẽ được cấp mới sau tách thửa"
 		newLand.LegalStatus = ""
-		
+
 		if isUpdate {
 			// Update original land
 			updatedOriginal = true
 			newLand.DocumentIDs = originalLand.DocumentIDs // Keep existing documents
 		}
-		
+
 		// Save the land parcel
 		landJSON, err := json.Marshal(newLand)
 		if err != nil {
 			return fmt.Errorf("lỗi khi mã hóa thửa đất %s: %v", newLand.ID, err)
 		}
-		if err := ctx.GetStub().PutState(newLand.ID, landJSON); err != nil {
+		if err := PutLandState(ctx, newLand.ID, landJSON); err != nil {
 			return fmt.Errorf("lỗi khi lưu thửa đất %s: %v", newLand.ID, err)
 		}
+		var oldBBox *BoundingBox
+		if isUpdate {
+			oldBBox = originalLand.BBox
+		}
+		if err := updateLandSpatialIndex(ctx, newLand.ID, oldBBox, newLand.BBox); err != nil {
+			return err
+		}
 		newLandIDs = append(newLandIDs, newLand.ID)
 	}
-	if totalArea > originalLand.Area {
-		return fmt.Errorf("tổng diện tích các thửa mới (%f m²) vượt quá diện tích thửa gốc (%f m²)", totalArea, originalLand.Area)
-	}
-	// Step 3: Invalidate original land if not updated
+	// Invalidate original land if not updated
 	if !updatedOriginal {
 		originalLand.CertificateID = ""
 		originalLand.IssueDate = time.Time{}
@@ -1442,30 +1827,38 @@ func (s *LandRegistryChaincode) ApproveSplitTransaction(ctx contractapi.Transact
 		if err != nil {
 			return fmt.Errorf("lỗi khi mã hóa thửa đất gốc %s: %v", landID, err)
 		}
-		if err := ctx.GetStub().PutState(landID, updatedLandJSON); err != nil {
+		if err := PutLandState(ctx, landID, updatedLandJSON); err != nil {
 			return fmt.Errorf("lỗi khi cập nhật thửa đất gốc %s: %v", landID, err)
 		}
+		// Thửa đất gốc đã được thay thế bởi các thửa mới nên không còn trong chỉ mục không gian
+		if err := updateLandSpatialIndex(ctx, landID, originalLand.BBox, nil); err != nil {
+			return err
+		}
 	}
 	// Update transaction
 	tx.ParcelIDs = newLandIDs
-	tx.Status = "APPROVED"
+	tx.Status = transition.To
+	if err := enterTransactionStage(ctx, tx); err != nil {
+		return err
+	}
 	tx.Details = fmt.Sprintf("%s; Đã phê duyệt tách thửa và tạo/cập nhật %d thửa đất mới, tất cả GCN đã vô hiệu hóa", tx.Details, len(newLandIDs))
 	tx.UpdatedAt = txTime
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
 	}
 	// Emit event for off-chain apps
 	if err := ctx.GetStub().SetEvent("SPLIT_APPROVED", txJSON); err != nil {
 		return fmt.Errorf("lỗi khi emit event: %v", err)
 	}
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "APPROVE_SPLIT", userID, fmt.Sprintf("Phê duyệt tách thửa %s thành %d thửa mới, tất cả GCN đã vô hiệu hóa", txID, len(newLandIDs)))
+	return RecordAuditLog(ctx, "APPROVE_SPLIT", userID, TransactionKeyPrefix, txID, fmt.Sprintf("Phê duyệt tách thửa %s thành %d thửa mới, tất cả GCN đã vô hiệu hóa", txID, len(newLandIDs)), transactionAuditDetails(tx))
 }
 
 // ApproveMergeTransaction approves a merge transaction, updating the selected original land and invalidating all certificates
+// landIdsStr phải gồm đúng các thửa trong yêu cầu hợp thửa, để trống thì dùng danh sách trong yêu cầu
 func (s *LandRegistryChaincode) ApproveMergeTransaction(ctx contractapi.TransactionContextInterface, txID, landIdsStr, selectedLandID, newParcelStr string) error {
 	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
 		return err
@@ -1478,48 +1871,85 @@ func (s *LandRegistryChaincode) ApproveMergeTransaction(ctx contractapi.Transact
 	if err != nil {
 		return err
 	}
-	if tx.Status != "VERIFIED" {
-		return fmt.Errorf("giao dịch %s không ở trạng thái VERIFIED", txID)
-	}
 	if tx.Type != "MERGE" {
 		return fmt.Errorf("giao dịch %s không phải là hợp thửa", txID)
 	}
-	var landIds []string
-	if err := json.Unmarshal([]byte(landIdsStr), &landIds); err != nil {
-		return fmt.Errorf("lỗi khi giải mã danh sách landIds: %v", err)
+	transition, err := ResolveTransition(ctx, tx, ActionApprove)
+	if err != nil {
+		return err
+	}
+	// Các thửa gốc phải đúng là các thửa trong yêu cầu hợp thửa (landIdsStr trống thì dùng danh sách trong yêu cầu)
+	requestedIDs, err := normalizeMergeParcelIDs(tx.ParcelIDs)
+	if err != nil {
+		return fmt.Errorf("yêu cầu hợp thửa %s không hợp lệ: %v", txID, err)
+	}
+	landIds := requestedIDs
+	if strings.TrimSpace(landIdsStr) != "" {
+		if err := json.Unmarshal([]byte(landIdsStr), &landIds); err != nil {
+			return fmt.Errorf("lỗi khi giải mã danh sách landIds: %v", err)
+		}
+		if landIds, err = normalizeMergeParcelIDs(landIds); err != nil {
+			return err
+		}
+		if !sameParcelSet(landIds, requestedIDs) {
+			return fmt.Errorf("danh sách landIds %v không khớp với các thửa đất trong yêu cầu hợp thửa %v", landIds, requestedIDs)
+		}
 	}
 	// Lấy thông tin area và geometryCID từ newParcelStr
 	var newParcelData struct {
-		ID          string  `json:"id"`
-		Area        float64 `json:"area"`
-		GeometryCID string  `json:"geometryCid"`
+		ID          string          `json:"id"`
+		Area        float64         `json:"area"`
+		GeometryCID string          `json:"geometryCid"`
+		Geometry    json.RawMessage `json:"geometry,omitempty"` // GeoJSON của thửa hợp nhất (không bắt buộc)
+		// GeoJSON của các thửa gốc do cán bộ phê duyệt cung cấp, được ưu tiên hơn hình học trong yêu cầu hợp thửa
+		ParcelGeometries map[string]json.RawMessage `json:"parcelGeometries,omitempty"`
 	}
 	if err := json.Unmarshal([]byte(newParcelStr), &newParcelData); err != nil {
 		return fmt.Errorf("lỗi khi giải mã thông tin thửa đất mới: %v", err)
 	}
-	// Verify selectedLandID is in landIds
-	isValidSelected := false
-	for _, id := range landIds {
-		if id == selectedLandID {
-			isValidSelected = true
-			break
+	// Thửa đất được giữ lại lấy từ payload của yêu cầu hợp thửa
+	if tx.Payload != nil && tx.Payload.Merge != nil && tx.Payload.Merge.SelectedLandID != "" {
+		if selectedLandID == "" {
+			selectedLandID = tx.Payload.Merge.SelectedLandID
+		} else if selectedLandID != tx.Payload.Merge.SelectedLandID {
+			return fmt.Errorf("selectedLandID %s không khớp với thửa đất được chọn trong yêu cầu (%s)", selectedLandID, tx.Payload.Merge.SelectedLandID)
 		}
 	}
-	if !isValidSelected {
+	// Verify selectedLandID is in landIds
+	if !containsString(landIds, selectedLandID) {
 		return fmt.Errorf("selectedLandID %s không nằm trong danh sách landIds %v", selectedLandID, landIds)
 	}
 	var totalArea float64
 	var baseLocation string
+	var baseLand *Land
+	var lands []*Land
 	for i, parcelID := range landIds {
-		parcelID = strings.TrimSpace(parcelID)
 		land, err := s.QueryLandByID(ctx, parcelID)
 		if err != nil {
 			return fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", parcelID, err)
 		}
-		if land.OwnerID != tx.FromOwnerID {
+		if !IsLandOwner(land, tx.FromOwnerID) {
 			return fmt.Errorf("người dùng %s không sở hữu thửa đất %s", tx.FromOwnerID, parcelID)
 		}
+		// Thửa đất hợp nhất giữ nguyên đồng sở hữu nên các thửa gốc phải cùng chủ và cùng phần sở hữu
+		if baseLand == nil {
+			baseLand = land
+		} else if !sameOwnership(baseLand, land) {
+			return fmt.Errorf("thửa đất %s không cùng chủ sử dụng và phần sở hữu với thửa đất %s", parcelID, baseLand.ID)
+		}
+		// Thửa đất bị đóng băng khi đang có tranh chấp
+		if err := VerifyNoOpenDispute(ctx, parcelID); err != nil {
+			return err
+		}
+		if err := VerifyNoActiveMortgage(ctx, parcelID); err != nil {
+			return err
+		}
+		// Hợp đồng thuê gắn với thửa đất gốc sẽ không còn đúng sau khi hợp thửa
+		if err := VerifyNoActiveLease(ctx, parcelID); err != nil {
+			return err
+		}
 		totalArea += land.Area
+		lands = append(lands, land)
 		if i == 0 {
 			baseLocation = land.Location
 		} else if land.Location != baseLocation {
@@ -1530,9 +1960,50 @@ func (s *LandRegistryChaincode) ApproveMergeTransaction(ctx contractapi.Transact
 	if newParcelData.Area <= 0 {
 		return fmt.Errorf("diện tích thừa đất mới phải lớn hơn 0")
 	}
-	if newParcelData.Area != totalArea {
+	config, err := GetChaincodeConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if math.Abs(newParcelData.Area-totalArea) > config.AreaTolerance {
 		return fmt.Errorf("diện tích thừa đất mới (%f m²) không khớp với tổng diện tích các thừa đất gốc (%f m²)", newParcelData.Area, totalArea)
 	}
+	// Mục đích sử dụng của thửa hợp nhất theo yêu cầu (kiểm tra lại vì danh mục có thể đã thay đổi)
+	var requestedPurpose string
+	if tx.Payload != nil && tx.Payload.Merge != nil {
+		requestedPurpose = tx.Payload.Merge.TargetPurpose
+	}
+	targetPurpose, _, err := resolveMergePurpose(ctx, lands, requestedPurpose)
+	if err != nil {
+		return err
+	}
+	// Các thửa gốc phải liền kề nhau; khung bao của thửa hợp nhất là khung bao chung của các thửa gốc
+	geometries := map[string]json.RawMessage{}
+	for landID, geometry := range newParcelData.ParcelGeometries {
+		geometries[landID] = geometry
+	}
+	if tx.Payload != nil && tx.Payload.Merge != nil {
+		for landID, geometry := range tx.Payload.Merge.ParcelGeometries {
+			if _, ok := geometries[landID]; !ok {
+				geometries[landID] = geometry
+			}
+		}
+	}
+	mergedBBox, err := verifyMergeGeometries(lands, geometries)
+	if err != nil {
+		return err
+	}
+	// Diện tích thửa hợp nhất phải khớp với diện tích tính từ hình học (nếu được cung cấp)
+	var parsedGeometry *ParcelGeometry
+	if len(newParcelData.Geometry) > 0 {
+		parsedGeometry, err = checkDeclaredArea(ctx, newParcelData.Geometry, selectedLandID, newParcelData.Area)
+		if err != nil {
+			return fmt.Errorf("hình học thửa đất hợp nhất không hợp lệ: %v", err)
+		}
+		// Hình học thửa hợp nhất phải phủ đúng khối các thửa gốc
+		if !bboxesMatch(parsedGeometry.BBox, mergedBBox, adjacencyTolerance) {
+			return fmt.Errorf("khung bao của hình học thửa đất hợp nhất %+v không khớp với khung bao chung của các thửa gốc %+v", *parsedGeometry.BBox, *mergedBBox)
+		}
+	}
 	txTime, err := GetTxTimestampAsTime(ctx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
@@ -1542,8 +2013,13 @@ func (s *LandRegistryChaincode) ApproveMergeTransaction(ctx contractapi.Transact
 	if err != nil {
 		return fmt.Errorf("lỗi khi truy vấn thừa đất gốc %s: %v", selectedLandID, err)
 	}
-	// Chỉ cập nhật area và vô hiệu hóa GCN, giữ nguyên các thông tin khác
+	// Thu hồi GCN của thửa đất được giữ lại, GCN mới sẽ được cấp sau hợp thửa
+	if err := revokeLandCertificates(ctx, existingLand, "Giấy chứng nhận đã vô hiệu do hợp thửa đất", userID, txTime); err != nil {
+		return err
+	}
+	// Cập nhật area, mục đích sử dụng và vô hiệu hóa GCN, giữ nguyên các thông tin khác
 	existingLand.Area = newParcelData.Area
+	existingLand.LandUsePurpose = targetPurpose
 	existingLand.UpdatedAt = txTime
 	existingLand.CertificateID = "" // Invalidate certificate
 	existingLand.IssueDate = time.Time{}
@@ -1557,14 +2033,26 @@ func (s *LandRegistryChaincode) ApproveMergeTransaction(ctx contractapi.Transact
 		}
 		existingLand.GeometryCID = newParcelData.GeometryCID
 	}
+	// Hình dạng thửa đất đã thay đổi nên SHA-256 và khung bao cũ không còn giá trị
+	oldBBox := existingLand.BBox
+	existingLand.GeometryHash = ""
+	existingLand.AreaOverride = ""
+	existingLand.BBox = mergedBBox
+	if parsedGeometry != nil {
+		existingLand.GeometryHash = parsedGeometry.Hash
+		existingLand.BBox = parsedGeometry.BBox
+	}
 	
 	landJSON, err := json.Marshal(existingLand)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa thửa đất gốc %s: %v", selectedLandID, err)
 	}
-	if err := ctx.GetStub().PutState(selectedLandID, landJSON); err != nil {
+	if err := PutLandState(ctx, selectedLandID, landJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật thửa đất gốc %s: %v", selectedLandID, err)
 	}
+	if err := updateLandSpatialIndex(ctx, selectedLandID, oldBBox, existingLand.BBox); err != nil {
+		return err
+	}
 	// Step 2: Invalidate other original lands
 	for _, parcelID := range landIds {
 		if parcelID == selectedLandID {
@@ -1574,6 +2062,9 @@ func (s *LandRegistryChaincode) ApproveMergeTransaction(ctx contractapi.Transact
 		if err != nil {
 			return fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", parcelID, err)
 		}
+		if err := revokeLandCertificates(ctx, originalLand, "Giấy chứng nhận đã vô hiệu do hợp thửa đất", userID, txTime); err != nil {
+			return err
+		}
 		originalLand.CertificateID = ""
 		originalLand.IssueDate = time.Time{}
 		originalLand.LegalInfo = "Giấy chứng nhận đã vô hiệu do hợp thửa đất"
@@ -1583,27 +2074,117 @@ func (s *LandRegistryChaincode) ApproveMergeTransaction(ctx contractapi.Transact
 		if err != nil {
 			return fmt.Errorf("lỗi khi mã hóa thửa đất cũ %s: %v", parcelID, err)
 		}
-		if err := ctx.GetStub().PutState(parcelID, updatedLandJSON); err != nil {
+		if err := PutLandState(ctx, parcelID, updatedLandJSON); err != nil {
 			return fmt.Errorf("lỗi khi cập nhật thửa đất cũ %s: %v", parcelID, err)
 		}
+		// Thửa đất đã nhập vào thửa hợp nhất nên không còn trong chỉ mục không gian
+		if err := updateLandSpatialIndex(ctx, parcelID, originalLand.BBox, nil); err != nil {
+			return err
+		}
 	}
 	// Update transaction
 	tx.LandParcelID = selectedLandID
-	tx.Status = "APPROVED"
+	tx.Status = transition.To
+	if err := enterTransactionStage(ctx, tx); err != nil {
+		return err
+	}
 	tx.Details = fmt.Sprintf("%s; Đã phê duyệt hợp thửa và cập nhật thửa đất %s, tất cả GCN đã vô hiệu hóa", tx.Details, selectedLandID)
 	tx.UpdatedAt = txTime
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
 	}
 	// Emit event for off-chain apps
 	if err := ctx.GetStub().SetEvent("MERGE_APPROVED", txJSON); err != nil {
 		return fmt.Errorf("lỗi khi emit event: %v", err)
 	}
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "APPROVE_MERGE", userID, fmt.Sprintf("Phê duyệt hợp thửa %s thành thửa %s, tất cả GCN đã vô hiệu hóa", txID, selectedLandID))
+	return RecordAuditLog(ctx, "APPROVE_MERGE", userID, TransactionKeyPrefix, txID, fmt.Sprintf("Phê duyệt hợp thửa %s thành thửa %s, tất cả GCN đã vô hiệu hóa", txID, selectedLandID), transactionAuditDetails(tx))
+}
+
+// MigrateChangePurposePayloads - Bổ sung payload cho các yêu cầu thay đổi mục đích sử dụng đang xử lý được tạo trước khi có payload (chỉ Org1, chạy một lần)
+// purposesJSON: JSON {"<txId>": "<mã mục đích sử dụng mới>"} do cán bộ xác định từ hồ sơ gốc
+// Mọi yêu cầu cũ đang xử lý phải có trong danh sách, nếu không toàn bộ lần chạy bị từ chối
+func (s *LandRegistryChaincode) MigrateChangePurposePayloads(ctx contractapi.TransactionContextInterface, purposesJSON string) (int, error) {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return 0, err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	purposes := map[string]string{}
+	if strings.TrimSpace(purposesJSON) != "" {
+		if err := json.Unmarshal([]byte(purposesJSON), &purposes); err != nil {
+			return 0, fmt.Errorf("lỗi khi giải mã danh sách mục đích sử dụng: %v", err)
+		}
+	}
+	queryBytes, err := json.Marshal(map[string]interface{}{
+		"selector": map[string]interface{}{
+			"txId":   map[string]interface{}{"$exists": true},
+			"type":   "CHANGE_PURPOSE",
+			"status": map[string]interface{}{"$in": openTxStatuses},
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("lỗi khi tạo truy vấn: %v", err)
+	}
+	transactions, err := s.getQueryResultForTransactions(ctx, string(queryBytes))
+	if err != nil {
+		return 0, fmt.Errorf("lỗi khi truy vấn giao dịch: %v", err)
+	}
+	legacy := []*Transaction{}
+	missing := []string{}
+	for _, tx := range transactions {
+		if tx.Payload != nil && tx.Payload.ChangePurpose != nil && tx.Payload.ChangePurpose.NewPurpose != "" {
+			continue
+		}
+		if strings.TrimSpace(purposes[tx.TxID]) == "" {
+			missing = append(missing, tx.TxID)
+			continue
+		}
+		legacy = append(legacy, tx)
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return 0, fmt.Errorf("không xác định được mục đích sử dụng mới của các giao dịch: %s", strings.Join(missing, ", "))
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	migrated := []string{}
+	for _, tx := range legacy {
+		newPurpose := strings.TrimSpace(purposes[tx.TxID])
+		if err := ValidateLandUsePurpose(ctx, newPurpose); err != nil {
+			return 0, fmt.Errorf("giao dịch %s: %v", tx.TxID, err)
+		}
+		land, err := s.QueryLandByID(ctx, tx.LandParcelID)
+		if err != nil {
+			return 0, fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", tx.LandParcelID, err)
+		}
+		if tx.Payload == nil {
+			tx.Payload = &TransactionPayload{}
+		}
+		tx.Payload.ChangePurpose = &ChangePurposePayload{CurrentPurpose: land.LandUsePurpose, NewPurpose: newPurpose}
+		tx.UpdatedAt = txTime
+		txJSON, err := json.Marshal(tx)
+		if err != nil {
+			return 0, fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
+		}
+		if err := PutTransactionState(ctx, tx.TxID, txJSON); err != nil {
+			return 0, fmt.Errorf("lỗi khi cập nhật giao dịch %s: %v", tx.TxID, err)
+		}
+		migrated = append(migrated, tx.TxID)
+	}
+	if len(migrated) == 0 {
+		return 0, nil
+	}
+	return len(migrated), RecordAuditLog(ctx, "MIGRATE_CHANGE_PURPOSE_PAYLOADS", userID, "", "",
+		fmt.Sprintf("Bổ sung payload cho %d yêu cầu thay đổi mục đích sử dụng", len(migrated)), map[string]string{"txIds": strings.Join(migrated, ",")})
 }
 
 // ApproveChangePurposeTransaction - Phê duyệt giao dịch thay đổi mục đích sử dụng
@@ -1619,12 +2200,13 @@ func (s *LandRegistryChaincode) ApproveChangePurposeTransaction(ctx contractapi.
 	if err != nil {
 		return err
 	}
-	if tx.Status != "VERIFIED" {
-		return fmt.Errorf("giao dịch %s không ở trạng thái VERIFIED", txID)
-	}
 	if tx.Type != "CHANGE_PURPOSE" {
 		return fmt.Errorf("giao dịch %s không phải là thay đổi mục đích sử dụng", txID)
 	}
+	transition, err := ResolveTransition(ctx, tx, ActionApprove)
+	if err != nil {
+		return err
+	}
 
 	land, err := s.QueryLandByID(ctx, tx.LandParcelID)
 	if err != nil {
@@ -1632,99 +2214,64 @@ func (s *LandRegistryChaincode) ApproveChangePurposeTransaction(ctx contractapi.
 	}
 
 	// Kiểm tra quyền sở hữu
-	if land.OwnerID != tx.FromOwnerID {
+	if !IsLandOwner(land, tx.FromOwnerID) {
 		return fmt.Errorf("người dùng %s không sở hữu thửa đất %s", tx.FromOwnerID, tx.LandParcelID)
 	}
 
-    // Trích xuất mục đích sử dụng mới từ details một cách an toàn
-    // Helper: lấy nội dung sau "sang " đến trước dấu kết câu tiếp theo
-    extractPurpose := func(source string) string {
-        idx := strings.LastIndex(source, "sang ")
-        if idx == -1 {
-            return ""
-        }
-        rest := strings.TrimSpace(source[idx+len("sang "):])
-        // Cắt đến dấu phân cách đầu tiên (., ;, , hoặc xuống dòng)
-        cut := len(rest)
-        for _, d := range []string{".", ";", ",", "\n"} {
-            if i := strings.Index(rest, d); i != -1 && i < cut {
-                cut = i
-            }
-        }
-        return strings.TrimSpace(rest[:cut])
-    }
-
-    var newPurpose string
-    // 1) Thử trích trực tiếp từ chi tiết hiện tại
-    newPurpose = extractPurpose(tx.Details)
-
-    // 2) Nếu không có, backfill từ lịch sử giao dịch (snapshot đầu tiên thường chứa câu gốc có "sang <mục đích>")
-    if newPurpose == "" {
-        resultsIterator, err := ctx.GetStub().GetHistoryForKey(txID)
-        if err != nil {
-            return fmt.Errorf("lỗi khi lấy lịch sử giao dịch: %v", err)
-        }
-        defer resultsIterator.Close()
-        for resultsIterator.HasNext() {
-            response, err := resultsIterator.Next()
-            if err != nil {
-                return fmt.Errorf("lỗi khi đọc lịch sử giao dịch: %v", err)
-            }
-            if len(response.Value) == 0 {
-                continue
-            }
-            var snap Transaction
-            if err := json.Unmarshal(response.Value, &snap); err != nil {
-                continue
-            }
-            if p := extractPurpose(snap.Details); p != "" {
-                newPurpose = p
-                break
-            }
-        }
-    }
-
-    if newPurpose == "" {
-        return fmt.Errorf("không tìm thấy mục đích sử dụng mới trong chi tiết giao dịch hiện tại hoặc lịch sử: %s", tx.Details)
-    }
-	// Removed ValidateLandUsePurpose validation as requested
+	// Mục đích sử dụng mới lấy trực tiếp từ payload của yêu cầu
+	// (yêu cầu tạo trước khi có payload cần chạy MigrateChangePurposePayloads trước)
+	if tx.Payload == nil || tx.Payload.ChangePurpose == nil || tx.Payload.ChangePurpose.NewPurpose == "" {
+		return fmt.Errorf("giao dịch %s không có mục đích sử dụng mới trong payload, cần chạy MigrateChangePurposePayloads", txID)
+	}
+	newPurpose := tx.Payload.ChangePurpose.NewPurpose
+	// Mã có thể đã bị ngừng sử dụng kể từ khi yêu cầu được tạo
+	if err := ValidateLandUsePurpose(ctx, newPurpose); err != nil {
+		return err
+	}
 
 	txTime, err := GetTxTimestampAsTime(ctx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
 	}
 
-	// Vô hiệu hóa giấy chứng nhận cũ (do thông tin thay đổi)
+	// Thu hồi giấy chứng nhận cũ (do thông tin thay đổi), lịch sử GCN được giữ lại
 	if land.CertificateID != "" {
-		land.CertificateID = ""      // Xóa giấy chứng nhận cũ
-		land.IssueDate = time.Time{} // Reset ngày cấp
-		land.LegalInfo = "Giấy chứng nhận đã vô hiệu do thay đổi mục đích sử dụng đất"
+		legalInfo := "Giấy chứng nhận đã vô hiệu do thay đổi mục đích sử dụng đất"
+		if err := revokeLandCertificates(ctx, land, legalInfo, userID, txTime); err != nil {
+			return err
+		}
+		land.CertificateID = ""
+		land.IssueDate = time.Time{}
+		land.LegalInfo = legalInfo
 	}
 
 	// Cập nhật mục đích sử dụng
-    land.LandUsePurpose = newPurpose
+	land.LandUsePurpose = newPurpose
 	land.UpdatedAt = txTime
 	landJSON, err := json.Marshal(land)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
 	}
-	if err := ctx.GetStub().PutState(tx.LandParcelID, landJSON); err != nil {
+	if err := PutLandState(ctx, tx.LandParcelID, landJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
 	}
 
 	// Cập nhật trạng thái giao dịch
-	tx.Status = "APPROVED"
-    tx.Details = fmt.Sprintf("%s; Đã phê duyệt thay đổi mục đích sử dụng sang %s", tx.Details, newPurpose)
+	tx.Status = transition.To
+	if err := enterTransactionStage(ctx, tx); err != nil {
+		return err
+	}
+	tx.Details = fmt.Sprintf("%s; Đã phê duyệt thay đổi mục đích sử dụng sang %s", tx.Details, newPurpose)
 	tx.UpdatedAt = txTime
 	txJSON, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
 	}
 
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "APPROVE_CHANGE_PURPOSE", userID, fmt.Sprintf("Phê duyệt thay đổi mục đích sử dụng %s", txID))
+	return RecordAuditLog(ctx, "APPROVE_CHANGE_PURPOSE", userID, TransactionKeyPrefix, txID, fmt.Sprintf("Phê duyệt thay đổi mục đích sử dụng %s", txID), transactionAuditDetails(tx))
 }
 
 // RejectTransaction - Từ chối giao dịch
@@ -1740,10 +2287,9 @@ func (s *LandRegistryChaincode) RejectTransaction(ctx contractapi.TransactionCon
 	if err != nil {
 		return err
 	}
-	if tx.Status != "VERIFIED" {
-		return fmt.Errorf("giao dịch %s không ở trạng thái VERIFIED", txID)
+	if err := ApplyTransition(ctx, tx, ActionReject); err != nil {
+		return err
 	}
-	tx.Status = "REJECTED"
 	tx.Details = fmt.Sprintf("%s; Lý do từ chối: %s", tx.Details, reason)
 	txTime, err := GetTxTimestampAsTime(ctx)
 	if err != nil {
@@ -1754,9 +2300,71 @@ func (s *LandRegistryChaincode) RejectTransaction(ctx contractapi.TransactionCon
 	if err != nil {
 		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
 	}
-	if err := ctx.GetStub().PutState(txID, txJSON); err != nil {
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
+	}
+	return RecordAuditLog(ctx, "REJECT_TRANSACTION", userID, TransactionKeyPrefix, txID, fmt.Sprintf("Từ chối giao dịch %s: %s", txID, reason), transactionAuditDetails(tx))
+}
+
+// WithdrawTransaction - Người tạo yêu cầu rút hồ sơ khi giao dịch chưa được thẩm định
+// Các bên liên quan được thông báo qua sự kiện TRANSACTION_WITHDRAWN, khóa thửa đất được giải phóng
+// Tổ chức được rút hồ sơ do bảng quy trình quyết định (Org3, hoặc Org2 với yêu cầu thừa kế do Org2 tạo)
+func (s *LandRegistryChaincode) WithdrawTransaction(ctx contractapi.TransactionContextInterface, txID, reason string) error {
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := GetTransaction(ctx, txID)
+	if err != nil {
+		return err
+	}
+	if err := ApplyTransition(ctx, tx, ActionWithdraw); err != nil {
+		return err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	if reason != "" {
+		tx.Details = fmt.Sprintf("%s; Người tạo yêu cầu rút hồ sơ - Lý do: %s", tx.Details, reason)
+	} else {
+		tx.Details = fmt.Sprintf("%s; Người tạo yêu cầu rút hồ sơ", tx.Details)
+	}
+	tx.UpdatedAt = txTime
+	txJSON, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
+	}
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
 		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
 	}
-	return RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "REJECT_TRANSACTION", userID, fmt.Sprintf("Từ chối giao dịch %s: %s", txID, reason))
+
+	// Thông báo cho bên nhận và các đồng sở hữu
+	notifyIDs := []string{}
+	for _, partyID := range append([]string{tx.FromOwnerID, tx.ToOwnerID}, tx.RequiredConsents...) {
+		if partyID != "" && partyID != userID && !containsString(notifyIDs, partyID) {
+			notifyIDs = append(notifyIDs, partyID)
+		}
+	}
+	event := TransactionWithdrawnEvent{
+		TxID:         txID,
+		Type:         tx.Type,
+		LandParcelID: tx.LandParcelID,
+		WithdrawnBy:  userID,
+		Reason:       reason,
+		NotifyIDs:    notifyIDs,
+		WithdrawnAt:  txTime,
+	}
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa sự kiện: %v", err)
+	}
+	if err := ctx.GetStub().SetEvent("TRANSACTION_WITHDRAWN", eventJSON); err != nil {
+		return fmt.Errorf("lỗi khi phát sự kiện: %v", err)
+	}
+
+	auditDetails := transactionAuditDetails(tx)
+	auditDetails["reason"] = reason
+	return RecordAuditLog(ctx, "WITHDRAW_TRANSACTION", userID, TransactionKeyPrefix, txID, fmt.Sprintf("Rút hồ sơ giao dịch %s", txID), auditDetails)
 }
 
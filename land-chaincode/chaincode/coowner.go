@@ -0,0 +1,332 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// shareTolerance sai số cho phép khi kiểm tra tổng phần sở hữu
+const shareTolerance = 1e-6
+
+// consentRequiredTxTypes các loại giao dịch định đoạt thửa đất cần sự đồng ý của tất cả đồng sở hữu
+var consentRequiredTxTypes = map[string]bool{
+	"TRANSFER":       true,
+	"SPLIT":          true,
+	"MERGE":          true,
+	"CHANGE_PURPOSE": true,
+	"GIFT":           true,
+	"INHERITANCE":    true, // Các người thừa kế khác phải đồng ý với việc phân chia
+}
+
+// coOwnerInput một người và phần sở hữu trong transient map (khóa "coOwners" hoặc "heirs")
+type coOwnerInput struct {
+	CCCD  string  `json:"cccd"`  // CCCD người đồng sở hữu
+	Share float64 `json:"share"` // Phần sở hữu
+}
+
+// landOwners trả về danh sách chủ sử dụng của thửa đất, thửa đất chưa khai báo đồng sở hữu thuộc về OwnerID
+func landOwners(land *Land) []LandCoOwner {
+	if len(land.CoOwners) == 0 {
+		return []LandCoOwner{{OwnerID: land.OwnerID, Share: 1}}
+	}
+	return land.CoOwners
+}
+
+// landOwnerIDs trả về mã định danh của tất cả chủ sử dụng thửa đất
+func landOwnerIDs(land *Land) []string {
+	ids := []string{}
+	for _, owner := range landOwners(land) {
+		ids = append(ids, owner.OwnerID)
+	}
+	return ids
+}
+
+// IsLandOwner kiểm tra người dùng có phải chủ sử dụng hoặc đồng sở hữu thửa đất không
+func IsLandOwner(land *Land, userID string) bool {
+	return containsString(landOwnerIDs(land), userID)
+}
+
+// sameOwnership kiểm tra hai thửa đất có cùng chủ sử dụng và cùng phần sở hữu
+func sameOwnership(a, b *Land) bool {
+	ownersA, ownersB := landOwners(a), landOwners(b)
+	if len(ownersA) != len(ownersB) {
+		return false
+	}
+	shares := map[string]float64{}
+	for _, owner := range ownersA {
+		shares[owner.OwnerID] = owner.Share
+	}
+	for _, owner := range ownersB {
+		share, ok := shares[owner.OwnerID]
+		if !ok || math.Abs(share-owner.Share) > shareTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// landOwnerSelector tạo điều kiện Mango tìm thửa đất theo chủ sử dụng hoặc đồng sở hữu
+func landOwnerSelector(ownerID string) map[string]interface{} {
+	return map[string]interface{}{
+		"$or": []map[string]interface{}{
+			{"ownerId": ownerID},
+			{"coOwners": map[string]interface{}{"$elemMatch": map[string]interface{}{"ownerId": ownerID}}},
+		},
+	}
+}
+
+// transientCoOwners đọc danh sách người và phần sở hữu từ transient map (khóa key), trả về nil nếu không có
+// Người đầu tiên trong danh sách là người đại diện (Land.OwnerID)
+func transientCoOwners(ctx contractapi.TransactionContextInterface, key, updatedBy string, txTime time.Time, required bool) ([]LandCoOwner, error) {
+	raw, err := getTransientValue(ctx, key, required)
+	if err != nil || raw == "" {
+		return nil, err
+	}
+	var inputs []coOwnerInput
+	if err := json.Unmarshal([]byte(raw), &inputs); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã danh sách %s: %v", key, err)
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("danh sách %s trống", key)
+	}
+
+	coOwners := []LandCoOwner{}
+	var total float64
+	for _, input := range inputs {
+		if input.Share <= 0 || input.Share > 1 {
+			return nil, fmt.Errorf("phần sở hữu của %s phải lớn hơn 0 và không vượt quá 1", input.CCCD)
+		}
+		ref, err := ensurePersonalData(ctx, strings.TrimSpace(input.CCCD), updatedBy, txTime)
+		if err != nil {
+			return nil, err
+		}
+		for _, existing := range coOwners {
+			if existing.OwnerID == ref {
+				return nil, fmt.Errorf("CCCD %s bị khai báo trùng trong danh sách %s", input.CCCD, key)
+			}
+		}
+		coOwners = append(coOwners, LandCoOwner{OwnerID: ref, Share: input.Share})
+		total += input.Share
+	}
+	if math.Abs(total-1) > shareTolerance {
+		return nil, fmt.Errorf("tổng phần sở hữu phải bằng 1 (hiện tại: %g)", total)
+	}
+	return coOwners, nil
+}
+
+// applyLandOwners gán chủ sử dụng cho thửa đất, chỉ lưu danh sách đồng sở hữu khi có từ hai người trở lên
+func applyLandOwners(land *Land, owners []LandCoOwner) {
+	land.OwnerID = owners[0].OwnerID
+	land.CoOwners = nil
+	if len(owners) > 1 {
+		land.CoOwners = append([]LandCoOwner{}, owners...)
+	}
+}
+
+// ownerConsentParties tập hợp các đồng sở hữu (trừ người yêu cầu) của các thửa đất phải cho ý kiến
+func ownerConsentParties(lands []*Land, requesterID string) []string {
+	parties := []string{}
+	for _, land := range lands {
+		for _, ownerID := range landOwnerIDs(land) {
+			if ownerID != requesterID && !containsString(parties, ownerID) {
+				parties = append(parties, ownerID)
+			}
+		}
+	}
+	return parties
+}
+
+// initTransactionConsents xác định các đồng sở hữu phải đồng ý với giao dịch vừa tạo
+func initTransactionConsents(ctx contractapi.TransactionContextInterface, tx *Transaction) error {
+	if !consentRequiredTxTypes[tx.Type] {
+		return nil
+	}
+	if tx.Type == "INHERITANCE" {
+		tx.RequiredConsents = inheritanceConsentParties(tx)
+		return nil
+	}
+	parcelIDs := tx.ParcelIDs
+	if tx.LandParcelID != "" {
+		parcelIDs = []string{tx.LandParcelID}
+	}
+	lands := []*Land{}
+	for _, parcelID := range parcelIDs {
+		data, err := GetLandState(ctx, strings.TrimSpace(parcelID))
+		if err != nil {
+			return fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", parcelID, err)
+		}
+		if data == nil {
+			return fmt.Errorf("thửa đất %s không tồn tại", parcelID)
+		}
+		var land Land
+		if err := json.Unmarshal(data, &land); err != nil {
+			return fmt.Errorf("lỗi khi giải mã thửa đất: %v", err)
+		}
+		lands = append(lands, &land)
+	}
+	tx.RequiredConsents = ownerConsentParties(lands, tx.FromOwnerID)
+	return nil
+}
+
+// pendingConsents trả về các đồng sở hữu chưa đồng ý
+func pendingConsents(required []string, consents []OwnerConsent) []string {
+	pending := []string{}
+	for _, ownerID := range required {
+		accepted := false
+		for _, consent := range consents {
+			if consent.OwnerID == ownerID && consent.Accepted {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			pending = append(pending, ownerID)
+		}
+	}
+	return pending
+}
+
+// hasGivenConsent kiểm tra đồng sở hữu đã cho ý kiến chưa
+func hasGivenConsent(consents []OwnerConsent, ownerID string) bool {
+	for _, consent := range consents {
+		if consent.OwnerID == ownerID {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransactionParty kiểm tra người dùng là bên tham gia giao dịch (kể cả đồng sở hữu phải cho ý kiến)
+func isTransactionParty(tx *Transaction, userID string) bool {
+	return tx.FromOwnerID == userID || tx.ToOwnerID == userID || containsString(tx.RequiredConsents, userID)
+}
+
+// VerifyOwnerConsents kiểm tra tất cả đồng sở hữu đã đồng ý với giao dịch
+func VerifyOwnerConsents(tx *Transaction) error {
+	if pending := pendingConsents(tx.RequiredConsents, tx.Consents); len(pending) > 0 {
+		return fmt.Errorf("giao dịch %s còn chờ %d đồng sở hữu đồng ý: %s", tx.TxID, len(pending), strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// ========================================
+// CO-OWNERSHIP FUNCTIONS
+// ========================================
+
+// SetLandCoOwners - Cập nhật danh sách đồng sở hữu và phần sở hữu của thửa đất (chỉ Org1)
+// Danh sách được truyền qua transient map (khóa "coOwners"): [{"cccd": "...", "share": 0.5}, ...]
+func (s *LandRegistryChaincode) SetLandCoOwners(ctx contractapi.TransactionContextInterface, landID, reason string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	land, err := s.QueryLandByID(ctx, landID)
+	if err != nil {
+		return err
+	}
+	if err := VerifyNoOpenDispute(ctx, landID); err != nil {
+		return err
+	}
+	// Giao dịch đang mở đã chốt danh sách đồng sở hữu phải đồng ý (RequiredConsents)
+	if err := VerifyParcelNotLocked(ctx, landID); err != nil {
+		return err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	owners, err := transientCoOwners(ctx, "coOwners", userID, txTime, true)
+	if err != nil {
+		return err
+	}
+
+	previousOwners := landOwnerIDs(land)
+	applyLandOwners(land, owners)
+	land.UpdatedAt = txTime
+	landJSON, err := json.Marshal(land)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
+	}
+	if err := PutLandState(ctx, landID, landJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
+	}
+
+	shares := []string{}
+	for _, owner := range landOwners(land) {
+		shares = append(shares, fmt.Sprintf("%s:%g", owner.OwnerID, owner.Share))
+	}
+	return RecordAuditLog(ctx, "SET_LAND_CO_OWNERS", userID, LandKeyPrefix, landID,
+		fmt.Sprintf("Cập nhật %d đồng sở hữu của thửa đất %s", len(owners), landID),
+		map[string]string{"previousOwners": strings.Join(previousOwners, ","), "owners": strings.Join(shares, ","), "reason": reason})
+}
+
+// ConsentToTransaction - Đồng sở hữu đồng ý hoặc không đồng ý với giao dịch do đồng sở hữu khác tạo
+// Chỉ cần một đồng sở hữu không đồng ý thì giao dịch bị từ chối
+func (s *LandRegistryChaincode) ConsentToTransaction(ctx contractapi.TransactionContextInterface, txID, isAcceptedStr, reason string) error {
+	if err := CheckOrganization(ctx, []string{"Org3MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := s.QueryTransactionByID(ctx, txID)
+	if err != nil {
+		return err
+	}
+	accepted := isAcceptedStr == "true"
+	action := ActionConsent
+	if !accepted {
+		action = ActionWithholdConsent
+		if strings.TrimSpace(reason) == "" {
+			return fmt.Errorf("phải nêu lý do khi không đồng ý với giao dịch")
+		}
+	}
+	if err := ApplyTransition(ctx, tx, action); err != nil {
+		return err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	tx.Consents = append(tx.Consents, OwnerConsent{OwnerID: userID, Accepted: accepted, Reason: reason, At: txTime})
+	tx.UpdatedAt = txTime
+	if !accepted {
+		tx.Details = fmt.Sprintf("%s; Đồng sở hữu %s không đồng ý - Lý do: %s", tx.Details, userID, reason)
+	}
+	txJSON, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
+	}
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu giao dịch: %v", err)
+	}
+
+	auditAction := "CONSENT_TRANSACTION_ACCEPTED"
+	if !accepted {
+		auditAction = "CONSENT_TRANSACTION_REJECTED"
+	}
+	details := transactionAuditDetails(tx)
+	details["reason"] = reason
+	details["pendingConsents"] = strings.Join(pendingConsents(tx.RequiredConsents, tx.Consents), ",")
+	return RecordAuditLog(ctx, auditAction, userID, TransactionKeyPrefix, txID,
+		fmt.Sprintf("Đồng sở hữu cho ý kiến về giao dịch %s: %s", txID, tx.Status), details)
+}
+
+// QueryPendingConsents - Truy vấn các đồng sở hữu chưa đồng ý với giao dịch
+func (s *LandRegistryChaincode) QueryPendingConsents(ctx contractapi.TransactionContextInterface, txID string) ([]string, error) {
+	tx, err := s.QueryTransactionByID(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	return pendingConsents(tx.RequiredConsents, tx.Consents), nil
+}
@@ -0,0 +1,413 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// openTxStatuses các trạng thái giao dịch còn đang xử lý (có thể có thời hạn)
+var openTxStatuses = []string{TxStatusPending, TxStatusConfirmed, TxStatusSupplementRequested, TxStatusVerified}
+
+// defaultDeadlinePolicies thời hạn xử lý mặc định (số ngày) theo loại giao dịch và trạng thái
+// PENDING của TRANSFER/GIFT là thời hạn người nhận xác nhận, của các loại khác là thời hạn Org2 thẩm định
+var defaultDeadlinePolicies = map[string]map[string]int{
+	"TRANSFER":       {TxStatusPending: 15, TxStatusConfirmed: 10, TxStatusSupplementRequested: 30, TxStatusVerified: 10},
+	"GIFT":           {TxStatusPending: 15, TxStatusConfirmed: 10, TxStatusSupplementRequested: 30, TxStatusVerified: 10},
+	"SPLIT":          {TxStatusPending: 15, TxStatusSupplementRequested: 30, TxStatusVerified: 15},
+	"MERGE":          {TxStatusPending: 15, TxStatusSupplementRequested: 30, TxStatusVerified: 15},
+	"CHANGE_PURPOSE": {TxStatusPending: 10, TxStatusSupplementRequested: 30, TxStatusVerified: 10},
+	"REISSUE":        {TxStatusPending: 7, TxStatusSupplementRequested: 30, TxStatusVerified: 7},
+	"INHERITANCE":    {TxStatusPending: 15, TxStatusSupplementRequested: 30, TxStatusVerified: 10},
+}
+
+// DeadlinePolicy thời hạn xử lý theo trạng thái của một loại giao dịch, lưu trên sổ cái
+type DeadlinePolicy struct {
+	TxType    string         `json:"txType"`    // Loại giao dịch
+	StageDays map[string]int `json:"stageDays"` // Số ngày xử lý tối đa theo trạng thái (không có nghĩa là không giới hạn)
+	UpdatedBy string         `json:"updatedBy"` // Mã định danh người cập nhật gần nhất
+	UpdatedAt time.Time      `json:"updatedAt"` // Thời gian cập nhật gần nhất
+}
+
+// GetDeadlinePolicy đọc thời hạn xử lý của loại giao dịch, dùng mặc định nếu chưa cấu hình
+func GetDeadlinePolicy(ctx contractapi.TransactionContextInterface, txType string) (*DeadlinePolicy, error) {
+	defaults, exists := defaultDeadlinePolicies[txType]
+	if !exists {
+		return nil, fmt.Errorf("loại giao dịch %s không hợp lệ", txType)
+	}
+	key, err := createEntityKey(ctx, DeadlinePolicyKeyPrefix, txType)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi đọc thời hạn xử lý %s: %v", txType, err)
+	}
+	policy := &DeadlinePolicy{TxType: txType, StageDays: map[string]int{}}
+	if data == nil {
+		for status, days := range defaults {
+			policy.StageDays[status] = days
+		}
+		return policy, nil
+	}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã thời hạn xử lý %s: %v", txType, err)
+	}
+	if policy.StageDays == nil {
+		policy.StageDays = map[string]int{}
+	}
+	return policy, nil
+}
+
+// scheduleStageDeadline tính hạn xử lý khi giao dịch chuyển sang trạng thái mới
+// Không thay đổi gì nếu giao dịch vẫn ở trạng thái đã được tính hạn (ví dụ đồng sở hữu cho ý kiến)
+func scheduleStageDeadline(ctx contractapi.TransactionContextInterface, tx *Transaction) error {
+	if tx.StageStatus == tx.Status && !tx.StageEnteredAt.IsZero() {
+		return nil
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	tx.StageStatus = tx.Status
+	tx.StageEnteredAt = txTime
+	tx.StageDueAt = time.Time{}
+	if !containsString(openTxStatuses, tx.Status) {
+		return nil
+	}
+	policy, err := GetDeadlinePolicy(ctx, tx.Type)
+	if err != nil {
+		return err
+	}
+	if days := policy.StageDays[tx.Status]; days > 0 {
+		tx.StageDueAt = txTime.AddDate(0, 0, days)
+	}
+	return nil
+}
+
+// stageDueAt hạn xử lý của trạng thái hiện tại (zero nếu không giới hạn)
+// Giao dịch tạo trước khi có thời hạn xử lý (chưa có StageEnteredAt) được tính hạn từ UpdatedAt
+func stageDueAt(tx *Transaction, policy *DeadlinePolicy) time.Time {
+	if !tx.StageEnteredAt.IsZero() {
+		if tx.StageStatus != tx.Status {
+			return time.Time{}
+		}
+		return tx.StageDueAt
+	}
+	if days := policy.StageDays[tx.Status]; days > 0 && !tx.UpdatedAt.IsZero() {
+		return tx.UpdatedAt.AddDate(0, 0, days)
+	}
+	return time.Time{}
+}
+
+// filterOverdueTransactions lọc các giao dịch đang xử lý đã quá hạn tại thời điểm at
+// StageDueAt của giao dịch trả về được gán hạn đã tính (kể cả giao dịch cũ chưa có hạn)
+func filterOverdueTransactions(ctx contractapi.TransactionContextInterface, transactions []*Transaction, at time.Time) ([]*Transaction, error) {
+	policies := map[string]*DeadlinePolicy{}
+	overdue := []*Transaction{}
+	for _, tx := range transactions {
+		if !containsString(openTxStatuses, tx.Status) {
+			continue
+		}
+		if _, exists := defaultDeadlinePolicies[tx.Type]; !exists {
+			continue
+		}
+		policy, exists := policies[tx.Type]
+		if !exists {
+			var err error
+			if policy, err = GetDeadlinePolicy(ctx, tx.Type); err != nil {
+				return nil, err
+			}
+			policies[tx.Type] = policy
+		}
+		dueAt := stageDueAt(tx, policy)
+		if dueAt.IsZero() || !dueAt.Before(at) {
+			continue
+		}
+		tx.StageDueAt = dueAt
+		overdue = append(overdue, tx)
+	}
+	return overdue, nil
+}
+
+// stageResponsibleOrgs các tổ chức phải xử lý giao dịch ở trạng thái hiện tại (theo bảng chuyển trạng thái)
+// Bỏ qua ý kiến đồng sở hữu, rút hồ sơ và hủy do quá hạn vì không phải bước xử lý chính
+func stageResponsibleOrgs(tx *Transaction) []string {
+	orgs := []string{}
+	for _, transition := range workflowTransitions[tx.Type] {
+		if transition.From != tx.Status {
+			continue
+		}
+		switch transition.Action {
+		case ActionConsent, ActionWithholdConsent, ActionWithdraw, ActionExpire:
+			continue
+		}
+		for _, org := range transition.Orgs {
+			if !containsString(orgs, org) {
+				orgs = append(orgs, org)
+			}
+		}
+	}
+	return orgs
+}
+
+// overdueSelector tạo selector cho các giao dịch có thể đã quá hạn tại thời điểm at
+// stageDueAt được so sánh dạng chuỗi nên đúng khi mọi thời điểm đều ghi theo múi giờ của GetTxTimestampAsTime
+// Giao dịch cũ chưa có StageEnteredAt được giữ lại để tính hạn từ UpdatedAt, filterOverdueTransactions kiểm tra lại từng giao dịch
+func overdueSelector(stages map[string][]string, at time.Time) map[string]interface{} {
+	stageConditions := []interface{}{}
+	txTypes := []string{}
+	for txType := range stages {
+		txTypes = append(txTypes, txType)
+	}
+	sort.Strings(txTypes)
+	for _, txType := range txTypes {
+		stageConditions = append(stageConditions, map[string]interface{}{
+			"type":   txType,
+			"status": map[string]interface{}{"$in": stages[txType]},
+		})
+	}
+	zeroTime := time.Time{}.Format(time.RFC3339)
+	return map[string]interface{}{
+		"txId": map[string]interface{}{"$exists": true},
+		"$and": []interface{}{
+			map[string]interface{}{"$or": stageConditions},
+			map[string]interface{}{"$or": []interface{}{
+				map[string]interface{}{"stageDueAt": map[string]interface{}{"$gt": zeroTime, "$lt": at.Format(time.RFC3339Nano)}},
+				map[string]interface{}{"stageEnteredAt": map[string]interface{}{"$exists": false}},
+				map[string]interface{}{"stageEnteredAt": zeroTime},
+			}},
+		},
+	}
+}
+
+// overdueStages các cặp loại giao dịch - trạng thái có thời hạn mà tổ chức org phải xử lý (org rỗng là tất cả)
+func overdueStages(org string) map[string][]string {
+	stages := map[string][]string{}
+	for txType := range defaultDeadlinePolicies {
+		for _, status := range openTxStatuses {
+			if org != "" && !containsString(stageResponsibleOrgs(&Transaction{Type: txType, Status: status}), org) {
+				continue
+			}
+			stages[txType] = append(stages[txType], status)
+		}
+	}
+	return stages
+}
+
+// ========================================
+// DEADLINE FUNCTIONS
+// ========================================
+
+// SetDeadlinePolicy - Cấu hình thời hạn xử lý theo trạng thái cho loại giao dịch (chỉ Org1)
+// stageDaysStr: JSON {"PENDING": 15, "VERIFIED": 10, ...}, số ngày 0 nghĩa là không giới hạn
+// Hạn mới áp dụng cho các giao dịch chuyển trạng thái sau khi cấu hình
+func (s *LandRegistryChaincode) SetDeadlinePolicy(ctx contractapi.TransactionContextInterface, txType, stageDaysStr string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	policy, err := GetDeadlinePolicy(ctx, txType)
+	if err != nil {
+		return err
+	}
+	var stageDays map[string]int
+	if err := json.Unmarshal([]byte(stageDaysStr), &stageDays); err != nil {
+		return fmt.Errorf("lỗi khi giải mã thời hạn xử lý: %v", err)
+	}
+	stages := map[string]int{}
+	summary := []string{}
+	for status, days := range stageDays {
+		status = strings.ToUpper(strings.TrimSpace(status))
+		if !containsString(openTxStatuses, status) {
+			return fmt.Errorf("trạng thái %s không phải bước xử lý, phải là một trong: %s", status, strings.Join(openTxStatuses, ", "))
+		}
+		if days < 0 {
+			return fmt.Errorf("số ngày xử lý của trạng thái %s không được âm", status)
+		}
+		if days == 0 {
+			continue
+		}
+		stages[status] = days
+		summary = append(summary, fmt.Sprintf("%s=%d", status, days))
+	}
+	sort.Strings(summary)
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	policy.StageDays = stages
+	policy.UpdatedBy = userID
+	policy.UpdatedAt = txTime
+	key, err := createEntityKey(ctx, DeadlinePolicyKeyPrefix, txType)
+	if err != nil {
+		return err
+	}
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa thời hạn xử lý: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, policyJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu thời hạn xử lý %s: %v", txType, err)
+	}
+	return RecordAuditLog(ctx, "SET_DEADLINE_POLICY", userID, DeadlinePolicyKeyPrefix, txType,
+		fmt.Sprintf("Cấu hình thời hạn xử lý cho giao dịch %s", txType), map[string]string{"stageDays": strings.Join(summary, ",")})
+}
+
+// GetDeadlinePolicies - Truy vấn thời hạn xử lý của tất cả loại giao dịch
+func (s *LandRegistryChaincode) GetDeadlinePolicies(ctx contractapi.TransactionContextInterface) ([]*DeadlinePolicy, error) {
+	txTypes := []string{}
+	for txType := range defaultDeadlinePolicies {
+		txTypes = append(txTypes, txType)
+	}
+	sort.Strings(txTypes)
+	policies := []*DeadlinePolicy{}
+	for _, txType := range txTypes {
+		policy, err := GetDeadlinePolicy(ctx, txType)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// QueryOverdueTransactions - Truy vấn các giao dịch quá hạn xử lý theo trang (Org1, Org2)
+// org: MSP của tổ chức phải xử lý bước hiện tại (Org1MSP, Org2MSP, Org3MSP), để trống để lấy tất cả
+// Trang có thể ít bản ghi hơn pageSize vì giao dịch cũ chưa có hạn xử lý được lọc lại sau truy vấn
+func (s *LandRegistryChaincode) QueryOverdueTransactions(ctx contractapi.TransactionContextInterface, org string, pageSize int32, bookmark string) (*TransactionPage, error) {
+	if err := CheckOrganization(ctx, []string{"Org1MSP", "Org2MSP"}); err != nil {
+		return nil, err
+	}
+	org = strings.TrimSpace(org)
+	if org != "" && !strings.HasSuffix(org, "MSP") {
+		org += "MSP"
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	stages := overdueStages(org)
+	if len(stages) == 0 {
+		return &TransactionPage{Records: []*Transaction{}}, nil
+	}
+	queryBytes, err := json.Marshal(map[string]interface{}{"selector": overdueSelector(stages, txTime)})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi tạo truy vấn: %v", err)
+	}
+	resultsIterator, metadata, err := getQueryResultWithPagination(ctx, string(queryBytes), pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	transactions, err := s.collectTransactions(ctx, resultsIterator)
+	if err != nil {
+		return nil, err
+	}
+	overdue, err := filterOverdueTransactions(ctx, transactions, txTime)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(overdue, func(i, j int) bool {
+		return overdue[i].StageDueAt.Before(overdue[j].StageDueAt)
+	})
+	return &TransactionPage{
+		Records:      overdue,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+	}, nil
+}
+
+// SweepExpiredTransactions - Hủy các giao dịch chuyển nhượng, tặng cho mà người nhận không xác nhận trong thời hạn
+// Gọi định kỳ bởi Org1 hoặc Org2, limit giới hạn số giao dịch xử lý mỗi lần (0 = mặc định 100)
+func (s *LandRegistryChaincode) SweepExpiredTransactions(ctx contractapi.TransactionContextInterface, limit int) ([]string, error) {
+	if err := CheckOrganization(ctx, []string{"Org1MSP", "Org2MSP"}); err != nil {
+		return nil, err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	stages := map[string][]string{}
+	for txType := range recipientConfirmTxTypes {
+		stages[txType] = []string{TxStatusPending}
+	}
+	queryBytes, err := json.Marshal(map[string]interface{}{"selector": overdueSelector(stages, txTime)})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi tạo truy vấn: %v", err)
+	}
+	// Fabric không cho phân trang khi submit nên đọc tuần tự và dừng khi đủ limit
+	// Giao dịch đã hủy rời khỏi tập kết quả nên lần gọi sau không cần bookmark
+	resultsIterator, err := ctx.GetStub().GetQueryResult(string(queryBytes))
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn giao dịch: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	expired := []string{}
+	for len(expired) < limit && resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if KeyNamespace(ctx, response.Key) != TransactionKeyPrefix {
+			continue
+		}
+		var candidate *Transaction
+		if err := json.Unmarshal(response.Value, &candidate); err != nil {
+			return nil, fmt.Errorf("lỗi khi giải mã giao dịch: %v", err)
+		}
+		overdue, err := filterOverdueTransactions(ctx, []*Transaction{candidate}, txTime)
+		if err != nil {
+			return nil, err
+		}
+		if len(overdue) == 0 || !recipientConfirmTxTypes[candidate.Type] {
+			continue
+		}
+		tx := overdue[0]
+		dueAt := tx.StageDueAt
+		if err := ApplyTransition(ctx, tx, ActionExpire); err != nil {
+			return nil, err
+		}
+		tx.Details = fmt.Sprintf("%s; Tự động hủy do người nhận không xác nhận trước %s", tx.Details, dueAt.Format("2006-01-02 15:04"))
+		tx.UpdatedAt = txTime
+		txJSON, err := json.Marshal(tx)
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
+		}
+		if err := PutTransactionState(ctx, tx.TxID, txJSON); err != nil {
+			return nil, fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
+		}
+		expired = append(expired, tx.TxID)
+	}
+
+	if len(expired) > 0 {
+		eventJSON, err := json.Marshal(map[string]interface{}{"txIds": expired, "expiredAt": txTime})
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi mã hóa sự kiện: %v", err)
+		}
+		if err := ctx.GetStub().SetEvent("TRANSACTIONS_EXPIRED", eventJSON); err != nil {
+			return nil, fmt.Errorf("lỗi khi phát sự kiện: %v", err)
+		}
+	}
+	// Ghi một nhật ký cho cả lô vì mã nhật ký gắn với mã giao dịch Fabric và hành động
+	return expired, RecordAuditLog(ctx, "SWEEP_EXPIRED_TRANSACTIONS", userID, "", "",
+		fmt.Sprintf("Hủy %d giao dịch quá hạn xác nhận", len(expired)), map[string]string{"count": strconv.Itoa(len(expired)), "txIds": strings.Join(expired, ",")})
+}
@@ -0,0 +1,365 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Trạng thái vụ tranh chấp
+const (
+	DisputeStatusOpen     = "OPEN"     // Đang giải quyết
+	DisputeStatusResolved = "RESOLVED" // Đã giải quyết
+)
+
+// Kết quả giải quyết tranh chấp
+const (
+	DisputeOutcomeUpheld    = "UPHELD"    // Chấp nhận yêu cầu của người khởi kiện
+	DisputeOutcomeDismissed = "DISMISSED" // Bác yêu cầu
+	DisputeOutcomeSettled   = "SETTLED"   // Hòa giải thành
+)
+
+// LegalStatusDisputed trạng thái pháp lý của thửa đất khi đang tranh chấp
+const LegalStatusDisputed = "Đang tranh chấp"
+
+// getDispute đọc vụ tranh chấp theo mã
+func getDispute(ctx contractapi.TransactionContextInterface, disputeID string) (*Dispute, error) {
+	data, err := GetDisputeState(ctx, disputeID)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn tranh chấp %s: %v", disputeID, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("tranh chấp %s không tồn tại", disputeID)
+	}
+	var dispute Dispute
+	if err := json.Unmarshal(data, &dispute); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã tranh chấp %s: %v", disputeID, err)
+	}
+	if dispute.EvidenceDocIDs == nil {
+		dispute.EvidenceDocIDs = []string{}
+	}
+	return &dispute, nil
+}
+
+// putDispute lưu vụ tranh chấp cùng chỉ mục theo thửa đất
+func putDispute(ctx contractapi.TransactionContextInterface, dispute *Dispute) error {
+	disputeJSON, err := json.Marshal(dispute)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa tranh chấp: %v", err)
+	}
+	if err := PutDisputeState(ctx, dispute.DisputeID, disputeJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu tranh chấp %s: %v", dispute.DisputeID, err)
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(DisputeLandIndex, []string{dispute.LandParcelID, dispute.DisputeID})
+	if err != nil {
+		return fmt.Errorf("lỗi khi tạo chỉ mục tranh chấp %s: %v", dispute.DisputeID, err)
+	}
+	if err := ctx.GetStub().PutState(indexKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("lỗi khi lưu chỉ mục tranh chấp %s: %v", dispute.DisputeID, err)
+	}
+	return nil
+}
+
+// getDisputesByLand liệt kê tất cả vụ tranh chấp của thửa đất
+func getDisputesByLand(ctx contractapi.TransactionContextInterface, landParcelID string) ([]*Dispute, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(DisputeLandIndex, []string{landParcelID})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn tranh chấp của thửa đất %s: %v", landParcelID, err)
+	}
+	defer resultsIterator.Close()
+
+	disputes := []*Dispute{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi đọc chỉ mục tranh chấp: %v", err)
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil || len(attributes) != 2 {
+			continue
+		}
+		dispute, err := getDispute(ctx, attributes[1])
+		if err != nil {
+			return nil, err
+		}
+		disputes = append(disputes, dispute)
+	}
+	return disputes, nil
+}
+
+// VerifyNoOpenDispute kiểm tra thửa đất không có vụ tranh chấp đang giải quyết
+func VerifyNoOpenDispute(ctx contractapi.TransactionContextInterface, landParcelID string) error {
+	disputes, err := getDisputesByLand(ctx, landParcelID)
+	if err != nil {
+		return err
+	}
+	for _, dispute := range disputes {
+		if dispute.Status == DisputeStatusOpen {
+			return fmt.Errorf("thửa đất %s đang có tranh chấp %s chưa được giải quyết", landParcelID, dispute.DisputeID)
+		}
+	}
+	return nil
+}
+
+// parseEvidenceDocIDs giải mã và kiểm tra danh sách tài liệu chứng cứ
+func parseEvidenceDocIDs(ctx contractapi.TransactionContextInterface, docIDsStr string) ([]string, error) {
+	docIDs := []string{}
+	if strings.TrimSpace(docIDsStr) == "" {
+		return docIDs, nil
+	}
+	if err := json.Unmarshal([]byte(docIDsStr), &docIDs); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã danh sách tài liệu chứng cứ: %v", err)
+	}
+	for _, docID := range docIDs {
+		if _, err := GetDocument(ctx, docID); err != nil {
+			return nil, fmt.Errorf("tài liệu chứng cứ %s không hợp lệ: %v", docID, err)
+		}
+	}
+	return docIDs, nil
+}
+
+// ========================================
+// DISPUTE MANAGEMENT FUNCTIONS
+// ========================================
+
+// OpenDispute - Tiếp nhận vụ tranh chấp và đóng băng thửa đất (Org1, Org2)
+// CCCD người khởi kiện được truyền qua transient map (khóa "claimantId")
+func (s *LandRegistryChaincode) OpenDispute(ctx contractapi.TransactionContextInterface, landParcelID, description, evidenceDocIdsStr string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP", "Org2MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	claimantID, err := transientSubjectRef(ctx, "claimantId", userID, txTime, true)
+	if err != nil {
+		return fmt.Errorf("CCCD người khởi kiện không hợp lệ: %v", err)
+	}
+	if strings.TrimSpace(description) == "" {
+		return fmt.Errorf("nội dung tranh chấp không được để trống")
+	}
+	land, err := s.QueryLandByID(ctx, landParcelID)
+	if err != nil {
+		return err
+	}
+	if IsLandOwner(land, claimantID) {
+		return fmt.Errorf("người khởi kiện %s đang là chủ sử dụng thửa đất %s", claimantID, landParcelID)
+	}
+	if err := VerifyNoOpenDispute(ctx, landParcelID); err != nil {
+		return err
+	}
+	evidenceDocIDs, err := parseEvidenceDocIDs(ctx, evidenceDocIdsStr)
+	if err != nil {
+		return err
+	}
+
+	disputeID := fmt.Sprintf("TRANH_CHAP_%d_%s", txTime.Unix(), landParcelID)
+	dispute := &Dispute{
+		DisputeID:           disputeID,
+		LandParcelID:        landParcelID,
+		ClaimantID:          claimantID,
+		RespondentID:        land.OwnerID,
+		Description:         description,
+		EvidenceDocIDs:      evidenceDocIDs,
+		Status:              DisputeStatusOpen,
+		PreviousLegalStatus: land.LegalStatus,
+		OpenedBy:            userID,
+		CreatedAt:           txTime,
+		UpdatedAt:           txTime,
+	}
+	if err := putDispute(ctx, dispute); err != nil {
+		return err
+	}
+	if err := refreshLandLegalStatus(ctx, land, dispute, nil, txTime); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "OPEN_DISPUTE", userID, DisputeKeyPrefix, disputeID,
+		fmt.Sprintf("Tiếp nhận tranh chấp thửa đất %s", landParcelID),
+		map[string]string{"landParcelId": landParcelID, "claimantId": claimantID, "respondentId": land.OwnerID})
+}
+
+// AddDisputeEvidence - Bổ sung tài liệu chứng cứ cho vụ tranh chấp đang giải quyết (Org1, Org2)
+func (s *LandRegistryChaincode) AddDisputeEvidence(ctx contractapi.TransactionContextInterface, disputeID, evidenceDocIdsStr string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP", "Org2MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	dispute, err := getDispute(ctx, disputeID)
+	if err != nil {
+		return err
+	}
+	if dispute.Status != DisputeStatusOpen {
+		return fmt.Errorf("tranh chấp %s đã được giải quyết", disputeID)
+	}
+	evidenceDocIDs, err := parseEvidenceDocIDs(ctx, evidenceDocIdsStr)
+	if err != nil {
+		return err
+	}
+	if len(evidenceDocIDs) == 0 {
+		return fmt.Errorf("danh sách tài liệu chứng cứ trống")
+	}
+	added := []string{}
+	for _, docID := range evidenceDocIDs {
+		if !containsString(dispute.EvidenceDocIDs, docID) {
+			dispute.EvidenceDocIDs = append(dispute.EvidenceDocIDs, docID)
+			added = append(added, docID)
+		}
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	dispute.UpdatedAt = txTime
+	if err := putDispute(ctx, dispute); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "ADD_DISPUTE_EVIDENCE", userID, DisputeKeyPrefix, disputeID,
+		fmt.Sprintf("Bổ sung %d tài liệu chứng cứ cho tranh chấp %s", len(added), disputeID),
+		map[string]string{"landParcelId": dispute.LandParcelID, "documentIds": strings.Join(added, ",")})
+}
+
+// ResolveDispute - Giải quyết tranh chấp, mở băng thửa đất và thay đổi chủ sử dụng nếu có (Org1, Org2)
+// Chỉ Org1 được thay đổi chủ sử dụng (CCCD qua transient map, khóa "newOwnerId"), khi đó GCN hiện hành bị thu hồi
+func (s *LandRegistryChaincode) ResolveDispute(ctx contractapi.TransactionContextInterface, disputeID, outcome, resolutionDocID, resolutionNote string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP", "Org2MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return err
+	}
+	dispute, err := getDispute(ctx, disputeID)
+	if err != nil {
+		return err
+	}
+	if dispute.Status != DisputeStatusOpen {
+		return fmt.Errorf("tranh chấp %s đã được giải quyết", disputeID)
+	}
+	switch outcome {
+	case DisputeOutcomeUpheld, DisputeOutcomeDismissed, DisputeOutcomeSettled:
+	default:
+		return fmt.Errorf("kết quả giải quyết %s không hợp lệ (UPHELD, DISMISSED, SETTLED)", outcome)
+	}
+	if _, err := GetDocument(ctx, resolutionDocID); err != nil {
+		return fmt.Errorf("tài liệu quyết định giải quyết không hợp lệ: %v", err)
+	}
+
+	land, err := s.QueryLandByID(ctx, dispute.LandParcelID)
+	if err != nil {
+		return err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	newOwnerID, err := transientSubjectRef(ctx, "newOwnerId", userID, txTime, false)
+	if err != nil {
+		return fmt.Errorf("CCCD chủ sử dụng mới không hợp lệ: %v", err)
+	}
+	if newOwnerID != "" {
+		if mspID != "Org1MSP" {
+			return fmt.Errorf("chỉ Org1MSP được thay đổi chủ sử dụng khi giải quyết tranh chấp")
+		}
+		if outcome == DisputeOutcomeDismissed {
+			return fmt.Errorf("không thể thay đổi chủ sử dụng khi yêu cầu tranh chấp bị bác")
+		}
+		if newOwnerID == land.OwnerID && len(land.CoOwners) == 0 {
+			return fmt.Errorf("chủ sử dụng mới %s trùng với chủ sử dụng hiện tại", newOwnerID)
+		}
+		if err := VerifyNoActiveMortgage(ctx, land.ID); err != nil {
+			return err
+		}
+	}
+
+	dispute.Status = DisputeStatusResolved
+	dispute.Outcome = outcome
+	dispute.ResolutionDocID = resolutionDocID
+	dispute.ResolutionNote = resolutionNote
+	dispute.NewOwnerID = newOwnerID
+	dispute.ResolvedBy = userID
+	dispute.ResolvedAt = txTime
+	dispute.UpdatedAt = txTime
+	if err := putDispute(ctx, dispute); err != nil {
+		return err
+	}
+
+	if newOwnerID != "" {
+		legalInfo := fmt.Sprintf("Giấy chứng nhận đã vô hiệu do giải quyết tranh chấp %s", disputeID)
+		if err := revokeLandCertificates(ctx, land, legalInfo, userID, txTime); err != nil {
+			return err
+		}
+		land.CertificateID = ""
+		land.LegalInfo = legalInfo
+		land.IssueDate = time.Time{}
+		land.OwnerID = newOwnerID
+		land.CoOwners = nil
+	}
+	// Trạng thái pháp lý được tính lại từ các tranh chấp, thế chấp còn hiệu lực
+	if err := refreshLandLegalStatus(ctx, land, dispute, nil, txTime); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "RESOLVE_DISPUTE", userID, DisputeKeyPrefix, disputeID,
+		fmt.Sprintf("Giải quyết tranh chấp %s: %s", disputeID, outcome),
+		map[string]string{"landParcelId": dispute.LandParcelID, "outcome": outcome, "newOwnerId": newOwnerID})
+}
+
+// QueryDisputeByID - Truy vấn vụ tranh chấp theo mã
+func (s *LandRegistryChaincode) QueryDisputeByID(ctx contractapi.TransactionContextInterface, disputeID string) (*Dispute, error) {
+	dispute, err := getDispute(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDisputeAccess(ctx, dispute); err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// QueryDisputesByLand - Truy vấn các vụ tranh chấp của thửa đất
+func (s *LandRegistryChaincode) QueryDisputesByLand(ctx contractapi.TransactionContextInterface, landParcelID string) ([]*Dispute, error) {
+	disputes, err := getDisputesByLand(ctx, landParcelID)
+	if err != nil {
+		return nil, err
+	}
+	result := []*Dispute{}
+	for _, dispute := range disputes {
+		if checkDisputeAccess(ctx, dispute) == nil {
+			result = append(result, dispute)
+		}
+	}
+	return result, nil
+}
+
+// checkDisputeAccess Org3 chỉ xem các vụ tranh chấp mà mình là một bên
+func checkDisputeAccess(ctx contractapi.TransactionContextInterface, dispute *Dispute) error {
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return err
+	}
+	if mspID != "Org3MSP" {
+		return nil
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	if dispute.ClaimantID != userID && dispute.RespondentID != userID {
+		return fmt.Errorf("người dùng %s không có quyền xem tranh chấp %s", userID, dispute.DisputeID)
+	}
+	return nil
+}
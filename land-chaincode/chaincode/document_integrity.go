@@ -0,0 +1,75 @@
+package chaincode
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// DocumentIntegrityResult kết quả đối chiếu SHA-256 của file với dữ liệu ghi nhận trên sổ cái
+type DocumentIntegrityResult struct {
+	DocID               string    `json:"docID"`                         // Mã tài liệu
+	SuppliedSHA256      string    `json:"suppliedSha256"`                // SHA-256 của file cần đối chiếu
+	Matches             bool      `json:"matches"`                       // File khớp với phiên bản hiện hành
+	MatchedVersion      int       `json:"matchedVersion"`                // Phiên bản khớp (0 nếu không khớp phiên bản nào)
+	CurrentVersion      int       `json:"currentVersion"`                // Phiên bản hiện hành
+	AnchoredSHA256      string    `json:"anchoredSha256"`                // SHA-256 của phiên bản hiện hành
+	FileSize            int64     `json:"fileSize"`                      // Kích thước file của phiên bản hiện hành (bytes)
+	IPFSHash            string    `json:"ipfsHash"`                      // Hash IPFS của phiên bản hiện hành
+	Status              string    `json:"status"`                        // Trạng thái thẩm định hiện hành
+	IntegrityVerifiedAt time.Time `json:"integrityVerifiedAt,omitempty"` // Thời gian Org2 xác thực nội dung hiện hành
+	Message             string    `json:"message"`                       // Kết luận đối chiếu
+}
+
+// VerifyDocumentIntegrity - Đối chiếu SHA-256 của file tải về với nội dung đã ghi nhận của tài liệu
+// Nếu file khớp một phiên bản cũ, kết quả cho biết phiên bản đó để phân biệt với file giả mạo
+func (s *LandRegistryChaincode) VerifyDocumentIntegrity(ctx contractapi.TransactionContextInterface, docID, sha256 string) (*DocumentIntegrityResult, error) {
+	if err := ValidateSHA256(sha256); err != nil {
+		return nil, err
+	}
+	doc, err := GetDocument(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+	supplied := NormalizeSHA256(sha256)
+	result := &DocumentIntegrityResult{
+		DocID:               docID,
+		SuppliedSHA256:      supplied,
+		CurrentVersion:      currentDocumentVersion(doc),
+		AnchoredSHA256:      doc.ContentSHA256,
+		FileSize:            doc.FileSize,
+		IPFSHash:            doc.IPFSHash,
+		Status:              doc.Status,
+		IntegrityVerifiedAt: doc.IntegrityVerifiedAt,
+	}
+
+	if doc.ContentSHA256 == "" {
+		result.Message = fmt.Sprintf("tài liệu %s chưa được ghi nhận SHA-256, không thể đối chiếu", docID)
+		return result, nil
+	}
+	if doc.ContentSHA256 == supplied {
+		result.Matches = true
+		result.MatchedVersion = result.CurrentVersion
+		if doc.IntegrityVerifiedAt.IsZero() {
+			result.Message = "File khớp với phiên bản hiện hành nhưng chưa được Org2 xác thực"
+		} else {
+			result.Message = "File khớp với phiên bản hiện hành đã được Org2 xác thực"
+		}
+		return result, nil
+	}
+
+	versions, err := getDocumentVersions(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+	for _, version := range versions {
+		if version.ContentSHA256 != "" && version.ContentSHA256 == supplied {
+			result.MatchedVersion = version.Version
+			result.Message = fmt.Sprintf("File khớp với phiên bản cũ %d, không phải phiên bản hiện hành", version.Version)
+			return result, nil
+		}
+	}
+	result.Message = "File không khớp với bất kỳ phiên bản nào của tài liệu"
+	return result, nil
+}
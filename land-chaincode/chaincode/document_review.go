@@ -0,0 +1,105 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Các bước trong lịch sử thẩm định tài liệu
+const (
+	DocumentReviewVerify   = "VERIFY"   // Org2 xác thực
+	DocumentReviewReject   = "REJECT"   // Org2 từ chối
+	DocumentReviewResubmit = "RESUBMIT" // Người nộp giải trình và nộp lại
+	DocumentReviewRevise   = "REVISE"   // Người nộp thay nội dung tài liệu
+)
+
+// Mã lý do từ chối tài liệu
+var documentRejectionReasons = map[string]string{
+	"ILLEGIBLE":         "Tài liệu không rõ ràng, không đọc được",
+	"INCOMPLETE":        "Tài liệu thiếu trang hoặc thiếu nội dung",
+	"INFO_MISMATCH":     "Thông tin không khớp với hồ sơ thửa đất hoặc người nộp",
+	"EXPIRED":           "Tài liệu đã hết hiệu lực",
+	"WRONG_TYPE":        "Tài liệu không đúng loại hoặc thành phần hồ sơ",
+	"MISSING_SIGNATURE": "Thiếu chữ ký hoặc con dấu",
+	"OTHER":             "Lý do khác",
+}
+
+// ValidateRejectionReasonCode kiểm tra mã lý do từ chối hợp lệ
+func ValidateRejectionReasonCode(reasonCode string) error {
+	if _, ok := documentRejectionReasons[reasonCode]; !ok {
+		return fmt.Errorf("mã lý do từ chối %s không hợp lệ (ILLEGIBLE, INCOMPLETE, INFO_MISMATCH, EXPIRED, WRONG_TYPE, MISSING_SIGNATURE, OTHER)", reasonCode)
+	}
+	return nil
+}
+
+// appendDocumentReview ghi thêm một bước vào lịch sử thẩm định của tài liệu
+func appendDocumentReview(doc *Document, action, reasonCode, note, actorID string, at time.Time) {
+	doc.Reviews = append(doc.Reviews, DocumentReview{
+		Action:     action,
+		ReasonCode: reasonCode,
+		Note:       note,
+		ActorID:    actorID,
+		Version:    currentDocumentVersion(doc),
+		At:         at,
+	})
+}
+
+// ResubmitDocument - Người nộp giải trình và đưa tài liệu bị từ chối về trạng thái chờ xác thực
+func (s *LandRegistryChaincode) ResubmitDocument(ctx contractapi.TransactionContextInterface, docID, explanation string) error {
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	doc, err := s.GetDocument(ctx, docID)
+	if err != nil {
+		return err
+	}
+
+	// Kiểm tra quyền chỉnh sửa - ai upload thì mới được thao tác
+	if doc.UploadedBy != userID {
+		return fmt.Errorf("người dùng %s không có quyền nộp lại tài liệu %s", userID, docID)
+	}
+	if !IsDocumentRejected(doc) {
+		return fmt.Errorf("chỉ có thể nộp lại tài liệu đã bị từ chối (trạng thái: %s)", doc.Status)
+	}
+	explanation = strings.TrimSpace(explanation)
+	if explanation == "" {
+		return fmt.Errorf("phải có giải trình khi nộp lại tài liệu")
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	SetDocumentPending(doc, txTime)
+	appendDocumentReview(doc, DocumentReviewResubmit, "", explanation, userID, txTime)
+
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa tài liệu: %v", err)
+	}
+	if err := PutDocumentState(ctx, docID, docJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật tài liệu: %v", err)
+	}
+
+	return RecordAuditLog(ctx, "RESUBMIT_DOCUMENT", userID, DocumentKeyPrefix, docID,
+		fmt.Sprintf("Nộp lại tài liệu %s: %s", docID, explanation), map[string]string{"explanation": explanation})
+}
+
+// GetDocumentReviews - Truy vấn lịch sử thẩm định của tài liệu theo thứ tự thời gian
+func (s *LandRegistryChaincode) GetDocumentReviews(ctx contractapi.TransactionContextInterface, docID string) ([]DocumentReview, error) {
+	doc, err := s.GetDocument(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]DocumentReview{}, doc.Reviews...), nil
+}
+
+// GetRejectionReasonCodes - Truy vấn danh mục mã lý do từ chối tài liệu
+func (s *LandRegistryChaincode) GetRejectionReasonCodes(ctx contractapi.TransactionContextInterface) (map[string]string, error) {
+	return documentRejectionReasons, nil
+}
@@ -0,0 +1,231 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// documentVersionKey tạo composite key cho một phiên bản cũ của tài liệu
+// Số phiên bản được đệm 0 để các phiên bản được liệt kê theo thứ tự tăng dần
+func documentVersionKey(ctx contractapi.TransactionContextInterface, docID string, version int) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(DocumentVersionKeyPrefix, []string{docID, fmt.Sprintf("%06d", version)})
+	if err != nil {
+		return "", fmt.Errorf("lỗi khi tạo khóa phiên bản %d của tài liệu %s: %v", version, docID, err)
+	}
+	return key, nil
+}
+
+// currentDocumentVersion trả về số phiên bản hiện hành (tài liệu cũ chưa có trường version là phiên bản 1)
+func currentDocumentVersion(doc *Document) int {
+	if doc.Version < 1 {
+		return 1
+	}
+	return doc.Version
+}
+
+// snapshotDocumentVersion tạo bản lưu nội dung hiện hành của tài liệu
+func snapshotDocumentVersion(doc *Document) *DocumentVersion {
+	uploadedAt := doc.RevisedAt
+	if uploadedAt.IsZero() {
+		uploadedAt = doc.CreatedAt
+	}
+	return &DocumentVersion{
+		DocID:               doc.DocID,
+		Version:             currentDocumentVersion(doc),
+		IPFSHash:            doc.IPFSHash,
+		ContentSHA256:       doc.ContentSHA256,
+		FileSize:            doc.FileSize,
+		FileType:            doc.FileType,
+		UploadedBy:          doc.UploadedBy,
+		UploadedAt:          uploadedAt,
+		Note:                doc.RevisionNote,
+		Status:              doc.Status,
+		VerifiedBy:          doc.VerifiedBy,
+		VerifiedAt:          doc.VerifiedAt,
+		IntegrityVerifiedAt: doc.IntegrityVerifiedAt,
+	}
+}
+
+// putDocumentVersion lưu phiên bản cũ của tài liệu
+func putDocumentVersion(ctx contractapi.TransactionContextInterface, version *DocumentVersion) error {
+	key, err := documentVersionKey(ctx, version.DocID, version.Version)
+	if err != nil {
+		return err
+	}
+	versionJSON, err := json.Marshal(version)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa phiên bản tài liệu: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, versionJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu phiên bản %d của tài liệu %s: %v", version.Version, version.DocID, err)
+	}
+	return nil
+}
+
+// getDocumentVersions liệt kê các phiên bản cũ của tài liệu theo thứ tự tăng dần
+func getDocumentVersions(ctx contractapi.TransactionContextInterface, docID string) ([]*DocumentVersion, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(DocumentVersionKeyPrefix, []string{docID})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn phiên bản tài liệu %s: %v", docID, err)
+	}
+	defer resultsIterator.Close()
+
+	versions := []*DocumentVersion{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi đọc phiên bản tài liệu: %v", err)
+		}
+		var version DocumentVersion
+		if err := json.Unmarshal(response.Value, &version); err != nil {
+			return nil, fmt.Errorf("lỗi khi giải mã phiên bản tài liệu: %v", err)
+		}
+		versions = append(versions, &version)
+	}
+	return versions, nil
+}
+
+// deleteDocumentVersions xóa toàn bộ phiên bản cũ khi tài liệu bị xóa
+func deleteDocumentVersions(ctx contractapi.TransactionContextInterface, docID string) error {
+	versions, err := getDocumentVersions(ctx, docID)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		key, err := documentVersionKey(ctx, docID, version.Version)
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return fmt.Errorf("lỗi khi xóa phiên bản %d của tài liệu %s: %v", version.Version, docID, err)
+		}
+	}
+	return nil
+}
+
+// reviewedTxStatuses trạng thái giao dịch có hồ sơ đã qua thẩm định của Org2
+// Org1 phê duyệt dựa trên nội dung đã thẩm định nên tài liệu của các giao dịch này không được thay nội dung
+var reviewedTxStatuses = []string{TxStatusVerified, TxStatusApproved}
+
+// verifyDocumentRevisable kiểm tra tài liệu không thuộc hồ sơ giao dịch đã qua thẩm định
+func verifyDocumentRevisable(ctx contractapi.TransactionContextInterface, docID string) error {
+	queryString := fmt.Sprintf(`{"selector":{"documentIds":{"$elemMatch":{"$eq":"%s"}}}}`, docID)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return fmt.Errorf("lỗi khi kiểm tra liên kết giao dịch: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return fmt.Errorf("lỗi khi đọc liên kết giao dịch: %v", err)
+		}
+		if KeyNamespace(ctx, response.Key) != TransactionKeyPrefix {
+			continue
+		}
+		var tx Transaction
+		if err := json.Unmarshal(response.Value, &tx); err != nil {
+			return fmt.Errorf("lỗi khi giải mã giao dịch: %v", err)
+		}
+		if containsString(reviewedTxStatuses, tx.Status) {
+			return fmt.Errorf("tài liệu %s thuộc hồ sơ giao dịch %s đã được thẩm định (trạng thái: %s), không thể thay nội dung", docID, tx.TxID, tx.Status)
+		}
+	}
+	return nil
+}
+
+// ReviseDocument - Thay nội dung tài liệu bằng phiên bản mới, phiên bản cũ được lưu lại và tài liệu chờ xác thực lại
+// Không áp dụng cho tài liệu thuộc hồ sơ giao dịch đã được Org2 thẩm định (VERIFIED, APPROVED)
+func (s *LandRegistryChaincode) ReviseDocument(ctx contractapi.TransactionContextInterface, docID, newIPFSHash, contentSHA256 string, fileSize int64, fileType, note string) error {
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	doc, err := s.GetDocument(ctx, docID)
+	if err != nil {
+		return err
+	}
+
+	// Kiểm tra quyền chỉnh sửa - ai upload thì mới được thao tác
+	if doc.UploadedBy != userID {
+		return fmt.Errorf("người dùng %s không có quyền chỉnh sửa tài liệu %s", userID, docID)
+	}
+	if err := verifyDocumentRevisable(ctx, docID); err != nil {
+		return err
+	}
+	if err := ValidateIPFSHash(newIPFSHash); err != nil {
+		return fmt.Errorf("hash IPFS không hợp lệ: %v", err)
+	}
+	if NormalizeIPFSHash(newIPFSHash) == NormalizeIPFSHash(doc.IPFSHash) {
+		return fmt.Errorf("nội dung mới của tài liệu %s trùng với phiên bản hiện hành", docID)
+	}
+	if err := ValidateSHA256(contentSHA256); err != nil {
+		return err
+	}
+	if fileSize <= 0 {
+		return fmt.Errorf("kích thước file phải lớn hơn 0")
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	// Lưu lại phiên bản hiện hành trước khi thay nội dung
+	previous := snapshotDocumentVersion(doc)
+	if err := putDocumentVersion(ctx, previous); err != nil {
+		return err
+	}
+
+	doc.Version = previous.Version + 1
+	doc.IPFSHash = newIPFSHash
+	doc.ContentSHA256 = NormalizeSHA256(contentSHA256)
+	doc.FileSize = fileSize
+	doc.IntegrityVerifiedAt = time.Time{}
+	if strings.TrimSpace(fileType) != "" {
+		doc.FileType = fileType
+	}
+	doc.RevisedAt = txTime
+	doc.RevisionNote = note
+	// Nội dung mới phải được Org2 xác thực lại
+	SetDocumentPending(doc, txTime)
+	appendDocumentReview(doc, DocumentReviewRevise, "", note, userID, txTime)
+
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa tài liệu: %v", err)
+	}
+	if err := PutDocumentState(ctx, docID, docJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật tài liệu: %v", err)
+	}
+
+	return RecordAuditLog(ctx, "REVISE_DOCUMENT", userID, DocumentKeyPrefix, docID,
+		fmt.Sprintf("Cập nhật nội dung tài liệu %s lên phiên bản %d", docID, doc.Version),
+		map[string]string{
+			"previousVersion":  fmt.Sprintf("%d", previous.Version),
+			"previousIpfsHash": previous.IPFSHash,
+			"previousStatus":   previous.Status,
+			"newVersion":       fmt.Sprintf("%d", doc.Version),
+			"newIpfsHash":      doc.IPFSHash,
+			"newContentSha256": doc.ContentSHA256,
+			"note":             note,
+		})
+}
+
+// GetDocumentVersions - Truy vấn toàn bộ phiên bản của tài liệu, phiên bản hiện hành nằm cuối danh sách
+func (s *LandRegistryChaincode) GetDocumentVersions(ctx contractapi.TransactionContextInterface, docID string) ([]*DocumentVersion, error) {
+	// Dùng GetDocument để áp dụng kiểm tra quyền truy cập tài liệu
+	doc, err := s.GetDocument(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := getDocumentVersions(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+	return append(versions, snapshotDocumentVersion(doc)), nil
+}
@@ -0,0 +1,294 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Mã thành phần hồ sơ (loại tài liệu chi tiết) theo Nghị định số 151/2025/NĐ-CP
+const (
+	DocSubTypeForm09DK          = "MAU_09_DK"          // Đơn đăng ký biến động Mẫu số 09/ĐK
+	DocSubTypeForm18            = "MAU_18"             // Đơn đề nghị cấp lại GCN Mẫu số 18
+	DocSubTypeForm21            = "MAU_21"             // Đơn đề nghị tách thửa, hợp thửa Mẫu số 21
+	DocSubTypeForm22            = "MAU_22"             // Bản vẽ tách thửa, hợp thửa Mẫu số 22
+	DocSubTypeTransferContract  = "HD_CHUYEN_NHUONG"   // Hợp đồng chuyển nhượng quyền sử dụng đất
+	DocSubTypeCertificate       = "GCN"                // Giấy chứng nhận quyền sử dụng đất
+	DocSubTypeCadastralExtract  = "TRICH_DO_DIA_CHINH" // Mảnh trích đo bản đồ địa chính
+	DocSubTypeTaxDeclaration    = "KE_KHAI_THUE"       // Bản kê khai nộp thuế
+	DocSubTypeIdentity          = "GIAY_TO_TUY_THAN"   // Giấy tờ tùy thân (CCCD)
+	DocSubTypeDeathCertificate  = "TRICH_LUC_KHAI_TU"  // Trích lục khai tử của người để lại di sản
+	DocSubTypeInheritanceDeed   = "VAN_BAN_THUA_KE"    // Di chúc hoặc văn bản thỏa thuận phân chia, khai nhận di sản
+	DocSubTypeGiftContract      = "HD_TANG_CHO"        // Hợp đồng tặng cho quyền sử dụng đất
+	DocSubTypeRelationshipProof = "GIAY_TO_QUAN_HE"    // Giấy tờ chứng minh quan hệ giữa bên tặng cho và bên nhận
+	DocSubTypeOther             = "KHAC"               // Tài liệu khác
+)
+
+// DocumentSubTypeInfo mô tả một mã thành phần hồ sơ trong danh mục
+type DocumentSubTypeInfo struct {
+	Code    string `json:"code"`    // Mã thành phần hồ sơ
+	Name    string `json:"name"`    // Tên đầy đủ theo quy định
+	DocType string `json:"docType"` // Loại tài liệu tương ứng (FORM, CONTRACT, ...)
+}
+
+// documentSubTypes danh mục thành phần hồ sơ, mỗi mã thuộc đúng một loại tài liệu
+var documentSubTypes = []DocumentSubTypeInfo{
+	{Code: DocSubTypeForm09DK, Name: "Đơn đăng ký biến động đất đai, tài sản gắn liền với đất theo Mẫu số 09/ĐK", DocType: "FORM"},
+	{Code: DocSubTypeForm18, Name: "Đơn đăng ký biến động đất đai, tài sản gắn liền với đất theo Mẫu số 18 ban hành kèm theo Nghị định số 151/2025/NĐ-CP", DocType: "FORM"},
+	{Code: DocSubTypeForm21, Name: "Đơn đề nghị tách thửa đất, hợp thửa đất theo Mẫu số 21 ban hành kèm theo Nghị định số 151/2025/NĐ-CP", DocType: "FORM"},
+	{Code: DocSubTypeForm22, Name: "Bản vẽ tách thửa đất, hợp thửa đất lập theo Mẫu số 22 ban hành kèm theo Nghị định số 151/2025/NĐ-CP", DocType: "TECHNICAL_DOC"},
+	{Code: DocSubTypeTransferContract, Name: "Hợp đồng chuyển nhượng quyền sử dụng đất", DocType: "CONTRACT"},
+	{Code: DocSubTypeCertificate, Name: "Giấy chứng nhận quyền sử dụng đất", DocType: "CERTIFICATE"},
+	{Code: DocSubTypeCadastralExtract, Name: "Mảnh trích đo bản đồ địa chính thửa đất", DocType: "MAP"},
+	{Code: DocSubTypeTaxDeclaration, Name: "Bản kê khai nộp thuế", DocType: "TAX_DOCUMENT"},
+	{Code: DocSubTypeIdentity, Name: "Giấy tờ tùy thân", DocType: "LEGAL_DOC"},
+	{Code: DocSubTypeDeathCertificate, Name: "Trích lục khai tử hoặc giấy chứng tử của người để lại di sản", DocType: "LEGAL_DOC"},
+	{Code: DocSubTypeInheritanceDeed, Name: "Di chúc hoặc văn bản thỏa thuận phân chia di sản, văn bản khai nhận di sản thừa kế", DocType: "LEGAL_DOC"},
+	{Code: DocSubTypeGiftContract, Name: "Hợp đồng tặng cho quyền sử dụng đất", DocType: "CONTRACT"},
+	{Code: DocSubTypeRelationshipProof, Name: "Giấy tờ chứng minh quan hệ nhân thân giữa bên tặng cho và bên nhận (giấy khai sinh, đăng ký kết hôn, ...)", DocType: "LEGAL_DOC"},
+	{Code: DocSubTypeOther, Name: "Tài liệu khác", DocType: "OTHER"},
+}
+
+// defaultDossierPolicies thành phần hồ sơ bắt buộc mặc định theo loại giao dịch
+var defaultDossierPolicies = map[string][]string{
+	"TRANSFER":       {DocSubTypeForm09DK, DocSubTypeTransferContract, DocSubTypeCertificate, DocSubTypeCadastralExtract, DocSubTypeTaxDeclaration},
+	"SPLIT":          {DocSubTypeForm21, DocSubTypeCertificate, DocSubTypeForm22},
+	"MERGE":          {DocSubTypeForm21, DocSubTypeCertificate, DocSubTypeForm22},
+	"CHANGE_PURPOSE": {DocSubTypeForm09DK, DocSubTypeCertificate},
+	"REISSUE":        {DocSubTypeForm18, DocSubTypeCertificate, DocSubTypeCadastralExtract},
+	"INHERITANCE":    {DocSubTypeForm09DK, DocSubTypeCertificate, DocSubTypeDeathCertificate, DocSubTypeInheritanceDeed},
+	"GIFT":           {DocSubTypeForm09DK, DocSubTypeGiftContract, DocSubTypeCertificate, DocSubTypeTaxDeclaration},
+}
+
+// DossierPolicy thành phần hồ sơ bắt buộc của một loại giao dịch, lưu trên sổ cái
+type DossierPolicy struct {
+	TxType           string    `json:"txType"`           // Loại giao dịch
+	RequiredSubTypes []string  `json:"requiredSubTypes"` // Các mã thành phần hồ sơ bắt buộc
+	UpdatedBy        string    `json:"updatedBy"`        // CCCD người cập nhật gần nhất
+	UpdatedAt        time.Time `json:"updatedAt"`        // Thời gian cập nhật gần nhất
+}
+
+// getDocumentSubType tra cứu mã thành phần hồ sơ trong danh mục
+func getDocumentSubType(code string) (DocumentSubTypeInfo, bool) {
+	for _, info := range documentSubTypes {
+		if info.Code == code {
+			return info, true
+		}
+	}
+	return DocumentSubTypeInfo{}, false
+}
+
+// ValidateDocumentSubType kiểm tra mã thành phần hồ sơ có trong danh mục và khớp với loại tài liệu
+func ValidateDocumentSubType(docType, subType string) error {
+	info, ok := getDocumentSubType(subType)
+	if !ok {
+		return fmt.Errorf("mã thành phần hồ sơ %s không hợp lệ", subType)
+	}
+	if info.DocType != docType {
+		return fmt.Errorf("mã thành phần hồ sơ %s thuộc loại tài liệu %s, không phải %s", subType, info.DocType, docType)
+	}
+	return nil
+}
+
+// describeSubType trả về mô tả dễ đọc của mã thành phần hồ sơ
+func describeSubType(code string) string {
+	if info, ok := getDocumentSubType(code); ok {
+		return fmt.Sprintf("%s (%s)", code, info.Name)
+	}
+	return code
+}
+
+// GetDossierPolicy đọc thành phần hồ sơ bắt buộc của loại giao dịch, dùng mặc định nếu chưa cấu hình
+func GetDossierPolicy(ctx contractapi.TransactionContextInterface, txType string) (*DossierPolicy, error) {
+	defaults, exists := defaultDossierPolicies[txType]
+	if !exists {
+		return nil, fmt.Errorf("loại giao dịch %s không hợp lệ", txType)
+	}
+	key, err := createEntityKey(ctx, DossierPolicyKeyPrefix, txType)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi đọc chính sách hồ sơ %s: %v", txType, err)
+	}
+	policy := &DossierPolicy{TxType: txType, RequiredSubTypes: append([]string{}, defaults...)}
+	if data == nil {
+		return policy, nil
+	}
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã chính sách hồ sơ %s: %v", txType, err)
+	}
+	if policy.RequiredSubTypes == nil {
+		policy.RequiredSubTypes = []string{}
+	}
+	return policy, nil
+}
+
+// CheckRequiredDocuments kiểm tra thành phần hồ sơ bắt buộc của giao dịch, trả về các mục còn thiếu
+// Tài liệu bị từ chối không được tính là đã nộp
+// Giao dịch tặng cho đề nghị miễn thuế phải có thêm giấy tờ chứng minh quan hệ
+func CheckRequiredDocuments(ctx contractapi.TransactionContextInterface, tx *Transaction) ([]string, error) {
+	policy, err := GetDossierPolicy(ctx, tx.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	submitted := map[string]bool{}
+	for _, docID := range tx.DocumentIDs {
+		doc, err := GetDocument(ctx, docID)
+		if err != nil {
+			continue
+		}
+		if doc.SubType != "" && !IsDocumentRejected(doc) {
+			submitted[doc.SubType] = true
+		}
+	}
+
+	required := policy.RequiredSubTypes
+	if isTaxExemptGift(tx) && !containsString(required, DocSubTypeRelationshipProof) {
+		required = append(append([]string{}, required...), DocSubTypeRelationshipProof)
+	}
+	// Hợp thửa kèm chuyển mục đích sử dụng cần thêm đơn đăng ký biến động như yêu cầu thay đổi mục đích
+	if isMergeWithPurposeChange(tx) && !containsString(required, DocSubTypeForm09DK) {
+		required = append(append([]string{}, required...), DocSubTypeForm09DK)
+	}
+
+	missing := []string{}
+	for _, subType := range required {
+		if !submitted[subType] {
+			missing = append(missing, describeSubType(subType))
+		}
+	}
+	return missing, nil
+}
+
+// ========================================
+// DOSSIER POLICY FUNCTIONS
+// ========================================
+
+// SetDossierPolicy - Cấu hình thành phần hồ sơ bắt buộc cho loại giao dịch (chỉ Org1)
+func (s *LandRegistryChaincode) SetDossierPolicy(ctx contractapi.TransactionContextInterface, txType, requiredSubTypesStr string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	policy, err := GetDossierPolicy(ctx, txType)
+	if err != nil {
+		return err
+	}
+	var subTypes []string
+	if err := json.Unmarshal([]byte(requiredSubTypesStr), &subTypes); err != nil {
+		return fmt.Errorf("lỗi khi giải mã danh sách thành phần hồ sơ: %v", err)
+	}
+	required := []string{}
+	for _, subType := range subTypes {
+		subType = strings.TrimSpace(subType)
+		if subType == "" || containsString(required, subType) {
+			continue
+		}
+		if _, ok := getDocumentSubType(subType); !ok {
+			return fmt.Errorf("mã thành phần hồ sơ %s không hợp lệ", subType)
+		}
+		required = append(required, subType)
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	policy.RequiredSubTypes = required
+	policy.UpdatedBy = userID
+	policy.UpdatedAt = txTime
+	key, err := createEntityKey(ctx, DossierPolicyKeyPrefix, txType)
+	if err != nil {
+		return err
+	}
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa chính sách hồ sơ: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, policyJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu chính sách hồ sơ %s: %v", txType, err)
+	}
+	return RecordAuditLog(ctx, "SET_DOSSIER_POLICY", userID, DossierPolicyKeyPrefix, txType,
+		fmt.Sprintf("Cấu hình thành phần hồ sơ cho giao dịch %s", txType), map[string]string{"requiredSubTypes": strings.Join(required, ",")})
+}
+
+// SetDocumentSubType - Phân loại thành phần hồ sơ cho tài liệu (người tải lên hoặc Org2)
+// Dùng để bổ sung mã cho tài liệu tạo trước khi có danh mục thành phần hồ sơ; người tải lên chỉ được đổi khi tài liệu chưa được xác thực
+func (s *LandRegistryChaincode) SetDocumentSubType(ctx contractapi.TransactionContextInterface, docID, subType string) error {
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return err
+	}
+	doc, err := s.GetDocument(ctx, docID)
+	if err != nil {
+		return err
+	}
+	if mspID != "Org2MSP" {
+		if doc.UploadedBy != userID {
+			return fmt.Errorf("người dùng %s không có quyền phân loại tài liệu %s", userID, docID)
+		}
+		if doc.Status == "VERIFIED" {
+			return fmt.Errorf("tài liệu %s đã được xác thực, chỉ Org2MSP được thay đổi thành phần hồ sơ", docID)
+		}
+	}
+	subType = strings.TrimSpace(subType)
+	if err := ValidateDocumentSubType(doc.Type, subType); err != nil {
+		return err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	previous := doc.SubType
+	doc.SubType = subType
+	doc.UpdatedAt = txTime
+	docJSON, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa tài liệu: %v", err)
+	}
+	if err := PutDocumentState(ctx, docID, docJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật tài liệu: %v", err)
+	}
+	return RecordAuditLog(ctx, "SET_DOCUMENT_SUBTYPE", userID, DocumentKeyPrefix, docID,
+		fmt.Sprintf("Phân loại tài liệu %s: %s", docID, subType), map[string]string{"previousSubType": previous, "subType": subType})
+}
+
+// GetDossierPolicies - Truy vấn thành phần hồ sơ bắt buộc của tất cả loại giao dịch
+func (s *LandRegistryChaincode) GetDossierPolicies(ctx contractapi.TransactionContextInterface) ([]*DossierPolicy, error) {
+	policies := []*DossierPolicy{}
+	for _, txType := range []string{"TRANSFER", "SPLIT", "MERGE", "CHANGE_PURPOSE", "REISSUE", "INHERITANCE", "GIFT"} {
+		policy, err := GetDossierPolicy(ctx, txType)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// GetDocumentSubTypes - Truy vấn danh mục thành phần hồ sơ
+func (s *LandRegistryChaincode) GetDocumentSubTypes(ctx contractapi.TransactionContextInterface) ([]DocumentSubTypeInfo, error) {
+	return append([]DocumentSubTypeInfo{}, documentSubTypes...), nil
+}
+
+// QueryMissingDocuments - Truy vấn các thành phần hồ sơ bắt buộc còn thiếu của giao dịch
+func (s *LandRegistryChaincode) QueryMissingDocuments(ctx contractapi.TransactionContextInterface, txID string) ([]string, error) {
+	tx, err := s.QueryTransactionByID(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	return CheckRequiredDocuments(ctx, tx)
+}
@@ -0,0 +1,304 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GeoJSONGeometry hình học GeoJSON của thửa đất (Polygon hoặc MultiPolygon, tọa độ phẳng VN-2000 đơn vị mét)
+type GeoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ParcelGeometry kết quả phân tích hình học thửa đất
+type ParcelGeometry struct {
+	Type     string          `json:"type"`     // Polygon hoặc MultiPolygon
+	Polygons [][][][]float64 `json:"polygons"` // Các polygon, mỗi polygon gồm vòng ngoài và các lỗ
+	Area     float64         `json:"area"`     // Diện tích phẳng (m²)
+	BBox     *BoundingBox    `json:"bbox"`     // Khung bao của các vòng ngoài
+	Hash     string          `json:"hash"`     // SHA-256 của hình học dạng chuẩn (không phải của tệp sau GeometryCID)
+}
+
+// GeometryVerification kết quả đối chiếu hình học với dữ liệu thửa đất trên sổ cái
+type GeometryVerification struct {
+	LandParcelID  string  `json:"landParcelId"`  // Mã thửa đất
+	GeometryCID   string  `json:"geometryCid"`   // IPFS CID đang lưu
+	StoredHash    string  `json:"storedHash"`    // SHA-256 đang lưu (trống nếu chưa có)
+	ComputedHash  string  `json:"computedHash"`  // SHA-256 của hình học được đối chiếu
+	HashMatches   bool    `json:"hashMatches"`   // Hình học trùng khớp với hình học đã đăng ký
+	DeclaredArea  float64 `json:"declaredArea"`  // Diện tích trên sổ cái (m²)
+	ComputedArea  float64 `json:"computedArea"`  // Diện tích tính từ hình học (m²)
+	AreaTolerance float64 `json:"areaTolerance"` // Sai số cho phép (m²)
+	AreaMatches   bool    `json:"areaMatches"`   // Diện tích nằm trong sai số cho phép
+	AreaOverride  string  `json:"areaOverride"`  // Lý do chấp nhận chênh lệch diện tích (trống nếu không có)
+}
+
+// parcelInput một thửa đất mới trong dữ liệu tách thửa, chỉ gồm các trường được nhận từ đầu vào
+// Chủ sử dụng, đồng sở hữu, mục đích sử dụng, hợp đồng thuê, giấy chứng nhận lấy từ thửa đất gốc hoặc để trống
+type parcelInput struct {
+	ID           string          `json:"id"`                 // Mã thửa đất mới
+	Area         float64         `json:"area"`               // Diện tích (m²)
+	GeometryCID  string          `json:"geometryCid"`        // IPFS CID của geometry data
+	DocumentIDs  []string        `json:"documentIds"`        // Tài liệu liên quan
+	Geometry     json.RawMessage `json:"geometry,omitempty"` // Hình học GeoJSON (không bắt buộc) để kiểm tra diện tích
+	GeometryHash string          `json:"-"`                  // SHA-256 tính từ Geometry khi thẩm định
+	BBox         *BoundingBox    `json:"-"`                  // Khung bao tính từ Geometry khi thẩm định
+}
+
+// ParseParcelGeometry phân tích hình học GeoJSON, chấp nhận geometry, Feature hoặc tệp {landId, geometry}
+// landID khác rỗng thì landId trong tệp (nếu có) phải trùng khớp
+// Hash là SHA-256 của dạng chuẩn {"type","coordinates"} được mã hóa lại, không phải của các byte lưu sau GeometryCID:
+// cùng một hình học cho cùng hash dù tệp khác khoảng trắng hoặc vỏ bọc, nên đối chiếu nội dung CID bằng VerifyLandGeometry
+// thay vì so sánh hash tệp
+func ParseParcelGeometry(data []byte, landID string) (*ParcelGeometry, error) {
+	var wrapper struct {
+		Type     string           `json:"type"`
+		LandID   string           `json:"landId"`
+		Geometry *GeoJSONGeometry `json:"geometry"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã hình học GeoJSON: %v", err)
+	}
+	var geometry GeoJSONGeometry
+	if wrapper.Geometry != nil {
+		if landID != "" && wrapper.LandID != "" && wrapper.LandID != landID {
+			return nil, fmt.Errorf("hình học thuộc thửa đất %s, không phải %s", wrapper.LandID, landID)
+		}
+		geometry = *wrapper.Geometry
+	} else if err := json.Unmarshal(data, &geometry); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã hình học GeoJSON: %v", err)
+	}
+
+	parsed := &ParcelGeometry{Type: geometry.Type}
+	var canonical interface{}
+	switch geometry.Type {
+	case "Polygon":
+		var polygon [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &polygon); err != nil {
+			return nil, fmt.Errorf("tọa độ Polygon không hợp lệ: %v", err)
+		}
+		parsed.Polygons = [][][][]float64{polygon}
+		canonical = polygon
+	case "MultiPolygon":
+		if err := json.Unmarshal(geometry.Coordinates, &parsed.Polygons); err != nil {
+			return nil, fmt.Errorf("tọa độ MultiPolygon không hợp lệ: %v", err)
+		}
+		canonical = parsed.Polygons
+	default:
+		return nil, fmt.Errorf("loại hình học %s không được hỗ trợ (chỉ Polygon, MultiPolygon)", geometry.Type)
+	}
+	if len(parsed.Polygons) == 0 {
+		return nil, fmt.Errorf("hình học không có polygon nào")
+	}
+
+	for i, polygon := range parsed.Polygons {
+		area, err := polygonArea(polygon)
+		if err != nil {
+			return nil, fmt.Errorf("polygon thứ %d không hợp lệ: %v", i+1, err)
+		}
+		parsed.Area += area
+		// Các lỗ nằm trong vòng ngoài nên khung bao chỉ cần tính theo vòng ngoài
+		for _, point := range polygon[0] {
+			if parsed.BBox == nil {
+				parsed.BBox = &BoundingBox{MinX: point[0], MinY: point[1], MaxX: point[0], MaxY: point[1]}
+				continue
+			}
+			parsed.BBox.MinX = math.Min(parsed.BBox.MinX, point[0])
+			parsed.BBox.MinY = math.Min(parsed.BBox.MinY, point[1])
+			parsed.BBox.MaxX = math.Max(parsed.BBox.MaxX, point[0])
+			parsed.BBox.MaxY = math.Max(parsed.BBox.MaxY, point[1])
+		}
+	}
+
+	// Dạng chuẩn {"type","coordinates"} thu gọn, độc lập với định dạng và vỏ bọc của tệp gốc
+	canonicalJSON, err := json.Marshal(struct {
+		Type        string      `json:"type"`
+		Coordinates interface{} `json:"coordinates"`
+	}{Type: geometry.Type, Coordinates: canonical})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi mã hóa hình học: %v", err)
+	}
+	hash := sha256.Sum256(canonicalJSON)
+	parsed.Hash = hex.EncodeToString(hash[:])
+	return parsed, nil
+}
+
+// polygonArea diện tích polygon = vòng ngoài trừ các lỗ
+func polygonArea(polygon [][][]float64) (float64, error) {
+	if len(polygon) == 0 {
+		return 0, fmt.Errorf("polygon không có vòng ngoài")
+	}
+	area := 0.0
+	for i, ring := range polygon {
+		ringArea, err := ringArea(ring)
+		if err != nil {
+			return 0, fmt.Errorf("vòng %d: %v", i+1, err)
+		}
+		if i == 0 {
+			area = ringArea
+		} else {
+			area -= ringArea
+		}
+	}
+	if area <= 0 {
+		return 0, fmt.Errorf("diện tích polygon phải lớn hơn 0")
+	}
+	return area, nil
+}
+
+// ringArea diện tích phẳng của một vòng khép kín theo công thức shoelace
+// Tọa độ được dời về điểm đầu để giảm sai số làm tròn với tọa độ VN-2000 lớn
+func ringArea(ring [][]float64) (float64, error) {
+	if len(ring) < 4 {
+		return 0, fmt.Errorf("vòng phải có ít nhất 4 điểm")
+	}
+	for _, point := range ring {
+		if len(point) < 2 || math.IsNaN(point[0]) || math.IsNaN(point[1]) || math.IsInf(point[0], 0) || math.IsInf(point[1], 0) {
+			return 0, fmt.Errorf("tọa độ %v không hợp lệ", point)
+		}
+	}
+	first, last := ring[0], ring[len(ring)-1]
+	if first[0] != last[0] || first[1] != last[1] {
+		return 0, fmt.Errorf("vòng chưa khép kín (điểm đầu khác điểm cuối)")
+	}
+	sum := 0.0
+	for i := 0; i < len(ring)-1; i++ {
+		x1, y1 := ring[i][0]-first[0], ring[i][1]-first[1]
+		x2, y2 := ring[i+1][0]-first[0], ring[i+1][1]-first[1]
+		sum += x1*y2 - x2*y1
+	}
+	return math.Abs(sum) / 2, nil
+}
+
+// geometryAreaTolerance sai số cho phép giữa diện tích kê khai và diện tích tính từ hình học
+// Lấy giá trị lớn hơn giữa sai số tuyệt đối (AreaTolerance) và sai số tương đối (GeometryAreaTolerancePct)
+func geometryAreaTolerance(config *ChaincodeConfig, declaredArea float64) float64 {
+	return math.Max(config.AreaTolerance, math.Abs(declaredArea)*config.GeometryAreaTolerancePct/100)
+}
+
+// checkDeclaredArea phân tích hình học và kiểm tra diện tích kê khai nằm trong sai số cho phép
+// Hình học trả về cung cấp SHA-256 và khung bao để lưu cùng thửa đất
+func checkDeclaredArea(ctx contractapi.TransactionContextInterface, data []byte, landID string, declaredArea float64) (*ParcelGeometry, error) {
+	geometry, err := ParseParcelGeometry(data, landID)
+	if err != nil {
+		return nil, err
+	}
+	config, err := GetChaincodeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tolerance := geometryAreaTolerance(config, declaredArea)
+	if math.Abs(geometry.Area-declaredArea) > tolerance {
+		return nil, fmt.Errorf("diện tích kê khai (%.2f m²) chênh lệch với diện tích tính từ hình học (%.2f m²) vượt quá sai số cho phép (%.2f m²)",
+			declaredArea, geometry.Area, tolerance)
+	}
+	return geometry, nil
+}
+
+// VerifyLandGeometry - Đối chiếu hình học (ví dụ nội dung tải từ GeometryCID) với SHA-256 và diện tích đã đăng ký của thửa đất
+func (s *LandRegistryChaincode) VerifyLandGeometry(ctx contractapi.TransactionContextInterface, landID, geometry string) (*GeometryVerification, error) {
+	land, err := s.QueryLandByID(ctx, landID)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(geometry) == "" {
+		return nil, fmt.Errorf("hình học không được để trống")
+	}
+	parsed, err := ParseParcelGeometry([]byte(geometry), landID)
+	if err != nil {
+		return nil, err
+	}
+	config, err := GetChaincodeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tolerance := geometryAreaTolerance(config, land.Area)
+	return &GeometryVerification{
+		LandParcelID:  landID,
+		GeometryCID:   land.GeometryCID,
+		StoredHash:    land.GeometryHash,
+		ComputedHash:  parsed.Hash,
+		HashMatches:   land.GeometryHash != "" && land.GeometryHash == parsed.Hash,
+		DeclaredArea:  land.Area,
+		ComputedArea:  parsed.Area,
+		AreaTolerance: tolerance,
+		AreaMatches:   math.Abs(parsed.Area-land.Area) <= tolerance,
+		AreaOverride:  land.AreaOverride,
+	}, nil
+}
+
+// RegisterLandGeometry - Org1 đăng ký hình học (SHA-256, khung bao) cho thửa đất đã có, ví dụ thửa đất nạp từ dữ liệu cũ chỉ có GeometryCID
+// Diện tích kê khai phải khớp với hình học; areaOverrideReason khác rỗng cho phép đăng ký dù chênh lệch,
+// lý do được lưu trên thửa đất (AreaOverride) để đo đạc lại. Muốn sửa diện tích thì dùng UpdateLandParcel
+func (s *LandRegistryChaincode) RegisterLandGeometry(ctx contractapi.TransactionContextInterface, id, geometry, areaOverrideReason string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	land, err := s.QueryLandByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	// Không thay đổi hình học của thửa đất đang có giao dịch xử lý
+	if err := VerifyParcelNotLocked(ctx, id); err != nil {
+		return err
+	}
+	if strings.TrimSpace(geometry) == "" {
+		return fmt.Errorf("hình học không được để trống")
+	}
+	parsed, err := ParseParcelGeometry([]byte(geometry), id)
+	if err != nil {
+		return fmt.Errorf("hình học thửa đất %s không hợp lệ: %v", id, err)
+	}
+	config, err := GetChaincodeConfig(ctx)
+	if err != nil {
+		return err
+	}
+	tolerance := geometryAreaTolerance(config, land.Area)
+	reason := strings.TrimSpace(areaOverrideReason)
+	mismatch := math.Abs(parsed.Area-land.Area) > tolerance
+	if mismatch && reason == "" {
+		return fmt.Errorf("diện tích kê khai (%.2f m²) chênh lệch với diện tích tính từ hình học (%.2f m²) vượt quá sai số cho phép (%.2f m²), cần sửa diện tích hoặc ghi lý do chấp nhận chênh lệch",
+			land.Area, parsed.Area, tolerance)
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	oldBBox := land.BBox
+	land.GeometryHash = parsed.Hash
+	land.BBox = parsed.BBox
+	land.AreaOverride = ""
+	if mismatch {
+		land.AreaOverride = reason
+	}
+	land.UpdatedAt = txTime
+	landJSON, err := json.Marshal(land)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
+	}
+	if err := PutLandState(ctx, id, landJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
+	}
+	if err := updateLandSpatialIndex(ctx, id, oldBBox, land.BBox); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "REGISTER_LAND_GEOMETRY", userID, LandKeyPrefix, id, fmt.Sprintf("Đăng ký hình học thửa đất %s", id),
+		map[string]string{
+			"geometryHash": parsed.Hash,
+			"declaredArea": fmt.Sprintf("%.2f", land.Area),
+			"computedArea": fmt.Sprintf("%.2f", parsed.Area),
+			"areaOverride": land.AreaOverride,
+		})
+}
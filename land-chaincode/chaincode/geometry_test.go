@@ -0,0 +1,187 @@
+package chaincode
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+const squareGeoJSON = `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]]]}`
+
+func TestParseParcelGeometryArea(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		area float64
+		bbox BoundingBox
+	}{
+		{
+			name: "square",
+			data: squareGeoJSON,
+			area: 100,
+			bbox: BoundingBox{MinX: 0, MinY: 0, MaxX: 10, MaxY: 10},
+		},
+		{
+			name: "clockwise ring",
+			data: `{"type":"Polygon","coordinates":[[[0,0],[0,10],[10,10],[10,0],[0,0]]]}`,
+			area: 100,
+			bbox: BoundingBox{MinX: 0, MinY: 0, MaxX: 10, MaxY: 10},
+		},
+		{
+			name: "VN-2000 coordinates",
+			data: `{"type":"Polygon","coordinates":[[[569213.86,2333803.11],[569233.86,2333803.11],[569233.86,2333813.11],[569213.86,2333813.11],[569213.86,2333803.11]]]}`,
+			area: 200,
+			bbox: BoundingBox{MinX: 569213.86, MinY: 2333803.11, MaxX: 569233.86, MaxY: 2333813.11},
+		},
+		{
+			name: "triangle",
+			data: `{"type":"Polygon","coordinates":[[[0,0],[8,0],[0,6],[0,0]]]}`,
+			area: 24,
+			bbox: BoundingBox{MinX: 0, MinY: 0, MaxX: 8, MaxY: 6},
+		},
+		{
+			name: "polygon with hole",
+			data: `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[2,2],[4,2],[4,4],[2,4],[2,2]]]}`,
+			area: 96,
+			bbox: BoundingBox{MinX: 0, MinY: 0, MaxX: 10, MaxY: 10},
+		},
+		{
+			name: "multipolygon with hole",
+			data: `{"type":"MultiPolygon","coordinates":[[[[0,0],[10,0],[10,10],[0,10],[0,0]],[[2,2],[4,2],[4,4],[2,4],[2,2]]],[[[20,0],[25,0],[25,4],[20,4],[20,0]]]]}`,
+			area: 116,
+			bbox: BoundingBox{MinX: 0, MinY: 0, MaxX: 25, MaxY: 10},
+		},
+		{
+			name: "feature wrapper",
+			data: `{"type":"Feature","properties":{},"geometry":` + squareGeoJSON + `}`,
+			area: 100,
+			bbox: BoundingBox{MinX: 0, MinY: 0, MaxX: 10, MaxY: 10},
+		},
+		{
+			name: "parcel file wrapper",
+			data: `{"landId":"13-1","geometry":` + squareGeoJSON + `}`,
+			area: 100,
+			bbox: BoundingBox{MinX: 0, MinY: 0, MaxX: 10, MaxY: 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geometry, err := ParseParcelGeometry([]byte(tt.data), "13-1")
+			if err != nil {
+				t.Fatalf("ParseParcelGeometry: %v", err)
+			}
+			if math.Abs(geometry.Area-tt.area) > 1e-6 {
+				t.Errorf("area = %v, want %v", geometry.Area, tt.area)
+			}
+			if *geometry.BBox != tt.bbox {
+				t.Errorf("bbox = %+v, want %+v", *geometry.BBox, tt.bbox)
+			}
+		})
+	}
+}
+
+func TestParseParcelGeometryFixture(t *testing.T) {
+	data, err := os.ReadFile("../../individual_geometries/dc13/geometry_13-284.json")
+	if err != nil {
+		t.Skipf("fixture not available: %v", err)
+	}
+	geometry, err := ParseParcelGeometry(data, "13-284")
+	if err != nil {
+		t.Fatalf("ParseParcelGeometry: %v", err)
+	}
+	if geometry.Type != "MultiPolygon" || len(geometry.Polygons) != 2 {
+		t.Fatalf("got %s with %d polygons, want MultiPolygon with 2", geometry.Type, len(geometry.Polygons))
+	}
+	// Diện tích kê khai trong land_data.json là 140 m² nên thửa này được gắn areaOverride chờ đo đạc lại
+	if math.Abs(geometry.Area-31107.27) > 0.01 {
+		t.Errorf("area = %.2f, want 31107.27", geometry.Area)
+	}
+	if _, err := ParseParcelGeometry(data, "13-285"); err == nil {
+		t.Error("expected error for geometry of another parcel")
+	}
+}
+
+func TestParseParcelGeometryInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"not json", `{`, "giải mã"},
+		{"unsupported type", `{"type":"Point","coordinates":[1,2]}`, "không được hỗ trợ"},
+		{"empty multipolygon", `{"type":"MultiPolygon","coordinates":[]}`, "không có polygon"},
+		{"empty polygon", `{"type":"Polygon","coordinates":[]}`, "không có vòng ngoài"},
+		{"too few points", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[0,0]]]}`, "ít nhất 4 điểm"},
+		{"open ring", `{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10],[0,10]]]}`, "chưa khép kín"},
+		{"missing coordinate", `{"type":"Polygon","coordinates":[[[0,0],[10],[10,10],[0,10],[0,0]]]}`, "không hợp lệ"},
+		{"degenerate ring", `{"type":"Polygon","coordinates":[[[0,0],[5,0],[10,0],[0,0]]]}`, "lớn hơn 0"},
+		{"hole larger than shell", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]],[[0,0],[5,0],[5,5],[0,5],[0,0]]]}`, "lớn hơn 0"},
+		{"other parcel", `{"landId":"13-2","geometry":` + squareGeoJSON + `}`, "13-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseParcelGeometry([]byte(tt.data), "13-1")
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseParcelGeometryHash(t *testing.T) {
+	// SHA-256 của dạng chuẩn thu gọn {"type","coordinates"}
+	const squareHash = "17154619b0d30ad909313dc0d8568717f747d8f1c079d99ffeaa5593fa666d7e"
+	equivalent := []string{
+		squareGeoJSON,
+		"{\n  \"coordinates\": [[[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]]],\n  \"type\": \"Polygon\"\n}",
+		`{"type":"Feature","properties":{"name":"x"},"geometry":` + squareGeoJSON + `}`,
+		`{"landId":"13-1","geometry":` + squareGeoJSON + `}`,
+		`{"type":"Polygon","coordinates":[[[0.0,0],[1e1,0],[10,10.00],[0,10],[0,0]]]}`,
+	}
+	for _, data := range equivalent {
+		geometry, err := ParseParcelGeometry([]byte(data), "13-1")
+		if err != nil {
+			t.Fatalf("ParseParcelGeometry(%s): %v", data, err)
+		}
+		if geometry.Hash != squareHash {
+			t.Errorf("hash of %s = %s, want %s", data, geometry.Hash, squareHash)
+		}
+	}
+
+	different := []string{
+		`{"type":"Polygon","coordinates":[[[0,0],[10,0],[10,10.01],[0,10],[0,0]]]}`,
+		`{"type":"Polygon","coordinates":[[[10,0],[10,10],[0,10],[0,0],[10,0]]]}`,
+		`{"type":"MultiPolygon","coordinates":[[[[0,0],[10,0],[10,10],[0,10],[0,0]]]]}`,
+	}
+	for _, data := range different {
+		geometry, err := ParseParcelGeometry([]byte(data), "13-1")
+		if err != nil {
+			t.Fatalf("ParseParcelGeometry(%s): %v", data, err)
+		}
+		if geometry.Hash == squareHash {
+			t.Errorf("hash of %s must differ from the square", data)
+		}
+	}
+}
+
+func TestGeometryAreaTolerance(t *testing.T) {
+	config := &ChaincodeConfig{AreaTolerance: 0.5, GeometryAreaTolerancePct: 1}
+	tests := []struct {
+		declared float64
+		want     float64
+	}{
+		{declared: 10, want: 0.5},
+		{declared: 50, want: 0.5},
+		{declared: 140, want: 1.4},
+		{declared: 1000, want: 10},
+	}
+	for _, tt := range tests {
+		if got := geometryAreaTolerance(config, tt.declared); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("geometryAreaTolerance(%v) = %v, want %v", tt.declared, got, tt.want)
+		}
+	}
+}
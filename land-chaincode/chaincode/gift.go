@@ -0,0 +1,267 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// GiftRelationshipInfo mô tả một quan hệ giữa bên tặng cho và bên nhận trong danh mục
+type GiftRelationshipInfo struct {
+	Code          string `json:"code"`          // Mã quan hệ
+	Name          string `json:"name"`          // Tên quan hệ
+	TaxExemptible bool   `json:"taxExemptible"` // Thuộc diện được miễn thuế thu nhập cá nhân, lệ phí trước bạ
+}
+
+// giftRelationships danh mục quan hệ tặng cho theo Luật Thuế thu nhập cá nhân
+// (tặng cho giữa các thành viên gia đình được miễn thuế)
+var giftRelationships = []GiftRelationshipInfo{
+	{Code: "SPOUSE", Name: "Vợ với chồng", TaxExemptible: true},
+	{Code: "PARENT_CHILD", Name: "Cha đẻ, mẹ đẻ với con đẻ", TaxExemptible: true},
+	{Code: "ADOPTIVE_PARENT_CHILD", Name: "Cha nuôi, mẹ nuôi với con nuôi", TaxExemptible: true},
+	{Code: "PARENT_IN_LAW", Name: "Cha chồng, mẹ chồng với con dâu; cha vợ, mẹ vợ với con rể", TaxExemptible: true},
+	{Code: "GRANDPARENT_GRANDCHILD", Name: "Ông nội, bà nội, ông ngoại, bà ngoại với cháu nội, cháu ngoại", TaxExemptible: true},
+	{Code: "SIBLING", Name: "Anh chị em ruột với nhau", TaxExemptible: true},
+	{Code: "OTHER", Name: "Quan hệ khác", TaxExemptible: false},
+}
+
+// getGiftRelationship tra cứu mã quan hệ trong danh mục
+func getGiftRelationship(code string) (GiftRelationshipInfo, bool) {
+	for _, info := range giftRelationships {
+		if info.Code == code {
+			return info, true
+		}
+	}
+	return GiftRelationshipInfo{}, false
+}
+
+// ValidateGiftRelationship kiểm tra mã quan hệ và điều kiện miễn thuế của giao dịch tặng cho
+func ValidateGiftRelationship(relationship string, taxExempt bool) error {
+	info, ok := getGiftRelationship(relationship)
+	if !ok {
+		return fmt.Errorf("quan hệ tặng cho %s không hợp lệ", relationship)
+	}
+	if taxExempt && !info.TaxExemptible {
+		return fmt.Errorf("quan hệ %s (%s) không thuộc diện được miễn thuế khi tặng cho", info.Code, info.Name)
+	}
+	return nil
+}
+
+// isTaxExemptGift kiểm tra giao dịch có phải tặng cho đề nghị miễn thuế không
+func isTaxExemptGift(tx *Transaction) bool {
+	return tx.Type == "GIFT" && tx.Payload != nil && tx.Payload.Gift != nil && tx.Payload.Gift.TaxExempt
+}
+
+// ========================================
+// GIFT FUNCTIONS
+// ========================================
+
+// CreateGiftRequest - Tạo yêu cầu tặng cho quyền sử dụng đất (auto-generate txID)
+// relationship: mã quan hệ giữa bên tặng cho và bên nhận (xem GetGiftRelationships)
+// taxExemptStr: "true" nếu đề nghị miễn thuế, chỉ áp dụng cho quan hệ thuộc diện miễn thuế
+// CCCD người nhận ("toOwnerId") được truyền qua transient map
+func (s *LandRegistryChaincode) CreateGiftRequest(ctx contractapi.TransactionContextInterface, landParcelID, relationship, taxExemptStr, documentIdsStr, reason string) error {
+	if err := CheckOrganization(ctx, []string{"Org3MSP"}); err != nil {
+		return err
+	}
+	callerID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := VerifyLandOwnership(ctx, landParcelID, callerID); err != nil {
+		return err
+	}
+	if err := VerifyLandLegalStatus(ctx, landParcelID, []string{"Đang tranh chấp", "Đang thế chấp"}); err != nil {
+		return err
+	}
+	// Thửa đất bị đóng băng khi đang có tranh chấp
+	if err := VerifyNoOpenDispute(ctx, landParcelID); err != nil {
+		return err
+	}
+	if err := VerifyNoActiveMortgage(ctx, landParcelID); err != nil {
+		return err
+	}
+
+	relationship = strings.ToUpper(strings.TrimSpace(relationship))
+	taxExempt := false
+	if taxExemptStr != "" {
+		taxExempt, err = strconv.ParseBool(taxExemptStr)
+		if err != nil {
+			return fmt.Errorf("giá trị miễn thuế không hợp lệ: %v", err)
+		}
+	}
+	if err := ValidateGiftRelationship(relationship, taxExempt); err != nil {
+		return err
+	}
+
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	toOwnerID, err := transientSubjectRef(ctx, "toOwnerId", callerID, txTime, true)
+	if err != nil {
+		return fmt.Errorf("CCCD người nhận tặng cho không hợp lệ: %v", err)
+	}
+	if toOwnerID == callerID {
+		return fmt.Errorf("không thể tặng cho thửa đất cho chính mình")
+	}
+
+	// Parse document IDs if provided
+	var documentIDs []string
+	if documentIdsStr != "" {
+		if err := json.Unmarshal([]byte(documentIdsStr), &documentIDs); err != nil {
+			return fmt.Errorf("lỗi khi giải mã danh sách document IDs: %v", err)
+		}
+	}
+
+	// Tự động tạo txID với timestamp
+	txID := fmt.Sprintf("TANG_CHO_%d_%s_%s", txTime.Unix(), shortSubjectRef(callerID), landParcelID)
+
+	// Tạo Details với quan hệ và lý do
+	info, _ := getGiftRelationship(relationship)
+	details := fmt.Sprintf("Tặng cho thửa đất %s từ %s sang %s (quan hệ: %s)", landParcelID, callerID, toOwnerID, info.Name)
+	if taxExempt {
+		details = fmt.Sprintf("%s, đề nghị miễn thuế", details)
+	}
+	if reason != "" {
+		details = fmt.Sprintf("%s. Lý do: %s", details, reason)
+	}
+
+	tx := Transaction{
+		TxID:         txID,
+		Type:         "GIFT",
+		LandParcelID: landParcelID,
+		ParcelIDs:    []string{},
+		FromOwnerID:  callerID,
+		ToOwnerID:    toOwnerID,
+		Status:       TxStatusPending,
+		Details:      details,
+		Payload:      &TransactionPayload{Gift: &GiftPayload{RecipientID: toOwnerID, Relationship: relationship, TaxExempt: taxExempt}},
+		UserID:       callerID,
+		DocumentIDs:  documentIDs,
+		CreatedAt:    txTime,
+		UpdatedAt:    txTime,
+	}
+	// Các đồng sở hữu khác phải đồng ý trước khi hồ sơ được thẩm định
+	if err := initTransactionConsents(ctx, &tx); err != nil {
+		return err
+	}
+	if err := scheduleStageDeadline(ctx, &tx); err != nil {
+		return err
+	}
+	// Khóa thửa đất để ngăn các yêu cầu xung đột cho đến khi giao dịch kết thúc
+	if err := acquireParcelLocks(ctx, &tx); err != nil {
+		return err
+	}
+	txJSON, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
+	}
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu giao dịch: %v", err)
+	}
+	auditDetails := transactionAuditDetails(&tx)
+	auditDetails["relationship"] = relationship
+	auditDetails["taxExempt"] = strconv.FormatBool(taxExempt)
+	return RecordAuditLog(ctx, "CREATE_GIFT_REQUEST", callerID, TransactionKeyPrefix, txID, fmt.Sprintf("Tạo yêu cầu tặng cho %s", txID), auditDetails)
+}
+
+// ApproveGiftTransaction - Phê duyệt giao dịch tặng cho, chuyển quyền sử dụng đất cho người nhận
+func (s *LandRegistryChaincode) ApproveGiftTransaction(ctx contractapi.TransactionContextInterface, txID string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := GetTransaction(ctx, txID)
+	if err != nil {
+		return err
+	}
+	if tx.Type != "GIFT" {
+		return fmt.Errorf("giao dịch %s không phải là tặng cho", txID)
+	}
+	transition, err := ResolveTransition(ctx, tx, ActionApprove)
+	if err != nil {
+		return err
+	}
+
+	land, err := s.QueryLandByID(ctx, tx.LandParcelID)
+	if err != nil {
+		return fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", tx.LandParcelID, err)
+	}
+	if !IsLandOwner(land, tx.FromOwnerID) {
+		return fmt.Errorf("người dùng %s không sở hữu thửa đất %s", tx.FromOwnerID, tx.LandParcelID)
+	}
+	// Thửa đất bị đóng băng khi đang có tranh chấp
+	if err := VerifyNoOpenDispute(ctx, tx.LandParcelID); err != nil {
+		return err
+	}
+	if err := VerifyNoActiveMortgage(ctx, tx.LandParcelID); err != nil {
+		return err
+	}
+	// Thửa đất đang cho thuê không được chuyển quyền cho đến khi hợp đồng thuê chấm dứt
+	if err := VerifyNoActiveLease(ctx, tx.LandParcelID); err != nil {
+		return err
+	}
+
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	// Thu hồi giấy chứng nhận cũ (do chủ sử dụng thay đổi), lịch sử GCN được giữ lại
+	if land.CertificateID != "" {
+		legalInfo := "Giấy chứng nhận đã vô hiệu do tặng cho quyền sử dụng đất"
+		if err := revokeLandCertificates(ctx, land, legalInfo, userID, txTime); err != nil {
+			return err
+		}
+		land.CertificateID = ""
+		land.IssueDate = time.Time{}
+		land.LegalInfo = legalInfo
+	}
+
+	// Cập nhật chủ sử dụng thửa đất theo người nhận trong payload
+	recipientID := tx.ToOwnerID
+	if tx.Payload != nil && tx.Payload.Gift != nil && tx.Payload.Gift.RecipientID != "" {
+		recipientID = tx.Payload.Gift.RecipientID
+	}
+	land.OwnerID = recipientID
+	// Toàn bộ thửa đất (kể cả phần của các đồng sở hữu đã đồng ý) chuyển cho người nhận
+	land.CoOwners = nil
+	land.UpdatedAt = txTime
+	landJSON, err := json.Marshal(land)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
+	}
+	if err := PutLandState(ctx, tx.LandParcelID, landJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
+	}
+
+	// Cập nhật trạng thái giao dịch
+	tx.Status = transition.To
+	if err := enterTransactionStage(ctx, tx); err != nil {
+		return err
+	}
+	tx.Details = fmt.Sprintf("%s; Đã phê duyệt tặng cho", tx.Details)
+	tx.UpdatedAt = txTime
+	txJSON, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
+	}
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
+	}
+
+	return RecordAuditLog(ctx, "APPROVE_GIFT", userID, TransactionKeyPrefix, txID, fmt.Sprintf("Phê duyệt tặng cho %s", txID), transactionAuditDetails(tx))
+}
+
+// GetGiftRelationships - Truy vấn danh mục quan hệ tặng cho và điều kiện miễn thuế
+func (s *LandRegistryChaincode) GetGiftRelationships(ctx contractapi.TransactionContextInterface) ([]GiftRelationshipInfo, error) {
+	return append([]GiftRelationshipInfo{}, giftRelationships...), nil
+}
@@ -0,0 +1,257 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// inheritanceConsentParties các người thừa kế (trừ người tạo yêu cầu) phải đồng ý với việc phân chia di sản
+func inheritanceConsentParties(tx *Transaction) []string {
+	parties := []string{}
+	if tx.Payload == nil || tx.Payload.Inheritance == nil {
+		return parties
+	}
+	for _, heir := range tx.Payload.Inheritance.Heirs {
+		if heir.OwnerID != tx.UserID && !containsString(parties, heir.OwnerID) {
+			parties = append(parties, heir.OwnerID)
+		}
+	}
+	return parties
+}
+
+// inheritedOwners tính danh sách chủ sử dụng sau thừa kế
+// Phần sở hữu của người để lại di sản được chia cho người thừa kế theo tỷ lệ, phần của các đồng sở hữu khác giữ nguyên
+func inheritedOwners(land *Land, deceasedID string, heirs []LandCoOwner) []LandCoOwner {
+	var deceasedShare float64
+	owners := []LandCoOwner{}
+	for _, owner := range landOwners(land) {
+		if owner.OwnerID == deceasedID {
+			deceasedShare = owner.Share
+			continue
+		}
+		owners = append(owners, owner)
+	}
+	for _, heir := range heirs {
+		share := heir.Share * deceasedShare
+		merged := false
+		for i := range owners {
+			if owners[i].OwnerID == heir.OwnerID {
+				owners[i].Share += share
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			owners = append(owners, LandCoOwner{OwnerID: heir.OwnerID, Share: share})
+		}
+	}
+	return owners
+}
+
+// ========================================
+// INHERITANCE FUNCTIONS
+// ========================================
+
+// CreateInheritanceRequest - Tạo yêu cầu đăng ký thừa kế quyền sử dụng đất (người thừa kế hoặc Org2)
+// CCCD người để lại di sản ("deceasedOwnerId") và danh sách người thừa kế ("heirs": [{"cccd": "...", "share": 0.5}, ...])
+// được truyền qua transient map, phần được hưởng tính trên phần sở hữu của người để lại di sản
+func (s *LandRegistryChaincode) CreateInheritanceRequest(ctx contractapi.TransactionContextInterface, landParcelID, documentIdsStr, reason string) error {
+	if err := CheckOrganization(ctx, []string{"Org2MSP", "Org3MSP"}); err != nil {
+		return err
+	}
+	callerID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	deceasedID, err := transientSubjectRef(ctx, "deceasedOwnerId", callerID, txTime, true)
+	if err != nil {
+		return fmt.Errorf("CCCD người để lại di sản không hợp lệ: %v", err)
+	}
+	heirs, err := transientCoOwners(ctx, "heirs", callerID, txTime, true)
+	if err != nil {
+		return err
+	}
+	heirIDs := []string{}
+	for _, heir := range heirs {
+		if heir.OwnerID == deceasedID {
+			return fmt.Errorf("người để lại di sản không thể là người thừa kế")
+		}
+		heirIDs = append(heirIDs, heir.OwnerID)
+	}
+	// Công dân chỉ được tạo yêu cầu khi là một trong những người thừa kế
+	if mspID == "Org3MSP" && !containsString(heirIDs, callerID) {
+		return fmt.Errorf("người dùng %s không có tên trong danh sách người thừa kế", callerID)
+	}
+
+	if err := VerifyLandOwnership(ctx, landParcelID, deceasedID); err != nil {
+		return err
+	}
+	if err := VerifyLandLegalStatus(ctx, landParcelID, []string{"Đang tranh chấp", "Đang thế chấp"}); err != nil {
+		return err
+	}
+	// Thửa đất bị đóng băng khi đang có tranh chấp
+	if err := VerifyNoOpenDispute(ctx, landParcelID); err != nil {
+		return err
+	}
+	if err := VerifyNoActiveMortgage(ctx, landParcelID); err != nil {
+		return err
+	}
+
+	// Parse document IDs if provided
+	var documentIDs []string
+	if documentIdsStr != "" {
+		if err := json.Unmarshal([]byte(documentIdsStr), &documentIDs); err != nil {
+			return fmt.Errorf("lỗi khi giải mã danh sách document IDs: %v", err)
+		}
+	}
+
+	// Người nhận đại diện là người thừa kế tạo yêu cầu, hoặc người thừa kế đầu tiên khi Org2 tạo
+	representativeID := heirs[0].OwnerID
+	if mspID == "Org3MSP" {
+		representativeID = callerID
+	}
+
+	// Tự động tạo txID với timestamp
+	txID := fmt.Sprintf("THUA_KE_%d_%s_%s", txTime.Unix(), shortSubjectRef(callerID), landParcelID)
+	details := fmt.Sprintf("Đăng ký thừa kế thửa đất %s của %s cho %d người thừa kế", landParcelID, deceasedID, len(heirs))
+	if reason != "" {
+		details = fmt.Sprintf("%s. Lý do: %s", details, reason)
+	}
+
+	tx := Transaction{
+		TxID:         txID,
+		Type:         "INHERITANCE",
+		LandParcelID: landParcelID,
+		ParcelIDs:    []string{},
+		FromOwnerID:  deceasedID,
+		ToOwnerID:    representativeID,
+		Status:       TxStatusPending,
+		Details:      details,
+		Payload:      &TransactionPayload{Inheritance: &InheritancePayload{DeceasedOwnerID: deceasedID, Heirs: heirs}},
+		UserID:       callerID,
+		DocumentIDs:  documentIDs,
+		CreatedAt:    txTime,
+		UpdatedAt:    txTime,
+	}
+	// Các người thừa kế khác phải đồng ý với việc phân chia trước khi hồ sơ được thẩm định
+	if err := initTransactionConsents(ctx, &tx); err != nil {
+		return err
+	}
+	if err := scheduleStageDeadline(ctx, &tx); err != nil {
+		return err
+	}
+	// Khóa thửa đất để ngăn các yêu cầu xung đột cho đến khi giao dịch kết thúc
+	if err := acquireParcelLocks(ctx, &tx); err != nil {
+		return err
+	}
+	txJSON, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
+	}
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu giao dịch: %v", err)
+	}
+	auditDetails := transactionAuditDetails(&tx)
+	auditDetails["heirs"] = strings.Join(heirIDs, ",")
+	return RecordAuditLog(ctx, "CREATE_INHERITANCE_REQUEST", callerID, TransactionKeyPrefix, txID, fmt.Sprintf("Tạo yêu cầu đăng ký thừa kế %s", txID), auditDetails)
+}
+
+// ApproveInheritanceTransaction - Phê duyệt giao dịch thừa kế, chuyển quyền sử dụng đất cho người thừa kế
+func (s *LandRegistryChaincode) ApproveInheritanceTransaction(ctx contractapi.TransactionContextInterface, txID string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := GetTransaction(ctx, txID)
+	if err != nil {
+		return err
+	}
+	if tx.Type != "INHERITANCE" {
+		return fmt.Errorf("giao dịch %s không phải là thừa kế", txID)
+	}
+	if tx.Payload == nil || tx.Payload.Inheritance == nil || len(tx.Payload.Inheritance.Heirs) == 0 {
+		return fmt.Errorf("giao dịch %s thiếu thông tin người thừa kế", txID)
+	}
+	transition, err := ResolveTransition(ctx, tx, ActionApprove)
+	if err != nil {
+		return err
+	}
+
+	inheritance := tx.Payload.Inheritance
+	land, err := s.QueryLandByID(ctx, tx.LandParcelID)
+	if err != nil {
+		return fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", tx.LandParcelID, err)
+	}
+	if !IsLandOwner(land, inheritance.DeceasedOwnerID) {
+		return fmt.Errorf("người để lại di sản %s không còn là chủ sử dụng thửa đất %s", inheritance.DeceasedOwnerID, tx.LandParcelID)
+	}
+	// Thửa đất bị đóng băng khi đang có tranh chấp
+	if err := VerifyNoOpenDispute(ctx, tx.LandParcelID); err != nil {
+		return err
+	}
+	if err := VerifyNoActiveMortgage(ctx, tx.LandParcelID); err != nil {
+		return err
+	}
+
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	// Thu hồi giấy chứng nhận cũ (do chủ sử dụng thay đổi), lịch sử GCN được giữ lại
+	if land.CertificateID != "" {
+		legalInfo := "Giấy chứng nhận đã vô hiệu do thừa kế quyền sử dụng đất"
+		if err := revokeLandCertificates(ctx, land, legalInfo, userID, txTime); err != nil {
+			return err
+		}
+		land.CertificateID = ""
+		land.IssueDate = time.Time{}
+		land.LegalInfo = legalInfo
+	}
+
+	previousOwners := landOwnerIDs(land)
+	applyLandOwners(land, inheritedOwners(land, inheritance.DeceasedOwnerID, inheritance.Heirs))
+	land.UpdatedAt = txTime
+	landJSON, err := json.Marshal(land)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
+	}
+	if err := PutLandState(ctx, tx.LandParcelID, landJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
+	}
+
+	// Cập nhật trạng thái giao dịch
+	tx.Status = transition.To
+	if err := enterTransactionStage(ctx, tx); err != nil {
+		return err
+	}
+	tx.Details = fmt.Sprintf("%s; Đã phê duyệt thừa kế", tx.Details)
+	tx.UpdatedAt = txTime
+	txJSON, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa giao dịch: %v", err)
+	}
+	if err := PutTransactionState(ctx, txID, txJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật giao dịch: %v", err)
+	}
+
+	auditDetails := transactionAuditDetails(tx)
+	auditDetails["previousOwners"] = strings.Join(previousOwners, ",")
+	auditDetails["owners"] = strings.Join(landOwnerIDs(land), ",")
+	return RecordAuditLog(ctx, "APPROVE_INHERITANCE", userID, TransactionKeyPrefix, txID, fmt.Sprintf("Phê duyệt thừa kế %s", txID), auditDetails)
+}
@@ -1,12 +1,20 @@
 package chaincode
 
 import (
+	"fmt"
+
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
-// InitRealData - Initialize empty (data will be loaded separately)
+// InitRealData - Khởi tạo danh mục mục đích sử dụng đất (dữ liệu thửa đất được nạp riêng)
 func (s *LandRegistryChaincode) InitRealData(ctx contractapi.TransactionContextInterface) error {
-	// Empty initialization - data will be loaded via LoadData functions
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	// Thửa đất được nạp qua các hàm LoadData, chỉ ghi danh mục mặc định tại đây
+	if _, err := seedLandUsePurposes(ctx, "SYSTEM", txTime); err != nil {
+		return fmt.Errorf("lỗi khi khởi tạo danh mục mục đích sử dụng: %v", err)
+	}
 	return nil
 }
-
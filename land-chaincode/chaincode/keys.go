@@ -0,0 +1,344 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Namespace (object type) của composite key cho từng loại thực thể
+const (
+	LandKeyPrefix               = "LAND"
+	DocumentKeyPrefix           = "DOC"
+	DocumentVersionKeyPrefix    = "DOC_VERSION" // Khóa gồm mã tài liệu + số phiên bản
+	TransactionKeyPrefix        = "TX"
+	CertificateKeyPrefix        = "CERT"
+	MortgageKeyPrefix           = "MORTGAGE"
+	DisputeKeyPrefix            = "DISPUTE"
+	LeaseKeyPrefix              = "LEASE"
+	PurposeKeyPrefix            = "PURPOSE"
+	DossierPolicyKeyPrefix      = "DOSSIER_POLICY"
+	DeadlinePolicyKeyPrefix     = "DEADLINE_POLICY"
+	ParcelLockKeyPrefix         = "PARCEL_LOCK"
+	ConfigKeyPrefix             = "CONFIG"
+	PersonalDataKeyPrefix       = "PERSON"     // Chỉ dùng trong PersonalDataCollection
+	TransactionPrivateKeyPrefix = "TX_PRIVATE" // Chỉ dùng trong TransactionPrivateCollection
+)
+
+// Namespace của các chỉ mục phụ (giá trị chỉ là đánh dấu, dữ liệu nằm ở bản ghi chính)
+const (
+	CertificateSerialIndex = "CERT_SERIAL"   // Số seri -> mã GCN, đảm bảo số seri là duy nhất
+	CertificateLandIndex   = "CERT_LAND"     // Thửa đất + mã GCN, dùng để liệt kê GCN theo thửa
+	MortgageLandIndex      = "MORTGAGE_LAND" // Thửa đất + mã thế chấp, dùng để liệt kê thế chấp theo thửa
+	DisputeLandIndex       = "DISPUTE_LAND"  // Thửa đất + mã tranh chấp, dùng để liệt kê tranh chấp theo thửa
+	LeaseLandIndex         = "LEASE_LAND"    // Thửa đất + mã hợp đồng thuê, dùng để liệt kê hợp đồng thuê theo thửa
+	LeaseLesseeIndex       = "LEASE_LESSEE"  // Bên thuê + mã hợp đồng thuê, dùng để liệt kê hợp đồng thuê theo bên thuê
+	LandTileIndex          = "LAND_TILE"     // Ô lưới (tileX, tileY) + mã thửa đất, dùng để truy vấn thửa đất theo vùng
+)
+
+// LandKey tạo composite key cho thửa đất
+func LandKey(ctx contractapi.TransactionContextInterface, landID string) (string, error) {
+	return createEntityKey(ctx, LandKeyPrefix, landID)
+}
+
+// DocumentKey tạo composite key cho tài liệu
+func DocumentKey(ctx contractapi.TransactionContextInterface, docID string) (string, error) {
+	return createEntityKey(ctx, DocumentKeyPrefix, docID)
+}
+
+// TransactionKey tạo composite key cho giao dịch
+func TransactionKey(ctx contractapi.TransactionContextInterface, txID string) (string, error) {
+	return createEntityKey(ctx, TransactionKeyPrefix, txID)
+}
+
+// CertificateKey tạo composite key cho giấy chứng nhận
+func CertificateKey(ctx contractapi.TransactionContextInterface, certificateID string) (string, error) {
+	return createEntityKey(ctx, CertificateKeyPrefix, certificateID)
+}
+
+// MortgageKey tạo composite key cho hợp đồng thế chấp
+func MortgageKey(ctx contractapi.TransactionContextInterface, mortgageID string) (string, error) {
+	return createEntityKey(ctx, MortgageKeyPrefix, mortgageID)
+}
+
+// DisputeKey tạo composite key cho vụ tranh chấp
+func DisputeKey(ctx contractapi.TransactionContextInterface, disputeID string) (string, error) {
+	return createEntityKey(ctx, DisputeKeyPrefix, disputeID)
+}
+
+// LeaseKey tạo composite key cho hợp đồng thuê đất
+func LeaseKey(ctx contractapi.TransactionContextInterface, leaseID string) (string, error) {
+	return createEntityKey(ctx, LeaseKeyPrefix, leaseID)
+}
+
+// createEntityKey tạo composite key theo namespace và ID thực thể
+func createEntityKey(ctx contractapi.TransactionContextInterface, objectType, id string) (string, error) {
+	if strings.TrimSpace(id) == "" {
+		return "", fmt.Errorf("ID của %s không được để trống", objectType)
+	}
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, []string{id})
+	if err != nil {
+		return "", fmt.Errorf("lỗi khi tạo khóa %s cho %s: %v", objectType, id, err)
+	}
+	return key, nil
+}
+
+// GetLandState đọc dữ liệu thô của thửa đất theo composite key
+func GetLandState(ctx contractapi.TransactionContextInterface, landID string) ([]byte, error) {
+	key, err := LandKey(ctx, landID)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.GetStub().GetState(key)
+}
+
+// PutLandState ghi dữ liệu thửa đất theo composite key
+func PutLandState(ctx contractapi.TransactionContextInterface, landID string, data []byte) error {
+	key, err := LandKey(ctx, landID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// GetDocumentState đọc dữ liệu thô của tài liệu theo composite key
+func GetDocumentState(ctx contractapi.TransactionContextInterface, docID string) ([]byte, error) {
+	key, err := DocumentKey(ctx, docID)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.GetStub().GetState(key)
+}
+
+// PutDocumentState ghi dữ liệu tài liệu theo composite key
+func PutDocumentState(ctx contractapi.TransactionContextInterface, docID string, data []byte) error {
+	key, err := DocumentKey(ctx, docID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// DelDocumentState xóa tài liệu theo composite key
+func DelDocumentState(ctx contractapi.TransactionContextInterface, docID string) error {
+	key, err := DocumentKey(ctx, docID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(key)
+}
+
+// GetTransactionState đọc dữ liệu thô của giao dịch theo composite key
+func GetTransactionState(ctx contractapi.TransactionContextInterface, txID string) ([]byte, error) {
+	key, err := TransactionKey(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.GetStub().GetState(key)
+}
+
+// PutTransactionState ghi dữ liệu giao dịch theo composite key
+func PutTransactionState(ctx contractapi.TransactionContextInterface, txID string, data []byte) error {
+	key, err := TransactionKey(ctx, txID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// GetCertificateState đọc dữ liệu thô của giấy chứng nhận theo composite key
+func GetCertificateState(ctx contractapi.TransactionContextInterface, certificateID string) ([]byte, error) {
+	key, err := CertificateKey(ctx, certificateID)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.GetStub().GetState(key)
+}
+
+// PutCertificateState ghi dữ liệu giấy chứng nhận theo composite key
+func PutCertificateState(ctx contractapi.TransactionContextInterface, certificateID string, data []byte) error {
+	key, err := CertificateKey(ctx, certificateID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// GetMortgageState đọc dữ liệu thô của hợp đồng thế chấp theo composite key
+func GetMortgageState(ctx contractapi.TransactionContextInterface, mortgageID string) ([]byte, error) {
+	key, err := MortgageKey(ctx, mortgageID)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.GetStub().GetState(key)
+}
+
+// PutMortgageState ghi dữ liệu hợp đồng thế chấp theo composite key
+func PutMortgageState(ctx contractapi.TransactionContextInterface, mortgageID string, data []byte) error {
+	key, err := MortgageKey(ctx, mortgageID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// GetDisputeState đọc dữ liệu thô của vụ tranh chấp theo composite key
+func GetDisputeState(ctx contractapi.TransactionContextInterface, disputeID string) ([]byte, error) {
+	key, err := DisputeKey(ctx, disputeID)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.GetStub().GetState(key)
+}
+
+// PutDisputeState ghi dữ liệu vụ tranh chấp theo composite key
+func PutDisputeState(ctx contractapi.TransactionContextInterface, disputeID string, data []byte) error {
+	key, err := DisputeKey(ctx, disputeID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// GetLeaseState đọc dữ liệu thô của hợp đồng thuê đất theo composite key
+func GetLeaseState(ctx contractapi.TransactionContextInterface, leaseID string) ([]byte, error) {
+	key, err := LeaseKey(ctx, leaseID)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.GetStub().GetState(key)
+}
+
+// PutLeaseState ghi dữ liệu hợp đồng thuê đất theo composite key
+func PutLeaseState(ctx contractapi.TransactionContextInterface, leaseID string, data []byte) error {
+	key, err := LeaseKey(ctx, leaseID)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(key, data)
+}
+
+// KeyNamespace trả về namespace của composite key (rỗng nếu là khóa phẳng)
+func KeyNamespace(ctx contractapi.TransactionContextInterface, key string) string {
+	if !strings.HasPrefix(key, "\x00") {
+		return ""
+	}
+	objectType, _, err := ctx.GetStub().SplitCompositeKey(key)
+	if err != nil {
+		return ""
+	}
+	return objectType
+}
+
+// ========================================
+// KEYSPACE MIGRATION
+// ========================================
+
+// KeyspaceMigrationResult kết quả của một lô di chuyển dữ liệu sang composite key
+type KeyspaceMigrationResult struct {
+	Migrated int            `json:"migrated"` // Số bản ghi đã di chuyển trong lô này
+	Skipped  []string       `json:"skipped"`  // Các khóa không xác định được loại thực thể
+	Counts   map[string]int `json:"counts"`   // Số bản ghi theo namespace
+	NextKey  string         `json:"nextKey"`  // Khóa bắt đầu cho lô tiếp theo (rỗng nếu đã xong)
+	Done     bool           `json:"done"`     // Đã di chuyển hết dữ liệu
+}
+
+// MigrateKeyspace - Di chuyển các bản ghi lưu theo khóa phẳng sang composite key (chỉ Org1)
+// Gọi lặp lại với startKey = nextKey cho đến khi done = true
+func (s *LandRegistryChaincode) MigrateKeyspace(ctx contractapi.TransactionContextInterface, startKey string, batchSize int) (*KeyspaceMigrationResult, error) {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return nil, err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	// GetStateByRange chỉ trả về các khóa phẳng, không bao gồm composite key
+	resultsIterator, err := ctx.GetStub().GetStateByRange(startKey, "")
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi duyệt dữ liệu cũ: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	result := &KeyspaceMigrationResult{
+		Skipped: []string{},
+		Counts:  map[string]int{},
+		Done:    true,
+	}
+	processed := 0
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi đọc dữ liệu cũ: %v", err)
+		}
+		if processed >= batchSize {
+			result.NextKey = response.Key
+			result.Done = false
+			break
+		}
+		processed++
+
+		objectType := classifyLegacyRecord(response.Key, response.Value)
+		if objectType == "" {
+			result.Skipped = append(result.Skipped, response.Key)
+			continue
+		}
+		if objectType == AuditLogKeyPrefix {
+			// Nhật ký cũ được chuyển đổi sang AuditLogEntry
+			if err := migrateLegacyLog(ctx, response.Key, response.Value); err != nil {
+				return nil, err
+			}
+		} else {
+			newKey, err := createEntityKey(ctx, objectType, response.Key)
+			if err != nil {
+				return nil, err
+			}
+			if err := ctx.GetStub().PutState(newKey, response.Value); err != nil {
+				return nil, fmt.Errorf("lỗi khi ghi bản ghi %s sang namespace %s: %v", response.Key, objectType, err)
+			}
+		}
+		if err := ctx.GetStub().DelState(response.Key); err != nil {
+			return nil, fmt.Errorf("lỗi khi xóa khóa cũ %s: %v", response.Key, err)
+		}
+		result.Migrated++
+		result.Counts[objectType]++
+	}
+
+	if result.Migrated > 0 {
+		details := fmt.Sprintf("Di chuyển %d bản ghi sang composite key %v", result.Migrated, result.Counts)
+		if err := RecordAuditLog(ctx, "MIGRATE_KEYSPACE", userID, "", "", details, nil); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// classifyLegacyRecord xác định namespace của một bản ghi lưu theo khóa phẳng
+func classifyLegacyRecord(key string, value []byte) string {
+	if strings.HasPrefix(key, "LOG_") {
+		return AuditLogKeyPrefix
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(value, &fields); err != nil {
+		return ""
+	}
+	if _, ok := fields["docID"]; ok {
+		return DocumentKeyPrefix
+	}
+	if _, ok := fields["txId"]; ok {
+		if t, _ := fields["type"].(string); t == "LOG" {
+			return AuditLogKeyPrefix
+		}
+		return TransactionKeyPrefix
+	}
+	if _, ok := fields["landUsePurpose"]; ok {
+		return LandKeyPrefix
+	}
+	return ""
+}
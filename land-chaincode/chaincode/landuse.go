@@ -0,0 +1,337 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Nhóm đất theo Luật Đất đai
+const (
+	PurposeCategoryAgricultural    = "AGRICULTURAL"     // Nhóm đất nông nghiệp
+	PurposeCategoryNonAgricultural = "NON_AGRICULTURAL" // Nhóm đất phi nông nghiệp
+	PurposeCategoryUnused          = "UNUSED"           // Nhóm đất chưa sử dụng
+)
+
+// defaultLandUsePurposes danh mục mã mục đích sử dụng đất khởi tạo, gồm các mã có trong dữ liệu hiện hành
+var defaultLandUsePurposes = []LandUsePurpose{
+	{Code: "LUC", Name: "Đất chuyên trồng lúa nước", Category: PurposeCategoryAgricultural},
+	{Code: "LUK", Name: "Đất trồng lúa nước còn lại", Category: PurposeCategoryAgricultural},
+	{Code: "BHK", Name: "Đất bằng trồng cây hàng năm khác", Category: PurposeCategoryAgricultural},
+	{Code: "CLN", Name: "Đất trồng cây lâu năm", Category: PurposeCategoryAgricultural},
+	{Code: "LNQ", Name: "Đất trồng cây ăn quả lâu năm", Category: PurposeCategoryAgricultural},
+	{Code: "RSX", Name: "Đất rừng sản xuất", Category: PurposeCategoryAgricultural},
+	{Code: "NTS", Name: "Đất nuôi trồng thủy sản", Category: PurposeCategoryAgricultural},
+	{Code: "ONT", Name: "Đất ở tại nông thôn", Category: PurposeCategoryNonAgricultural},
+	{Code: "ODT", Name: "Đất ở tại đô thị", Category: PurposeCategoryNonAgricultural},
+	{Code: "TSC", Name: "Đất xây dựng trụ sở cơ quan", Category: PurposeCategoryNonAgricultural},
+	{Code: "TSK", Name: "Đất xây dựng trụ sở của tổ chức khác", Category: PurposeCategoryNonAgricultural},
+	{Code: "DVH", Name: "Đất xây dựng cơ sở văn hóa", Category: PurposeCategoryNonAgricultural},
+	{Code: "DYT", Name: "Đất xây dựng cơ sở y tế", Category: PurposeCategoryNonAgricultural},
+	{Code: "DGD", Name: "Đất xây dựng cơ sở giáo dục và đào tạo", Category: PurposeCategoryNonAgricultural},
+	{Code: "SKC", Name: "Đất cơ sở sản xuất phi nông nghiệp", Category: PurposeCategoryNonAgricultural},
+	{Code: "TMD", Name: "Đất thương mại, dịch vụ", Category: PurposeCategoryNonAgricultural},
+	{Code: "DGT", Name: "Đất giao thông", Category: PurposeCategoryNonAgricultural},
+	{Code: "DTL", Name: "Đất công trình thủy lợi", Category: PurposeCategoryNonAgricultural},
+	{Code: "DNL", Name: "Đất công trình năng lượng", Category: PurposeCategoryNonAgricultural},
+	{Code: "DCH", Name: "Đất chợ", Category: PurposeCategoryNonAgricultural},
+	{Code: "DRA", Name: "Đất bãi thải, xử lý chất thải", Category: PurposeCategoryNonAgricultural},
+	{Code: "TIN", Name: "Đất cơ sở tín ngưỡng", Category: PurposeCategoryNonAgricultural},
+	{Code: "NTD", Name: "Đất nghĩa trang, nghĩa địa, nhà tang lễ, nhà hỏa táng", Category: PurposeCategoryNonAgricultural},
+	{Code: "SON", Name: "Đất sông, ngòi, kênh, rạch, suối", Category: PurposeCategoryNonAgricultural},
+	{Code: "MNC", Name: "Đất có mặt nước chuyên dùng", Category: PurposeCategoryNonAgricultural},
+	{Code: "BCS", Name: "Đất bằng chưa sử dụng", Category: PurposeCategoryUnused},
+	{Code: "DCS", Name: "Đất đồi núi chưa sử dụng", Category: PurposeCategoryUnused},
+}
+
+// validatePurposeCategory kiểm tra nhóm đất hợp lệ
+func validatePurposeCategory(category string) error {
+	switch category {
+	case PurposeCategoryAgricultural, PurposeCategoryNonAgricultural, PurposeCategoryUnused:
+		return nil
+	default:
+		return fmt.Errorf("nhóm đất %s không hợp lệ (AGRICULTURAL, NON_AGRICULTURAL, UNUSED)", category)
+	}
+}
+
+// getLandUsePurpose đọc mã mục đích sử dụng trong danh mục (trả về nil nếu không tồn tại)
+func getLandUsePurpose(ctx contractapi.TransactionContextInterface, code string) (*LandUsePurpose, error) {
+	key, err := createEntityKey(ctx, PurposeKeyPrefix, code)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn mục đích sử dụng %s: %v", code, err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	var purpose LandUsePurpose
+	if err := json.Unmarshal(data, &purpose); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã mục đích sử dụng %s: %v", code, err)
+	}
+	return &purpose, nil
+}
+
+// putLandUsePurpose lưu mã mục đích sử dụng vào danh mục
+func putLandUsePurpose(ctx contractapi.TransactionContextInterface, purpose *LandUsePurpose) error {
+	key, err := createEntityKey(ctx, PurposeKeyPrefix, purpose.Code)
+	if err != nil {
+		return err
+	}
+	purposeJSON, err := json.Marshal(purpose)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa mục đích sử dụng: %v", err)
+	}
+	if err := ctx.GetStub().PutState(key, purposeJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu mục đích sử dụng %s: %v", purpose.Code, err)
+	}
+	return nil
+}
+
+// seedLandUsePurposes ghi danh mục mặc định cho các mã chưa có trên sổ cái
+func seedLandUsePurposes(ctx contractapi.TransactionContextInterface, userID string, txTime time.Time) (int, error) {
+	seeded := 0
+	for _, purpose := range defaultLandUsePurposes {
+		existing, err := getLandUsePurpose(ctx, purpose.Code)
+		if err != nil {
+			return seeded, err
+		}
+		if existing != nil {
+			continue
+		}
+		entry := purpose
+		entry.Active = true
+		entry.UpdatedBy = userID
+		entry.CreatedAt = txTime
+		entry.UpdatedAt = txTime
+		if err := putLandUsePurpose(ctx, &entry); err != nil {
+			return seeded, err
+		}
+		seeded++
+	}
+	return seeded, nil
+}
+
+// ValidateLandUsePurpose kiểm tra mã mục đích sử dụng có trong danh mục và đang được sử dụng
+func ValidateLandUsePurpose(ctx contractapi.TransactionContextInterface, code string) error {
+	if strings.TrimSpace(code) == "" {
+		return fmt.Errorf("mục đích sử dụng không được để trống")
+	}
+	purpose, err := getLandUsePurpose(ctx, code)
+	if err != nil {
+		return err
+	}
+	if purpose == nil {
+		return fmt.Errorf("mục đích sử dụng %s không có trong danh mục", code)
+	}
+	if !purpose.Active {
+		return fmt.Errorf("mục đích sử dụng %s (%s) đã ngừng sử dụng", code, purpose.Name)
+	}
+	return nil
+}
+
+// ========================================
+// LAND USE PURPOSE CATALOG FUNCTIONS
+// ========================================
+
+// SeedLandUsePurposes - Khởi tạo danh mục mục đích sử dụng mặc định (chỉ Org1), bỏ qua các mã đã có
+func (s *LandRegistryChaincode) SeedLandUsePurposes(ctx contractapi.TransactionContextInterface) (int, error) {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return 0, err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	seeded, err := seedLandUsePurposes(ctx, userID, txTime)
+	if err != nil {
+		return 0, err
+	}
+	if err := RecordAuditLog(ctx, "SEED_LAND_USE_PURPOSES", userID, PurposeKeyPrefix, "", fmt.Sprintf("Khởi tạo %d mã mục đích sử dụng", seeded), nil); err != nil {
+		return 0, err
+	}
+	return seeded, nil
+}
+
+// CreateLandUsePurpose - Thêm mã mục đích sử dụng vào danh mục (chỉ Org1)
+func (s *LandRegistryChaincode) CreateLandUsePurpose(ctx contractapi.TransactionContextInterface, code, name, category string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return fmt.Errorf("mã mục đích sử dụng không được để trống")
+	}
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("tên mục đích sử dụng không được để trống")
+	}
+	if err := validatePurposeCategory(category); err != nil {
+		return err
+	}
+	existing, err := getLandUsePurpose(ctx, code)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return fmt.Errorf("mục đích sử dụng %s đã tồn tại", code)
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	purpose := &LandUsePurpose{
+		Code:      code,
+		Name:      name,
+		Category:  category,
+		Active:    true,
+		UpdatedBy: userID,
+		CreatedAt: txTime,
+		UpdatedAt: txTime,
+	}
+	if err := putLandUsePurpose(ctx, purpose); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "CREATE_LAND_USE_PURPOSE", userID, PurposeKeyPrefix, code, fmt.Sprintf("Thêm mục đích sử dụng %s - %s", code, name), map[string]string{"category": category})
+}
+
+// UpdateLandUsePurpose - Cập nhật tên, nhóm đất và trạng thái sử dụng của mã (chỉ Org1)
+func (s *LandRegistryChaincode) UpdateLandUsePurpose(ctx contractapi.TransactionContextInterface, code, name, category, activeStr string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	purpose, err := getLandUsePurpose(ctx, code)
+	if err != nil {
+		return err
+	}
+	if purpose == nil {
+		return fmt.Errorf("mục đích sử dụng %s không tồn tại", code)
+	}
+	if strings.TrimSpace(name) != "" {
+		purpose.Name = name
+	}
+	if category != "" {
+		if err := validatePurposeCategory(category); err != nil {
+			return err
+		}
+		purpose.Category = category
+	}
+	switch activeStr {
+	case "":
+	case "true":
+		purpose.Active = true
+	case "false":
+		purpose.Active = false
+	default:
+		return fmt.Errorf("giá trị active %s không hợp lệ (true hoặc false)", activeStr)
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	purpose.UpdatedBy = userID
+	purpose.UpdatedAt = txTime
+	if err := putLandUsePurpose(ctx, purpose); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "UPDATE_LAND_USE_PURPOSE", userID, PurposeKeyPrefix, code, fmt.Sprintf("Cập nhật mục đích sử dụng %s", code),
+		map[string]string{"name": purpose.Name, "category": purpose.Category, "active": fmt.Sprintf("%t", purpose.Active)})
+}
+
+// DeleteLandUsePurpose - Xóa mã mục đích sử dụng chưa được thửa đất nào sử dụng (chỉ Org1)
+// Mã đã được sử dụng chỉ có thể ngừng sử dụng qua UpdateLandUsePurpose
+func (s *LandRegistryChaincode) DeleteLandUsePurpose(ctx contractapi.TransactionContextInterface, code string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	purpose, err := getLandUsePurpose(ctx, code)
+	if err != nil {
+		return err
+	}
+	if purpose == nil {
+		return fmt.Errorf("mục đích sử dụng %s không tồn tại", code)
+	}
+
+	queryString := fmt.Sprintf(`{"selector":{"landUsePurpose":"%s"},"limit":1}`, code)
+	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
+	if err != nil {
+		return fmt.Errorf("lỗi khi kiểm tra thửa đất sử dụng mã %s: %v", code, err)
+	}
+	defer resultsIterator.Close()
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return fmt.Errorf("lỗi khi đọc kết quả truy vấn: %v", err)
+		}
+		if KeyNamespace(ctx, response.Key) == LandKeyPrefix {
+			return fmt.Errorf("mục đích sử dụng %s đang được sử dụng, chỉ có thể ngừng sử dụng", code)
+		}
+	}
+
+	key, err := createEntityKey(ctx, PurposeKeyPrefix, code)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().DelState(key); err != nil {
+		return fmt.Errorf("lỗi khi xóa mục đích sử dụng %s: %v", code, err)
+	}
+	return RecordAuditLog(ctx, "DELETE_LAND_USE_PURPOSE", userID, PurposeKeyPrefix, code, fmt.Sprintf("Xóa mục đích sử dụng %s", code), nil)
+}
+
+// GetLandUsePurpose - Truy vấn một mã mục đích sử dụng
+func (s *LandRegistryChaincode) GetLandUsePurpose(ctx contractapi.TransactionContextInterface, code string) (*LandUsePurpose, error) {
+	purpose, err := getLandUsePurpose(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if purpose == nil {
+		return nil, fmt.Errorf("mục đích sử dụng %s không tồn tại", code)
+	}
+	return purpose, nil
+}
+
+// ListLandUsePurposes - Liệt kê danh mục mục đích sử dụng (mặc định chỉ các mã đang sử dụng)
+func (s *LandRegistryChaincode) ListLandUsePurposes(ctx contractapi.TransactionContextInterface, includeInactive bool) ([]*LandUsePurpose, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(PurposeKeyPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn danh mục mục đích sử dụng: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	purposes := []*LandUsePurpose{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi đọc danh mục mục đích sử dụng: %v", err)
+		}
+		var purpose LandUsePurpose
+		if err := json.Unmarshal(response.Value, &purpose); err != nil {
+			return nil, fmt.Errorf("lỗi khi giải mã mục đích sử dụng: %v", err)
+		}
+		if !purpose.Active && !includeInactive {
+			continue
+		}
+		purposes = append(purposes, &purpose)
+	}
+	return purposes, nil
+}
@@ -0,0 +1,568 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Trạng thái hợp đồng thuê đất
+const (
+	LeaseStatusPendingConfirmation = "PENDING_CONFIRMATION" // Chờ các bên xác nhận
+	LeaseStatusConfirmed           = "CONFIRMED"            // Các bên đã xác nhận, chờ Org1 ghi nhận
+	LeaseStatusActive              = "ACTIVE"               // Đã ghi nhận, đang hiệu lực
+	LeaseStatusDeclined            = "DECLINED"             // Một bên từ chối
+	LeaseStatusTerminated          = "TERMINATED"           // Đã chấm dứt
+)
+
+// rentPaymentPeriods các kỳ thanh toán tiền thuê hợp lệ
+var rentPaymentPeriods = []string{"MONTHLY", "QUARTERLY", "YEARLY", "LUMP_SUM"}
+
+// getLease đọc hợp đồng thuê theo mã
+func getLease(ctx contractapi.TransactionContextInterface, leaseID string) (*Lease, error) {
+	data, err := GetLeaseState(ctx, leaseID)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn hợp đồng thuê %s: %v", leaseID, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("hợp đồng thuê %s không tồn tại", leaseID)
+	}
+	var lease Lease
+	if err := json.Unmarshal(data, &lease); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã hợp đồng thuê %s: %v", leaseID, err)
+	}
+	return &lease, nil
+}
+
+// putLease lưu hợp đồng thuê cùng chỉ mục theo thửa đất và theo bên thuê
+func putLease(ctx contractapi.TransactionContextInterface, lease *Lease) error {
+	leaseJSON, err := json.Marshal(lease)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa hợp đồng thuê: %v", err)
+	}
+	if err := PutLeaseState(ctx, lease.LeaseID, leaseJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu hợp đồng thuê %s: %v", lease.LeaseID, err)
+	}
+	indexes := map[string]string{LeaseLandIndex: lease.LandParcelID, LeaseLesseeIndex: lease.LesseeID}
+	for index, attribute := range indexes {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{attribute, lease.LeaseID})
+		if err != nil {
+			return fmt.Errorf("lỗi khi tạo chỉ mục hợp đồng thuê %s: %v", lease.LeaseID, err)
+		}
+		if err := ctx.GetStub().PutState(indexKey, []byte{0x00}); err != nil {
+			return fmt.Errorf("lỗi khi lưu chỉ mục hợp đồng thuê %s: %v", lease.LeaseID, err)
+		}
+	}
+	return nil
+}
+
+// getLeasesByIndex liệt kê hợp đồng thuê theo chỉ mục (thửa đất hoặc bên thuê)
+func getLeasesByIndex(ctx contractapi.TransactionContextInterface, index, attribute string) ([]*Lease, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(index, []string{attribute})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn hợp đồng thuê theo %s: %v", attribute, err)
+	}
+	defer resultsIterator.Close()
+
+	leases := []*Lease{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi đọc chỉ mục hợp đồng thuê: %v", err)
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil || len(attributes) != 2 {
+			continue
+		}
+		lease, err := getLease(ctx, attributes[1])
+		if err != nil {
+			return nil, err
+		}
+		leases = append(leases, lease)
+	}
+	return leases, nil
+}
+
+// getLeasesByLand liệt kê tất cả hợp đồng thuê, thuê lại của thửa đất
+func getLeasesByLand(ctx contractapi.TransactionContextInterface, landParcelID string) ([]*Lease, error) {
+	return getLeasesByIndex(ctx, LeaseLandIndex, landParcelID)
+}
+
+// isLeaseInForce kiểm tra hợp đồng thuê đã ghi nhận và chưa hết hạn tại thời điểm at
+func isLeaseInForce(lease *Lease, at time.Time) bool {
+	return lease.Status == LeaseStatusActive && lease.EndDate.After(at)
+}
+
+// isLeaseOpen kiểm tra hợp đồng thuê còn đang xử lý hoặc đang hiệu lực
+func isLeaseOpen(lease *Lease, at time.Time) bool {
+	switch lease.Status {
+	case LeaseStatusPendingConfirmation, LeaseStatusConfirmed:
+		return true
+	default:
+		return isLeaseInForce(lease, at)
+	}
+}
+
+// leasePeriodsOverlap kiểm tra hai thời hạn thuê có giao nhau không
+func leasePeriodsOverlap(a, b *Lease) bool {
+	return a.StartDate.Before(b.EndDate) && b.StartDate.Before(a.EndDate)
+}
+
+// VerifyNoActiveLease kiểm tra thửa đất không có hợp đồng thuê đang hiệu lực
+func VerifyNoActiveLease(ctx contractapi.TransactionContextInterface, landParcelID string) error {
+	leases, err := getLeasesByLand(ctx, landParcelID)
+	if err != nil {
+		return err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	for _, lease := range leases {
+		if isLeaseInForce(lease, txTime) {
+			return fmt.Errorf("thửa đất %s đang được cho thuê đến %s (hợp đồng %s)", landParcelID, lease.EndDate.Format("2006-01-02"), lease.LeaseID)
+		}
+	}
+	return nil
+}
+
+// getLeaseLand đọc thửa đất trực tiếp từ sổ cái (bên thuê cho thuê lại không phải chủ sử dụng nên không dùng QueryLandByID)
+func getLeaseLand(ctx contractapi.TransactionContextInterface, landParcelID string) (*Land, error) {
+	data, err := GetLandState(ctx, landParcelID)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", landParcelID, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("thửa đất %s không tồn tại", landParcelID)
+	}
+	var land Land
+	if err := json.Unmarshal(data, &land); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã thửa đất: %v", err)
+	}
+	return &land, nil
+}
+
+// inForceLeaseIDs lọc danh sách hợp đồng thuê trên thửa đất, bỏ các hợp đồng đã hết hạn, đã chấm dứt
+// hoặc nằm trong excluded (hợp đồng vừa chấm dứt trong cùng giao dịch chưa đọc lại được)
+func inForceLeaseIDs(ctx contractapi.TransactionContextInterface, leaseIDs, excluded []string, txTime time.Time) ([]string, error) {
+	var remaining []string
+	for _, id := range leaseIDs {
+		if containsString(excluded, id) || containsString(remaining, id) {
+			continue
+		}
+		lease, err := getLease(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if isLeaseInForce(lease, txTime) {
+			remaining = append(remaining, id)
+		}
+	}
+	return remaining, nil
+}
+
+// saveLandLeaseIDs cập nhật danh sách hợp đồng thuê đang hiệu lực trên thửa đất
+func saveLandLeaseIDs(ctx contractapi.TransactionContextInterface, land *Land, leaseIDs []string, txTime time.Time) error {
+	land.LeaseIDs = leaseIDs
+	land.UpdatedAt = txTime
+	landJSON, err := json.Marshal(land)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
+	}
+	if err := PutLandState(ctx, land.ID, landJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
+	}
+	return nil
+}
+
+// ========================================
+// LEASE MANAGEMENT FUNCTIONS
+// ========================================
+
+// RegisterLease - Đăng ký hợp đồng cho thuê (chủ sử dụng) hoặc cho thuê lại (bên thuê của hợp đồng gốc)
+// CCCD bên thuê ("lesseeId") được truyền qua transient map; parentLeaseID để trống nếu không phải cho thuê lại
+// Chủ sử dụng, bên cho thuê lại và bên thuê đều phải xác nhận trước khi Org1 ghi nhận
+func (s *LandRegistryChaincode) RegisterLease(ctx contractapi.TransactionContextInterface, landParcelID, parentLeaseID, rentAmount, rentPaymentPeriod, rentTerms, contractDocID, startDate, endDate string) error {
+	if err := CheckOrganization(ctx, []string{"Org3MSP"}); err != nil {
+		return err
+	}
+	callerID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	amount, err := parseFloat(rentAmount)
+	if err != nil {
+		return fmt.Errorf("lỗi khi chuyển đổi tiền thuê: %v", err)
+	}
+	if amount < 0 {
+		return fmt.Errorf("tiền thuê không được âm")
+	}
+	rentPaymentPeriod = strings.ToUpper(strings.TrimSpace(rentPaymentPeriod))
+	if !containsString(rentPaymentPeriods, rentPaymentPeriod) {
+		return fmt.Errorf("kỳ thanh toán %s không hợp lệ, phải là một trong: %s", rentPaymentPeriod, strings.Join(rentPaymentPeriods, ", "))
+	}
+	start, err := parseDate(startDate)
+	if err != nil {
+		return fmt.Errorf("ngày bắt đầu không hợp lệ: %v", err)
+	}
+	end, err := parseDate(endDate)
+	if err != nil {
+		return fmt.Errorf("ngày kết thúc không hợp lệ: %v", err)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("ngày kết thúc phải sau ngày bắt đầu")
+	}
+	if _, err := GetDocument(ctx, contractDocID); err != nil {
+		return fmt.Errorf("tài liệu hợp đồng thuê không hợp lệ: %v", err)
+	}
+
+	land, err := getLeaseLand(ctx, landParcelID)
+	if err != nil {
+		return err
+	}
+	// Thửa đất bị đóng băng khi đang có tranh chấp
+	if err := VerifyNoOpenDispute(ctx, landParcelID); err != nil {
+		return err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	lesseeID, err := transientSubjectRef(ctx, "lesseeId", callerID, txTime, true)
+	if err != nil {
+		return fmt.Errorf("CCCD bên thuê không hợp lệ: %v", err)
+	}
+
+	lease := &Lease{
+		LandParcelID:      landParcelID,
+		ParentLeaseID:     strings.TrimSpace(parentLeaseID),
+		LessorID:          callerID,
+		LesseeID:          lesseeID,
+		RentAmount:        amount,
+		RentPaymentPeriod: rentPaymentPeriod,
+		RentTerms:         rentTerms,
+		ContractDocID:     contractDocID,
+		StartDate:         start,
+		EndDate:           end,
+		Status:            LeaseStatusPendingConfirmation,
+		RequiredConsents:  landOwnerIDs(land),
+		CreatedBy:         callerID,
+		CreatedAt:         txTime,
+		UpdatedAt:         txTime,
+	}
+	if lease.ParentLeaseID == "" {
+		if !IsLandOwner(land, callerID) {
+			return fmt.Errorf("người dùng %s không sở hữu thửa đất %s", callerID, landParcelID)
+		}
+	} else {
+		// Cho thuê lại: bên cho thuê lại phải là bên thuê của hợp đồng gốc đang hiệu lực
+		parent, err := getLease(ctx, lease.ParentLeaseID)
+		if err != nil {
+			return err
+		}
+		if parent.LandParcelID != landParcelID || parent.ParentLeaseID != "" {
+			return fmt.Errorf("hợp đồng thuê %s không phải hợp đồng thuê gốc của thửa đất %s", parent.LeaseID, landParcelID)
+		}
+		if parent.LesseeID != callerID {
+			return fmt.Errorf("người dùng %s không phải bên thuê của hợp đồng %s", callerID, parent.LeaseID)
+		}
+		if !isLeaseInForce(parent, txTime) {
+			return fmt.Errorf("hợp đồng thuê gốc %s không còn hiệu lực", parent.LeaseID)
+		}
+		if start.Before(parent.StartDate) || end.After(parent.EndDate) {
+			return fmt.Errorf("thời hạn cho thuê lại phải nằm trong thời hạn của hợp đồng thuê gốc %s", parent.LeaseID)
+		}
+		lease.RequiredConsents = append(lease.RequiredConsents, callerID)
+	}
+	if lesseeID == callerID || containsString(lease.RequiredConsents, lesseeID) {
+		return fmt.Errorf("bên thuê không được là bên cho thuê hoặc chủ sử dụng thửa đất")
+	}
+	lease.RequiredConsents = append(lease.RequiredConsents, lesseeID)
+
+	// Không được có hai hợp đồng thuê (hoặc hai hợp đồng thuê lại của cùng hợp đồng gốc) trùng thời hạn
+	existing, err := getLeasesByLand(ctx, landParcelID)
+	if err != nil {
+		return err
+	}
+	for _, other := range existing {
+		if other.ParentLeaseID != lease.ParentLeaseID || !isLeaseOpen(other, txTime) {
+			continue
+		}
+		if leasePeriodsOverlap(lease, other) {
+			return fmt.Errorf("thời hạn thuê trùng với hợp đồng %s (%s)", other.LeaseID, other.Status)
+		}
+	}
+
+	lease.LeaseID = fmt.Sprintf("THUE_DAT_%d_%s_%s", txTime.Unix(), shortSubjectRef(callerID), landParcelID)
+	if lease.ParentLeaseID != "" {
+		lease.LeaseID = fmt.Sprintf("THUE_LAI_%d_%s_%s", txTime.Unix(), shortSubjectRef(callerID), landParcelID)
+	}
+	// Người đăng ký được xem là đã xác nhận
+	lease.Consents = []OwnerConsent{{OwnerID: callerID, Accepted: true, At: txTime}}
+	if err := putLease(ctx, lease); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "REGISTER_LEASE", callerID, LeaseKeyPrefix, lease.LeaseID,
+		fmt.Sprintf("Đăng ký hợp đồng thuê thửa đất %s", landParcelID),
+		map[string]string{"landParcelId": landParcelID, "parentLeaseId": lease.ParentLeaseID, "lesseeId": lesseeID, "startDate": startDate, "endDate": endDate})
+}
+
+// ConfirmLease - Chủ sử dụng, bên cho thuê lại hoặc bên thuê xác nhận hoặc từ chối hợp đồng thuê
+// Hợp đồng chuyển sang CONFIRMED khi tất cả các bên xác nhận, một bên từ chối thì hợp đồng bị từ chối
+func (s *LandRegistryChaincode) ConfirmLease(ctx contractapi.TransactionContextInterface, leaseID, isAcceptedStr, reason string) error {
+	if err := CheckOrganization(ctx, []string{"Org3MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	lease, err := getLease(ctx, leaseID)
+	if err != nil {
+		return err
+	}
+	if lease.Status != LeaseStatusPendingConfirmation {
+		return fmt.Errorf("hợp đồng thuê %s không ở trạng thái chờ xác nhận (hiện tại: %s)", leaseID, lease.Status)
+	}
+	if !containsString(lease.RequiredConsents, userID) {
+		return fmt.Errorf("người dùng %s không phải một bên của hợp đồng thuê %s", userID, leaseID)
+	}
+	if hasGivenConsent(lease.Consents, userID) {
+		return fmt.Errorf("người dùng %s đã xác nhận hợp đồng thuê %s", userID, leaseID)
+	}
+	accepted := isAcceptedStr == "true"
+	if !accepted && strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("phải nêu lý do khi từ chối hợp đồng thuê")
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	action := "CONFIRM_LEASE_ACCEPTED"
+	lease.Consents = append(lease.Consents, OwnerConsent{OwnerID: userID, Accepted: accepted, Reason: reason, At: txTime})
+	if accepted {
+		if len(pendingConsents(lease.RequiredConsents, lease.Consents)) == 0 {
+			lease.Status = LeaseStatusConfirmed
+		}
+	} else {
+		action = "CONFIRM_LEASE_REJECTED"
+		lease.Status = LeaseStatusDeclined
+		lease.TerminationReason = reason
+	}
+	lease.UpdatedAt = txTime
+	if err := putLease(ctx, lease); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, action, userID, LeaseKeyPrefix, leaseID,
+		fmt.Sprintf("Xác nhận hợp đồng thuê %s: %s", leaseID, lease.Status),
+		map[string]string{"landParcelId": lease.LandParcelID, "status": lease.Status})
+}
+
+// RecordLease - Org1 ghi nhận hợp đồng thuê đã được các bên xác nhận, hợp đồng có hiệu lực trên thửa đất
+func (s *LandRegistryChaincode) RecordLease(ctx contractapi.TransactionContextInterface, leaseID string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	lease, err := getLease(ctx, leaseID)
+	if err != nil {
+		return err
+	}
+	if lease.Status != LeaseStatusConfirmed {
+		return fmt.Errorf("hợp đồng thuê %s chưa được các bên xác nhận (hiện tại: %s)", leaseID, lease.Status)
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	if !lease.EndDate.After(txTime) {
+		return fmt.Errorf("hợp đồng thuê %s đã hết hạn", leaseID)
+	}
+	land, err := getLeaseLand(ctx, lease.LandParcelID)
+	if err != nil {
+		return err
+	}
+	if err := VerifyNoOpenDispute(ctx, lease.LandParcelID); err != nil {
+		return err
+	}
+	// Chủ sử dụng có thể đã thay đổi sau khi các bên xác nhận
+	for _, ownerID := range landOwnerIDs(land) {
+		if !containsString(lease.RequiredConsents, ownerID) {
+			return fmt.Errorf("chủ sử dụng thửa đất %s đã thay đổi, hợp đồng thuê %s cần đăng ký lại", lease.LandParcelID, leaseID)
+		}
+	}
+	if lease.ParentLeaseID != "" {
+		parent, err := getLease(ctx, lease.ParentLeaseID)
+		if err != nil {
+			return err
+		}
+		if !isLeaseInForce(parent, txTime) {
+			return fmt.Errorf("hợp đồng thuê gốc %s không còn hiệu lực", parent.LeaseID)
+		}
+	}
+
+	lease.Status = LeaseStatusActive
+	lease.RecordedBy = userID
+	lease.RecordedAt = txTime
+	lease.UpdatedAt = txTime
+	if err := putLease(ctx, lease); err != nil {
+		return err
+	}
+	// Bỏ các hợp đồng đã hết hạn hoặc đã chấm dứt khỏi danh sách của thửa đất
+	leaseIDs, err := inForceLeaseIDs(ctx, land.LeaseIDs, []string{leaseID}, txTime)
+	if err != nil {
+		return err
+	}
+	if err := saveLandLeaseIDs(ctx, land, append(leaseIDs, leaseID), txTime); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "RECORD_LEASE", userID, LeaseKeyPrefix, leaseID,
+		fmt.Sprintf("Ghi nhận hợp đồng thuê %s trên thửa đất %s", leaseID, lease.LandParcelID),
+		map[string]string{"landParcelId": lease.LandParcelID, "lesseeId": lease.LesseeID})
+}
+
+// TerminateLease - Org1 chấm dứt hợp đồng thuê (kèm các hợp đồng thuê lại của hợp đồng đó)
+func (s *LandRegistryChaincode) TerminateLease(ctx contractapi.TransactionContextInterface, leaseID, reason string) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Errorf("lý do chấm dứt hợp đồng thuê không được để trống")
+	}
+	lease, err := getLease(ctx, leaseID)
+	if err != nil {
+		return err
+	}
+	switch lease.Status {
+	case LeaseStatusPendingConfirmation, LeaseStatusConfirmed, LeaseStatusActive:
+	default:
+		return fmt.Errorf("hợp đồng thuê %s đã kết thúc (hiện tại: %s)", leaseID, lease.Status)
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	leases, err := getLeasesByLand(ctx, lease.LandParcelID)
+	if err != nil {
+		return err
+	}
+	terminated := []string{}
+	for _, candidate := range leases {
+		if candidate.LeaseID != leaseID && candidate.ParentLeaseID != leaseID {
+			continue
+		}
+		switch candidate.Status {
+		case LeaseStatusPendingConfirmation, LeaseStatusConfirmed, LeaseStatusActive:
+		default:
+			continue
+		}
+		candidate.Status = LeaseStatusTerminated
+		candidate.TerminatedBy = userID
+		candidate.TerminatedAt = txTime
+		candidate.TerminationReason = reason
+		if candidate.LeaseID != leaseID {
+			candidate.TerminationReason = fmt.Sprintf("Hợp đồng thuê gốc %s chấm dứt: %s", leaseID, reason)
+		}
+		candidate.UpdatedAt = txTime
+		if err := putLease(ctx, candidate); err != nil {
+			return err
+		}
+		terminated = append(terminated, candidate.LeaseID)
+	}
+
+	land, err := getLeaseLand(ctx, lease.LandParcelID)
+	if err != nil {
+		return err
+	}
+	remaining, err := inForceLeaseIDs(ctx, land.LeaseIDs, terminated, txTime)
+	if err != nil {
+		return err
+	}
+	if len(remaining) != len(land.LeaseIDs) {
+		if err := saveLandLeaseIDs(ctx, land, remaining, txTime); err != nil {
+			return err
+		}
+	}
+	return RecordAuditLog(ctx, "TERMINATE_LEASE", userID, LeaseKeyPrefix, leaseID,
+		fmt.Sprintf("Chấm dứt hợp đồng thuê %s", leaseID),
+		map[string]string{"landParcelId": lease.LandParcelID, "reason": reason, "terminated": strings.Join(terminated, ",")})
+}
+
+// QueryLeaseByID - Truy vấn hợp đồng thuê theo mã
+func (s *LandRegistryChaincode) QueryLeaseByID(ctx contractapi.TransactionContextInterface, leaseID string) (*Lease, error) {
+	lease, err := getLease(ctx, leaseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkLeaseAccess(ctx, lease); err != nil {
+		return nil, err
+	}
+	return lease, nil
+}
+
+// QueryLeasesByLand - Truy vấn các hợp đồng thuê, thuê lại của thửa đất
+func (s *LandRegistryChaincode) QueryLeasesByLand(ctx contractapi.TransactionContextInterface, landParcelID string) ([]*Lease, error) {
+	leases, err := getLeasesByLand(ctx, landParcelID)
+	if err != nil {
+		return nil, err
+	}
+	result := []*Lease{}
+	for _, lease := range leases {
+		if checkLeaseAccess(ctx, lease) == nil {
+			result = append(result, lease)
+		}
+	}
+	return result, nil
+}
+
+// QueryLeasesByLessee - Truy vấn các hợp đồng thuê của bên thuê (nhận CCCD hoặc mã định danh)
+// Org3 chỉ truy vấn được hợp đồng thuê của chính mình
+func (s *LandRegistryChaincode) QueryLeasesByLessee(ctx contractapi.TransactionContextInterface, lesseeID string) ([]*Lease, error) {
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lesseeID, err = resolveSubjectRef(ctx, lesseeID)
+	if err != nil {
+		return nil, err
+	}
+	if mspID == "Org3MSP" && lesseeID != userID {
+		return nil, fmt.Errorf("người dùng %s không có quyền truy vấn hợp đồng thuê của %s", userID, lesseeID)
+	}
+	return getLeasesByIndex(ctx, LeaseLesseeIndex, lesseeID)
+}
+
+// checkLeaseAccess Org3 chỉ xem các hợp đồng thuê mà mình là một bên
+func checkLeaseAccess(ctx contractapi.TransactionContextInterface, lease *Lease) error {
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return err
+	}
+	if mspID != "Org3MSP" {
+		return nil
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	if !containsString(lease.RequiredConsents, userID) {
+		return fmt.Errorf("người dùng %s không có quyền xem hợp đồng thuê %s", userID, lease.LeaseID)
+	}
+	return nil
+}
@@ -76037,13 +76037,23 @@ func (s *LandRegistryChaincode) LoadAllData(ctx contractapi.TransactionContextIn
 		},
 	}
 
+	// Chủ sử dụng được lưu bằng mã định danh băm, cần thiết lập salt trước khi nạp dữ liệu
+	if _, err := getPersonalDataSalt(ctx); err != nil {
+		return err
+	}
+
 	successCount := 0
 	errorCount := 0
 
 	for _, landEntry := range landData {
+		ownerRef, err := ensurePersonalData(ctx, landEntry.OwnerID, "SYSTEM", txTime)
+		if err != nil {
+			errorCount++
+			continue
+		}
 		land := &Land{
 			ID:             landEntry.ID,
-			OwnerID:        landEntry.OwnerID,
+			OwnerID:        ownerRef,
 			Area:           landEntry.Area,
 			Location:       landEntry.Location,
 			LandUsePurpose: landEntry.LandUsePurpose,
@@ -76057,7 +76067,7 @@ func (s *LandRegistryChaincode) LoadAllData(ctx contractapi.TransactionContextIn
 			UpdatedAt:      txTime,
 		}
 
-		err := ValidateLand(ctx, *land, false)
+		err = ValidateLand(ctx, *land, false)
 		if err != nil {
 			errorCount++
 			continue
@@ -76069,7 +76079,7 @@ func (s *LandRegistryChaincode) LoadAllData(ctx contractapi.TransactionContextIn
 			continue
 		}
 
-		err = ctx.GetStub().PutState(land.ID, landJSON)
+		err = PutLandState(ctx, land.ID, landJSON)
 		if err != nil {
 			errorCount++
 			continue
@@ -76079,21 +76089,9 @@ func (s *LandRegistryChaincode) LoadAllData(ctx contractapi.TransactionContextIn
 	}
 
 	// Log completion
-	logEntry := &Transaction{
-		TxID:           ctx.GetStub().GetTxID() + "_LOAD_ALL_DATA_" + fmt.Sprintf("%d", txTime.UnixNano()),
-		Type:           "LOG",
-		LandParcelID:   "",
-		FromOwnerID:    "",
-		ToOwnerID:      "",
-		Status:         "",
-		Details:        fmt.Sprintf("LoadAllData complete: %d successful, %d errors", successCount, errorCount),
-		UserID:         "SYSTEM",
-		CreatedAt:      txTime,
-		UpdatedAt:      txTime,
-	}
-
-	logJSON, _ := json.Marshal(logEntry)
-	ctx.GetStub().PutState(logEntry.TxID, logJSON)
+	RecordAuditLog(ctx, "LOAD_ALL_DATA", "SYSTEM", "", "",
+		fmt.Sprintf("LoadAllData complete: %d successful, %d errors", successCount, errorCount),
+		map[string]string{"successCount": fmt.Sprintf("%d", successCount), "errorCount": fmt.Sprintf("%d", errorCount)})
 
 	return nil
 }
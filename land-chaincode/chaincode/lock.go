@@ -0,0 +1,136 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// getParcelLock đọc khóa của thửa đất (nil nếu không bị khóa)
+func getParcelLock(ctx contractapi.TransactionContextInterface, landID string) (*ParcelLock, error) {
+	key, err := createEntityKey(ctx, ParcelLockKeyPrefix, landID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi đọc khóa thửa đất %s: %v", landID, err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	var lock ParcelLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã khóa thửa đất %s: %v", landID, err)
+	}
+	return &lock, nil
+}
+
+// activeParcelLock trả về khóa của thửa đất nếu giao dịch giữ khóa vẫn đang xử lý
+// Khóa của giao dịch đã kết thúc hoặc không còn tồn tại được xem như đã giải phóng
+func activeParcelLock(ctx contractapi.TransactionContextInterface, landID string) (*ParcelLock, error) {
+	lock, err := getParcelLock(ctx, landID)
+	if err != nil || lock == nil {
+		return nil, err
+	}
+	data, err := GetTransactionState(ctx, lock.TxID)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi đọc giao dịch %s giữ khóa thửa đất %s: %v", lock.TxID, landID, err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	var holder Transaction
+	if err := json.Unmarshal(data, &holder); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã giao dịch %s giữ khóa thửa đất %s: %v", lock.TxID, landID, err)
+	}
+	if !containsString(openTxStatuses, holder.Status) {
+		return nil, nil
+	}
+	return lock, nil
+}
+
+// txLockedParcelIDs các thửa đất mà giao dịch cần khóa
+func txLockedParcelIDs(tx *Transaction) []string {
+	parcelIDs := []string{}
+	for _, landID := range append([]string{tx.LandParcelID}, tx.ParcelIDs...) {
+		if landID != "" && !containsString(parcelIDs, landID) {
+			parcelIDs = append(parcelIDs, landID)
+		}
+	}
+	return parcelIDs
+}
+
+// VerifyParcelNotLocked kiểm tra thửa đất không bị khóa bởi giao dịch đang xử lý
+func VerifyParcelNotLocked(ctx contractapi.TransactionContextInterface, landID string) error {
+	lock, err := activeParcelLock(ctx, landID)
+	if err != nil {
+		return err
+	}
+	if lock != nil {
+		return fmt.Errorf("thửa đất %s đang được xử lý trong giao dịch %s (%s)", landID, lock.TxID, lock.TxType)
+	}
+	return nil
+}
+
+// acquireParcelLocks khóa các thửa đất của giao dịch mới tạo, lỗi nếu thửa đất đang bị giao dịch khác khóa
+func acquireParcelLocks(ctx contractapi.TransactionContextInterface, tx *Transaction) error {
+	for _, landID := range txLockedParcelIDs(tx) {
+		lock, err := activeParcelLock(ctx, landID)
+		if err != nil {
+			return err
+		}
+		if lock != nil && lock.TxID != tx.TxID {
+			return fmt.Errorf("thửa đất %s đang được xử lý trong giao dịch %s (%s)", landID, lock.TxID, lock.TxType)
+		}
+		key, err := createEntityKey(ctx, ParcelLockKeyPrefix, landID)
+		if err != nil {
+			return err
+		}
+		lockJSON, err := json.Marshal(ParcelLock{LandParcelID: landID, TxID: tx.TxID, TxType: tx.Type, LockedBy: tx.UserID, LockedAt: tx.CreatedAt})
+		if err != nil {
+			return fmt.Errorf("lỗi khi mã hóa khóa thửa đất: %v", err)
+		}
+		if err := ctx.GetStub().PutState(key, lockJSON); err != nil {
+			return fmt.Errorf("lỗi khi lưu khóa thửa đất %s: %v", landID, err)
+		}
+	}
+	return nil
+}
+
+// releaseParcelLocks giải phóng các khóa do giao dịch đang giữ
+func releaseParcelLocks(ctx contractapi.TransactionContextInterface, tx *Transaction) error {
+	for _, landID := range txLockedParcelIDs(tx) {
+		lock, err := getParcelLock(ctx, landID)
+		if err != nil {
+			return err
+		}
+		if lock == nil || lock.TxID != tx.TxID {
+			continue
+		}
+		key, err := createEntityKey(ctx, ParcelLockKeyPrefix, landID)
+		if err != nil {
+			return err
+		}
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return fmt.Errorf("lỗi khi giải phóng khóa thửa đất %s: %v", landID, err)
+		}
+	}
+	return nil
+}
+
+// GetParcelLock - Truy vấn giao dịch đang giữ khóa thửa đất (txId trống nếu thửa đất không bị khóa)
+func (s *LandRegistryChaincode) GetParcelLock(ctx contractapi.TransactionContextInterface, landID string) (*ParcelLock, error) {
+	if _, err := s.QueryLandByID(ctx, landID); err != nil {
+		return nil, err
+	}
+	lock, err := activeParcelLock(ctx, landID)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil {
+		return &ParcelLock{LandParcelID: landID}, nil
+	}
+	return lock, nil
+}
@@ -0,0 +1,47 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestActiveParcelLock(t *testing.T) {
+	tests := []struct {
+		name   string
+		holder string
+		locked bool
+		err    bool
+	}{
+		{name: "holder open", holder: `{"txId":"T1","type":"SPLIT","status":"PENDING"}`, locked: true},
+		{name: "holder finished", holder: `{"txId":"T1","type":"SPLIT","status":"APPROVED"}`},
+		{name: "holder missing"},
+		{name: "holder unreadable", holder: `{"txId":`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newMockContext(t)
+			if err := acquireParcelLocks(ctx, &Transaction{TxID: "T1", Type: "SPLIT", LandParcelID: "L1"}); err != nil {
+				t.Fatalf("acquireParcelLocks: %v", err)
+			}
+			if tt.holder != "" {
+				if err := PutTransactionState(ctx, "T1", json.RawMessage(tt.holder)); err != nil {
+					t.Fatalf("put holder: %v", err)
+				}
+			}
+
+			lock, err := activeParcelLock(ctx, "L1")
+			if tt.err {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("activeParcelLock: %v", err)
+			}
+			if (lock != nil) != tt.locked {
+				t.Errorf("lock = %+v, want locked %v", lock, tt.locked)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// adjacencyTolerance sai số (m) khi so khớp ranh giới chung giữa hai thửa đất
+const adjacencyTolerance = 0.05
+
+// minSharedBoundary chiều dài (m) tối thiểu của ranh giới chung để hai thửa được xem là liền kề
+// (hai thửa chỉ chạm nhau tại một điểm không được hợp thửa)
+const minSharedBoundary = 0.1
+
+// normalizeMergeParcelIDs chuẩn hóa danh sách thửa gốc: bỏ khoảng trắng, từ chối mã trống hoặc trùng lặp
+// Hợp thửa cần ít nhất 2 thửa khác nhau (một thửa lặp lại sẽ bị cộng diện tích hai lần)
+func normalizeMergeParcelIDs(parcelIDs []string) ([]string, error) {
+	normalized := []string{}
+	for _, parcelID := range parcelIDs {
+		parcelID = strings.TrimSpace(parcelID)
+		if parcelID == "" {
+			return nil, fmt.Errorf("mã thửa đất hợp nhất không được để trống")
+		}
+		if containsString(normalized, parcelID) {
+			return nil, fmt.Errorf("thửa đất %s bị lặp lại trong danh sách hợp nhất", parcelID)
+		}
+		normalized = append(normalized, parcelID)
+	}
+	if len(normalized) < 2 {
+		return nil, fmt.Errorf("hợp thửa cần ít nhất 2 thửa đất khác nhau, nhận được %v", normalized)
+	}
+	return normalized, nil
+}
+
+// sameParcelSet kiểm tra hai danh sách mã thửa (đã chuẩn hóa) gồm đúng các thửa như nhau
+func sameParcelSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, parcelID := range a {
+		if !containsString(b, parcelID) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseMergeGeometries giải mã danh sách hình học của các thửa gốc (JSON object: mã thửa -> GeoJSON)
+func parseMergeGeometries(geometriesStr string) (map[string]json.RawMessage, error) {
+	geometries := map[string]json.RawMessage{}
+	if strings.TrimSpace(geometriesStr) == "" {
+		return geometries, nil
+	}
+	if err := json.Unmarshal([]byte(geometriesStr), &geometries); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã hình học các thửa đất: %v", err)
+	}
+	return geometries, nil
+}
+
+// verifyMergeGeometries kiểm tra hình học của từng thửa gốc khớp với hình học đã đăng ký
+// và các thửa tạo thành một khối liền kề, trả về khung bao của thửa hợp nhất
+// Diện tích đã được đối chiếu (hoặc Org1 chấp nhận chênh lệch) khi đăng ký hình học nên không kiểm tra lại
+func verifyMergeGeometries(lands []*Land, geometries map[string]json.RawMessage) (*BoundingBox, error) {
+	parsed := make([]*ParcelGeometry, len(lands))
+	var mergedBBox *BoundingBox
+	for i, land := range lands {
+		// Thửa đất phải được Org1 đăng ký hình học (RegisterLandGeometry, UpdateLandParcel) trước khi hợp thửa
+		if land.GeometryHash == "" {
+			return nil, fmt.Errorf("thửa đất %s chưa đăng ký hình học, cần đăng ký hình học trước khi hợp thửa", land.ID)
+		}
+		data, ok := geometries[land.ID]
+		if !ok || len(data) == 0 {
+			return nil, fmt.Errorf("thiếu hình học của thửa đất %s", land.ID)
+		}
+		geometry, err := ParseParcelGeometry(data, land.ID)
+		if err != nil {
+			return nil, fmt.Errorf("hình học thửa đất %s không hợp lệ: %v", land.ID, err)
+		}
+		if land.GeometryHash != geometry.Hash {
+			return nil, fmt.Errorf("hình học của thửa đất %s không khớp với hình học đã đăng ký", land.ID)
+		}
+		parsed[i] = geometry
+		if mergedBBox == nil {
+			mergedBBox = geometry.BBox
+		} else {
+			mergedBBox = bboxUnion(mergedBBox, geometry.BBox)
+		}
+	}
+	if groups := connectedParcelGroups(lands, parsed); len(groups) > 1 {
+		descriptions := []string{}
+		for _, group := range groups {
+			descriptions = append(descriptions, fmt.Sprintf("%v", group))
+		}
+		return nil, fmt.Errorf("các thửa đất không liền kề, tách thành %d khối: %s", len(groups), strings.Join(descriptions, ", "))
+	}
+	return mergedBBox, nil
+}
+
+// bboxesMatch kiểm tra hai khung bao trùng nhau trong sai số tolerance (m) ở cả bốn cạnh
+func bboxesMatch(a, b *BoundingBox, tolerance float64) bool {
+	return math.Abs(a.MinX-b.MinX) <= tolerance && math.Abs(a.MinY-b.MinY) <= tolerance &&
+		math.Abs(a.MaxX-b.MaxX) <= tolerance && math.Abs(a.MaxY-b.MaxY) <= tolerance
+}
+
+// connectedParcelGroups chia các thửa đất thành các khối liền kề (có ranh giới chung)
+func connectedParcelGroups(lands []*Land, geometries []*ParcelGeometry) [][]string {
+	visited := make([]bool, len(lands))
+	groups := [][]string{}
+	for start := range lands {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		queue := []int{start}
+		group := []string{}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			group = append(group, lands[current].ID)
+			for next := range lands {
+				if !visited[next] && parcelsShareBoundary(geometries[current], geometries[next]) {
+					visited[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+		sort.Strings(group)
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// parcelsShareBoundary kiểm tra hai thửa đất có chung một đoạn ranh giới đủ dài
+func parcelsShareBoundary(a, b *ParcelGeometry) bool {
+	expanded := &BoundingBox{
+		MinX: a.BBox.MinX - adjacencyTolerance,
+		MinY: a.BBox.MinY - adjacencyTolerance,
+		MaxX: a.BBox.MaxX + adjacencyTolerance,
+		MaxY: a.BBox.MaxY + adjacencyTolerance,
+	}
+	if !bboxIntersects(expanded, b.BBox) {
+		return false
+	}
+	for _, polygonA := range a.Polygons {
+		for _, ringA := range polygonA {
+			for _, polygonB := range b.Polygons {
+				for _, ringB := range polygonB {
+					for i := 0; i < len(ringA)-1; i++ {
+						for j := 0; j < len(ringB)-1; j++ {
+							if segmentsShareBoundary(ringA[i], ringA[i+1], ringB[j], ringB[j+1]) {
+								return true
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// segmentsShareBoundary kiểm tra hai cạnh nằm trên cùng một đường thẳng (trong sai số) và chồng lên nhau ít nhất minSharedBoundary
+func segmentsShareBoundary(p1, p2, q1, q2 []float64) bool {
+	dx, dy := p2[0]-p1[0], p2[1]-p1[1]
+	length := math.Hypot(dx, dy)
+	if length < minSharedBoundary {
+		return false
+	}
+	ux, uy := dx/length, dy/length
+	// Khoảng cách vuông góc từ hai đầu cạnh q đến đường thẳng chứa cạnh p
+	for _, q := range [][]float64{q1, q2} {
+		if math.Abs((q[0]-p1[0])*uy-(q[1]-p1[1])*ux) > adjacencyTolerance {
+			return false
+		}
+	}
+	// Chiều dài phần chồng lấn khi chiếu cạnh q lên cạnh p
+	t1 := (q1[0]-p1[0])*ux + (q1[1]-p1[1])*uy
+	t2 := (q2[0]-p1[0])*ux + (q2[1]-p1[1])*uy
+	overlap := math.Min(length, math.Max(t1, t2)) - math.Max(0, math.Min(t1, t2))
+	return overlap >= minSharedBoundary
+}
+
+// resolveMergePurpose xác định mục đích sử dụng của thửa hợp nhất
+// Các thửa khác mục đích sử dụng chỉ được hợp khi có mục đích sử dụng đích (hợp thửa kèm chuyển mục đích)
+// Trả về mục đích sử dụng đích và cờ cho biết có thửa phải chuyển mục đích
+func resolveMergePurpose(ctx contractapi.TransactionContextInterface, lands []*Land, targetPurpose string) (string, bool, error) {
+	targetPurpose = strings.TrimSpace(targetPurpose)
+	purposes := []string{}
+	for _, land := range lands {
+		if !containsString(purposes, land.LandUsePurpose) {
+			purposes = append(purposes, land.LandUsePurpose)
+		}
+	}
+	if targetPurpose == "" {
+		if len(purposes) > 1 {
+			return "", false, fmt.Errorf("các thửa đất có mục đích sử dụng khác nhau %v, cần chỉ định mục đích sử dụng của thửa hợp nhất", purposes)
+		}
+		return purposes[0], false, nil
+	}
+	purposeChange := len(purposes) > 1 || purposes[0] != targetPurpose
+	// Mục đích sử dụng mới phải có trong danh mục và đang được sử dụng
+	if purposeChange {
+		if err := ValidateLandUsePurpose(ctx, targetPurpose); err != nil {
+			return "", false, err
+		}
+	}
+	return targetPurpose, purposeChange, nil
+}
+
+// isMergeWithPurposeChange kiểm tra giao dịch hợp thửa có kèm chuyển mục đích sử dụng không
+func isMergeWithPurposeChange(tx *Transaction) bool {
+	return tx.Type == "MERGE" && tx.Payload != nil && tx.Payload.Merge != nil && tx.Payload.Merge.PurposeChange
+}
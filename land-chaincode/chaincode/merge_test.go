@@ -0,0 +1,231 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// rectGeoJSON hình chữ nhật [x0, x0+w] x [y0, y0+h] dạng GeoJSON Polygon
+func rectGeoJSON(x0, y0, w, h float64) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"type":"Polygon","coordinates":[[[%g,%g],[%g,%g],[%g,%g],[%g,%g],[%g,%g]]]}`,
+		x0, y0, x0+w, y0, x0+w, y0+h, x0, y0+h, x0, y0))
+}
+
+// mustParseGeometry phân tích hình học trong test
+func mustParseGeometry(t *testing.T, data json.RawMessage) *ParcelGeometry {
+	t.Helper()
+	geometry, err := ParseParcelGeometry(data, "")
+	if err != nil {
+		t.Fatalf("ParseParcelGeometry(%s): %v", data, err)
+	}
+	return geometry
+}
+
+// registeredLand thửa đất đã đăng ký hình học
+func registeredLand(t *testing.T, id string, data json.RawMessage) *Land {
+	t.Helper()
+	geometry := mustParseGeometry(t, data)
+	return &Land{ID: id, Area: geometry.Area, GeometryHash: geometry.Hash, BBox: geometry.BBox}
+}
+
+func TestParcelsShareBoundary(t *testing.T) {
+	base := rectGeoJSON(0, 0, 10, 10)
+	tests := []struct {
+		name  string
+		other json.RawMessage
+		want  bool
+	}{
+		{"shared edge", rectGeoJSON(10, 0, 10, 10), true},
+		{"partial shared edge", rectGeoJSON(10, 5, 10, 10), true},
+		{"shared edge within tolerance", rectGeoJSON(10.03, 0, 10, 10), true},
+		{"corner only", rectGeoJSON(10, 10, 10, 10), false},
+		{"shared edge too short", rectGeoJSON(10, 9.95, 10, 10), false},
+		{"gap beyond tolerance", rectGeoJSON(10.2, 0, 10, 10), false},
+		{"disjoint", rectGeoJSON(30, 0, 10, 10), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := mustParseGeometry(t, base), mustParseGeometry(t, tt.other)
+			if got := parcelsShareBoundary(a, b); got != tt.want {
+				t.Errorf("parcelsShareBoundary(a, b) = %v, want %v", got, tt.want)
+			}
+			if got := parcelsShareBoundary(b, a); got != tt.want {
+				t.Errorf("parcelsShareBoundary(b, a) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyMergeGeometries(t *testing.T) {
+	shapes := map[string]json.RawMessage{
+		"A": rectGeoJSON(0, 0, 10, 10),
+		"B": rectGeoJSON(10, 0, 10, 10),
+		"C": rectGeoJSON(10, 10, 10, 10),
+		"D": rectGeoJSON(30, 0, 10, 10),
+		"E": rectGeoJSON(20, 0, 10, 10),
+	}
+	tests := []struct {
+		name string
+		ids  []string
+		bbox BoundingBox
+		err  string
+	}{
+		{name: "shared edge", ids: []string{"A", "B"}, bbox: BoundingBox{MinX: 0, MinY: 0, MaxX: 20, MaxY: 10}},
+		{name: "chain of shared edges", ids: []string{"A", "E", "B"}, bbox: BoundingBox{MinX: 0, MinY: 0, MaxX: 30, MaxY: 10}},
+		{name: "corner only", ids: []string{"A", "C"}, err: "không liền kề, tách thành 2 khối"},
+		{name: "disjoint", ids: []string{"A", "B", "D"}, err: "[A B], [D]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lands := []*Land{}
+			for _, id := range tt.ids {
+				lands = append(lands, registeredLand(t, id, shapes[id]))
+			}
+			bbox, err := verifyMergeGeometries(lands, shapes)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyMergeGeometries: %v", err)
+			}
+			if *bbox != tt.bbox {
+				t.Errorf("bbox = %+v, want %+v", *bbox, tt.bbox)
+			}
+		})
+	}
+}
+
+func TestVerifyMergeGeometriesRegisteredShape(t *testing.T) {
+	a := rectGeoJSON(0, 0, 10, 10)
+	b := rectGeoJSON(10, 0, 10, 10)
+
+	unregistered := &Land{ID: "B", Area: 100}
+	if _, err := verifyMergeGeometries([]*Land{registeredLand(t, "A", a), unregistered}, map[string]json.RawMessage{"A": a, "B": b}); err == nil ||
+		!strings.Contains(err.Error(), "chưa đăng ký hình học") {
+		t.Errorf("unregistered parcel: error = %v", err)
+	}
+
+	// Hình học khác hình học đã đăng ký bị từ chối dù vẫn liền kề
+	lands := []*Land{registeredLand(t, "A", a), registeredLand(t, "B", b)}
+	moved := map[string]json.RawMessage{"A": a, "B": rectGeoJSON(10, 0, 10, 12)}
+	if _, err := verifyMergeGeometries(lands, moved); err == nil || !strings.Contains(err.Error(), "không khớp với hình học đã đăng ký") {
+		t.Errorf("changed shape: error = %v", err)
+	}
+
+	if _, err := verifyMergeGeometries(lands, map[string]json.RawMessage{"A": a}); err == nil || !strings.Contains(err.Error(), "thiếu hình học của thửa đất B") {
+		t.Errorf("missing shape: error = %v", err)
+	}
+}
+
+func TestBBoxesMatch(t *testing.T) {
+	merged := &BoundingBox{MinX: 0, MinY: 0, MaxX: 20, MaxY: 10}
+	tests := []struct {
+		name string
+		bbox BoundingBox
+		want bool
+	}{
+		{"equal", BoundingBox{MinX: 0, MinY: 0, MaxX: 20, MaxY: 10}, true},
+		{"within tolerance", BoundingBox{MinX: 0.04, MinY: -0.04, MaxX: 20.04, MaxY: 10}, true},
+		{"larger", BoundingBox{MinX: 0, MinY: 0, MaxX: 25, MaxY: 10}, false},
+		{"shifted", BoundingBox{MinX: 1, MinY: 0, MaxX: 21, MaxY: 10}, false},
+	}
+	for _, tt := range tests {
+		if got := bboxesMatch(&tt.bbox, merged, adjacencyTolerance); got != tt.want {
+			t.Errorf("%s: bboxesMatch = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResolveMergePurpose(t *testing.T) {
+	tests := []struct {
+		name     string
+		purposes []string
+		target   string
+		want     string
+		change   bool
+		err      string
+	}{
+		{name: "same purpose", purposes: []string{"ONT", "ONT"}, want: "ONT"},
+		{name: "same purpose as target", purposes: []string{"ONT", "ONT"}, target: " ONT ", want: "ONT"},
+		{name: "mixed purpose without target", purposes: []string{"ONT", "CLN"}, err: "mục đích sử dụng khác nhau [ONT CLN]"},
+		{name: "mixed purpose with blank target", purposes: []string{"ONT", "CLN", "ONT"}, target: "  ", err: "cần chỉ định mục đích sử dụng"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lands := []*Land{}
+			for i, purpose := range tt.purposes {
+				lands = append(lands, &Land{ID: fmt.Sprintf("L%d", i), LandUsePurpose: purpose})
+			}
+			// Các trường hợp không chuyển mục đích không đọc danh mục nên không cần context
+			got, change, err := resolveMergePurpose(nil, lands, tt.target)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveMergePurpose: %v", err)
+			}
+			if got != tt.want || change != tt.change {
+				t.Errorf("resolveMergePurpose = (%q, %v), want (%q, %v)", got, change, tt.want, tt.change)
+			}
+		})
+	}
+}
+
+func TestNormalizeMergeParcelIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []string
+		want []string
+		err  string
+	}{
+		{name: "trimmed", ids: []string{" A", "B "}, want: []string{"A", "B"}},
+		{name: "duplicate", ids: []string{"A", "A"}, err: "bị lặp lại"},
+		{name: "duplicate after trim", ids: []string{"A", " A ", "B"}, err: "bị lặp lại"},
+		{name: "blank id", ids: []string{"A", " "}, err: "không được để trống"},
+		{name: "single parcel", ids: []string{"A"}, err: "ít nhất 2 thửa"},
+		{name: "empty", err: "ít nhất 2 thửa"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeMergeParcelIDs(tt.ids)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeMergeParcelIDs: %v", err)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameParcelSet(t *testing.T) {
+	requested := []string{"A", "B", "C"}
+	tests := []struct {
+		ids  []string
+		want bool
+	}{
+		{[]string{"C", "A", "B"}, true},
+		{[]string{"A", "B"}, false},
+		{[]string{"A", "B", "D"}, false},
+		{[]string{"A", "B", "C", "D"}, false},
+	}
+	for _, tt := range tests {
+		if got := sameParcelSet(tt.ids, requested); got != tt.want {
+			t.Errorf("sameParcelSet(%v) = %v, want %v", tt.ids, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Trạng thái hợp đồng thế chấp
+const (
+	MortgageStatusPendingConsent = "PENDING_CONSENT" // Chờ chủ sử dụng đồng ý
+	MortgageStatusActive         = "ACTIVE"          // Đang thế chấp
+	MortgageStatusDeclined       = "DECLINED"        // Chủ sử dụng từ chối
+	MortgageStatusReleased       = "RELEASED"        // Đã giải chấp
+)
+
+// LegalStatusMortgaged trạng thái pháp lý của thửa đất khi đang thế chấp
+const LegalStatusMortgaged = "Đang thế chấp"
+
+// getMortgage đọc hợp đồng thế chấp theo mã
+func getMortgage(ctx contractapi.TransactionContextInterface, mortgageID string) (*Mortgage, error) {
+	data, err := GetMortgageState(ctx, mortgageID)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn thế chấp %s: %v", mortgageID, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("thế chấp %s không tồn tại", mortgageID)
+	}
+	var mortgage Mortgage
+	if err := json.Unmarshal(data, &mortgage); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã thế chấp %s: %v", mortgageID, err)
+	}
+	return &mortgage, nil
+}
+
+// putMortgage lưu hợp đồng thế chấp cùng chỉ mục theo thửa đất
+func putMortgage(ctx contractapi.TransactionContextInterface, mortgage *Mortgage) error {
+	mortgageJSON, err := json.Marshal(mortgage)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa thế chấp: %v", err)
+	}
+	if err := PutMortgageState(ctx, mortgage.MortgageID, mortgageJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu thế chấp %s: %v", mortgage.MortgageID, err)
+	}
+	indexKey, err := ctx.GetStub().CreateCompositeKey(MortgageLandIndex, []string{mortgage.LandParcelID, mortgage.MortgageID})
+	if err != nil {
+		return fmt.Errorf("lỗi khi tạo chỉ mục thế chấp %s: %v", mortgage.MortgageID, err)
+	}
+	if err := ctx.GetStub().PutState(indexKey, []byte{0x00}); err != nil {
+		return fmt.Errorf("lỗi khi lưu chỉ mục thế chấp %s: %v", mortgage.MortgageID, err)
+	}
+	return nil
+}
+
+// getMortgagesByLand liệt kê tất cả hợp đồng thế chấp của thửa đất
+func getMortgagesByLand(ctx contractapi.TransactionContextInterface, landParcelID string) ([]*Mortgage, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(MortgageLandIndex, []string{landParcelID})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn thế chấp của thửa đất %s: %v", landParcelID, err)
+	}
+	defer resultsIterator.Close()
+
+	mortgages := []*Mortgage{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi đọc chỉ mục thế chấp: %v", err)
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(response.Key)
+		if err != nil || len(attributes) != 2 {
+			continue
+		}
+		mortgage, err := getMortgage(ctx, attributes[1])
+		if err != nil {
+			return nil, err
+		}
+		mortgages = append(mortgages, mortgage)
+	}
+	return mortgages, nil
+}
+
+// VerifyNoActiveMortgage kiểm tra thửa đất không có hợp đồng thế chấp đang hiệu lực
+func VerifyNoActiveMortgage(ctx contractapi.TransactionContextInterface, landParcelID string) error {
+	mortgages, err := getMortgagesByLand(ctx, landParcelID)
+	if err != nil {
+		return err
+	}
+	for _, mortgage := range mortgages {
+		if mortgage.Status == MortgageStatusActive {
+			return fmt.Errorf("thửa đất %s đang được thế chấp tại %s (hợp đồng %s)", landParcelID, mortgage.LenderName, mortgage.MortgageID)
+		}
+	}
+	return nil
+}
+
+// mortgageConsentParties các chủ sử dụng phải đồng ý thế chấp (hợp đồng cũ chỉ cần chủ sử dụng đại diện)
+func mortgageConsentParties(mortgage *Mortgage) []string {
+	if len(mortgage.RequiredConsents) == 0 {
+		return []string{mortgage.OwnerID}
+	}
+	return mortgage.RequiredConsents
+}
+
+// saveLandLegalStatus cập nhật trạng thái pháp lý của thửa đất
+func saveLandLegalStatus(ctx contractapi.TransactionContextInterface, land *Land, legalStatus string, txTime time.Time) error {
+	land.LegalStatus = legalStatus
+	land.UpdatedAt = txTime
+	landJSON, err := json.Marshal(land)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa thửa đất: %v", err)
+	}
+	if err := PutLandState(ctx, land.ID, landJSON); err != nil {
+		return fmt.Errorf("lỗi khi cập nhật thửa đất: %v", err)
+	}
+	return nil
+}
+
+// isEncumbranceLegalStatus kiểm tra trạng thái pháp lý do tranh chấp hoặc thế chấp quyết định
+func isEncumbranceLegalStatus(legalStatus string) bool {
+	return legalStatus == LegalStatusDisputed || legalStatus == LegalStatusMortgaged
+}
+
+// deriveLandLegalStatus xác định trạng thái pháp lý từ các hồ sơ còn hiệu lực của thửa đất
+// Tranh chấp đang giải quyết ưu tiên hơn thế chấp đang hiệu lực; không còn hồ sơ nào thì trở về trạng thái gốc
+// (trạng thái hiện tại nếu không do tranh chấp, thế chấp quyết định, nếu không thì trạng thái trước hồ sơ gần nhất)
+func deriveLandLegalStatus(land *Land, disputes []*Dispute, mortgages []*Mortgage) string {
+	for _, dispute := range disputes {
+		if dispute.Status == DisputeStatusOpen {
+			return LegalStatusDisputed
+		}
+	}
+	for _, mortgage := range mortgages {
+		if mortgage.Status == MortgageStatusActive {
+			return LegalStatusMortgaged
+		}
+	}
+	if !isEncumbranceLegalStatus(land.LegalStatus) {
+		return land.LegalStatus
+	}
+	baseStatus := ""
+	var baseTime time.Time
+	for _, dispute := range disputes {
+		if !isEncumbranceLegalStatus(dispute.PreviousLegalStatus) && !dispute.CreatedAt.Before(baseTime) {
+			baseStatus, baseTime = dispute.PreviousLegalStatus, dispute.CreatedAt
+		}
+	}
+	for _, mortgage := range mortgages {
+		// PreviousLegalStatus của thế chấp được ghi nhận khi thế chấp có hiệu lực
+		if mortgage.ConfirmedAt.IsZero() || isEncumbranceLegalStatus(mortgage.PreviousLegalStatus) {
+			continue
+		}
+		if !mortgage.ConfirmedAt.Before(baseTime) {
+			baseStatus, baseTime = mortgage.PreviousLegalStatus, mortgage.ConfirmedAt
+		}
+	}
+	return baseStatus
+}
+
+// refreshLandLegalStatus tính lại và lưu trạng thái pháp lý của thửa đất
+// dispute, mortgage (có thể nil) là hồ sơ vừa được ghi trong giao dịch này, vì sổ cái chưa phản ánh lần ghi đó
+func refreshLandLegalStatus(ctx contractapi.TransactionContextInterface, land *Land, dispute *Dispute, mortgage *Mortgage, txTime time.Time) error {
+	disputes, err := getDisputesByLand(ctx, land.ID)
+	if err != nil {
+		return err
+	}
+	mortgages, err := getMortgagesByLand(ctx, land.ID)
+	if err != nil {
+		return err
+	}
+	if dispute != nil {
+		disputes = replaceDispute(disputes, dispute)
+	}
+	if mortgage != nil {
+		mortgages = replaceMortgage(mortgages, mortgage)
+	}
+	return saveLandLegalStatus(ctx, land, deriveLandLegalStatus(land, disputes, mortgages), txTime)
+}
+
+// replaceDispute thay bản ghi cùng mã trong danh sách, thêm mới nếu chưa có
+func replaceDispute(disputes []*Dispute, updated *Dispute) []*Dispute {
+	for i, dispute := range disputes {
+		if dispute.DisputeID == updated.DisputeID {
+			disputes[i] = updated
+			return disputes
+		}
+	}
+	return append(disputes, updated)
+}
+
+// replaceMortgage thay bản ghi cùng mã trong danh sách, thêm mới nếu chưa có
+func replaceMortgage(mortgages []*Mortgage, updated *Mortgage) []*Mortgage {
+	for i, mortgage := range mortgages {
+		if mortgage.MortgageID == updated.MortgageID {
+			mortgages[i] = updated
+			return mortgages
+		}
+	}
+	return append(mortgages, updated)
+}
+
+// ========================================
+// MORTGAGE MANAGEMENT FUNCTIONS
+// ========================================
+
+// RegisterMortgage - Tổ chức tín dụng đăng ký thế chấp thửa đất, chờ chủ sử dụng đồng ý
+func (s *LandRegistryChaincode) RegisterMortgage(ctx contractapi.TransactionContextInterface, landParcelID, lenderName, securedAmount, contractDocID, startDate, endDate string) error {
+	lenderMSP, err := CheckLenderOrganization(ctx)
+	if err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(lenderName) == "" {
+		return fmt.Errorf("tên tổ chức nhận thế chấp không được để trống")
+	}
+	amount, err := parseFloat(securedAmount)
+	if err != nil {
+		return fmt.Errorf("lỗi khi chuyển đổi số tiền bảo đảm: %v", err)
+	}
+	if amount <= 0 {
+		return fmt.Errorf("số tiền bảo đảm phải lớn hơn 0")
+	}
+	start, err := parseDate(startDate)
+	if err != nil {
+		return fmt.Errorf("ngày bắt đầu không hợp lệ: %v", err)
+	}
+	end, err := parseDate(endDate)
+	if err != nil {
+		return fmt.Errorf("ngày kết thúc không hợp lệ: %v", err)
+	}
+	if !end.After(start) {
+		return fmt.Errorf("ngày kết thúc phải sau ngày bắt đầu")
+	}
+	if _, err := GetDocument(ctx, contractDocID); err != nil {
+		return fmt.Errorf("tài liệu hợp đồng thế chấp không hợp lệ: %v", err)
+	}
+
+	land, err := s.QueryLandByID(ctx, landParcelID)
+	if err != nil {
+		return err
+	}
+	if err := VerifyLandLegalStatus(ctx, landParcelID, []string{"Đang tranh chấp", LegalStatusMortgaged}); err != nil {
+		return err
+	}
+	mortgages, err := getMortgagesByLand(ctx, landParcelID)
+	if err != nil {
+		return err
+	}
+	for _, existing := range mortgages {
+		if existing.Status == MortgageStatusActive || existing.Status == MortgageStatusPendingConsent {
+			return fmt.Errorf("thửa đất %s đã có hợp đồng thế chấp %s ở trạng thái %s", landParcelID, existing.MortgageID, existing.Status)
+		}
+	}
+
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+	mortgageID := fmt.Sprintf("THE_CHAP_%d_%s", txTime.Unix(), landParcelID)
+	mortgage := &Mortgage{
+		MortgageID:       mortgageID,
+		LandParcelID:     landParcelID,
+		OwnerID:          land.OwnerID,
+		RequiredConsents: landOwnerIDs(land),
+		LenderMSP:        lenderMSP,
+		LenderName:       lenderName,
+		RegisteredBy:     userID,
+		SecuredAmount:    amount,
+		ContractDocID:    contractDocID,
+		StartDate:        start,
+		EndDate:          end,
+		Status:           MortgageStatusPendingConsent,
+		CreatedAt:        txTime,
+		UpdatedAt:        txTime,
+	}
+	if err := putMortgage(ctx, mortgage); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "REGISTER_MORTGAGE", userID, MortgageKeyPrefix, mortgageID,
+		fmt.Sprintf("Đăng ký thế chấp thửa đất %s tại %s", landParcelID, lenderName),
+		map[string]string{"landParcelId": landParcelID, "lenderMsp": lenderMSP, "securedAmount": securedAmount})
+}
+
+// ConfirmMortgage - Chủ sử dụng đồng ý hoặc từ chối hợp đồng thế chấp
+// Thửa đất đồng sở hữu chỉ được thế chấp khi tất cả đồng sở hữu đồng ý, một người từ chối thì hợp đồng bị từ chối
+func (s *LandRegistryChaincode) ConfirmMortgage(ctx contractapi.TransactionContextInterface, mortgageID, isAcceptedStr, reason string) error {
+	if err := CheckOrganization(ctx, []string{"Org3MSP"}); err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	mortgage, err := getMortgage(ctx, mortgageID)
+	if err != nil {
+		return err
+	}
+	if mortgage.Status != MortgageStatusPendingConsent {
+		return fmt.Errorf("thế chấp %s không ở trạng thái chờ xác nhận (hiện tại: %s)", mortgageID, mortgage.Status)
+	}
+	land, err := s.QueryLandByID(ctx, mortgage.LandParcelID)
+	if err != nil {
+		return err
+	}
+	if !IsLandOwner(land, userID) || !containsString(mortgageConsentParties(mortgage), userID) {
+		return fmt.Errorf("người dùng %s không phải chủ sử dụng thửa đất %s", userID, mortgage.LandParcelID)
+	}
+	if hasGivenConsent(mortgage.Consents, userID) {
+		return fmt.Errorf("người dùng %s đã cho ý kiến về thế chấp %s", userID, mortgageID)
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	action := "CONFIRM_MORTGAGE_ACCEPTED"
+	accepted := isAcceptedStr == "true"
+	mortgage.Consents = append(mortgage.Consents, OwnerConsent{OwnerID: userID, Accepted: accepted, Reason: reason, At: txTime})
+	if accepted {
+		// Chỉ kích hoạt thế chấp khi đồng sở hữu cuối cùng đồng ý
+		if len(pendingConsents(mortgageConsentParties(mortgage), mortgage.Consents)) == 0 {
+			if err := VerifyLandLegalStatus(ctx, land.ID, []string{"Đang tranh chấp", LegalStatusMortgaged}); err != nil {
+				return err
+			}
+			mortgage.Status = MortgageStatusActive
+			mortgage.PreviousLegalStatus = land.LegalStatus
+			mortgage.ConfirmedAt = txTime
+			if err := refreshLandLegalStatus(ctx, land, nil, mortgage, txTime); err != nil {
+				return err
+			}
+		}
+	} else {
+		action = "CONFIRM_MORTGAGE_REJECTED"
+		mortgage.Status = MortgageStatusDeclined
+		mortgage.ReleaseReason = reason
+	}
+	mortgage.UpdatedAt = txTime
+	if err := putMortgage(ctx, mortgage); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, action, userID, MortgageKeyPrefix, mortgageID,
+		fmt.Sprintf("Chủ sử dụng xác nhận thế chấp %s: %s", mortgageID, mortgage.Status),
+		map[string]string{"landParcelId": mortgage.LandParcelID, "status": mortgage.Status})
+}
+
+// ReleaseMortgage - Tổ chức tín dụng giải chấp thửa đất
+func (s *LandRegistryChaincode) ReleaseMortgage(ctx contractapi.TransactionContextInterface, mortgageID, reason string) error {
+	lenderMSP, err := CheckLenderOrganization(ctx)
+	if err != nil {
+		return err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	mortgage, err := getMortgage(ctx, mortgageID)
+	if err != nil {
+		return err
+	}
+	if mortgage.LenderMSP != lenderMSP {
+		return fmt.Errorf("tổ chức %s không phải bên nhận thế chấp %s", lenderMSP, mortgageID)
+	}
+	if mortgage.Status != MortgageStatusActive {
+		return fmt.Errorf("thế chấp %s không ở trạng thái đang hiệu lực (hiện tại: %s)", mortgageID, mortgage.Status)
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	mortgage.Status = MortgageStatusReleased
+	mortgage.ReleasedBy = userID
+	mortgage.ReleasedAt = txTime
+	mortgage.ReleaseReason = reason
+	mortgage.UpdatedAt = txTime
+	if err := putMortgage(ctx, mortgage); err != nil {
+		return err
+	}
+
+	// Trạng thái pháp lý được tính lại từ các tranh chấp, thế chấp còn hiệu lực
+	land, err := s.QueryLandByID(ctx, mortgage.LandParcelID)
+	if err != nil {
+		return err
+	}
+	if err := refreshLandLegalStatus(ctx, land, nil, mortgage, txTime); err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "RELEASE_MORTGAGE", userID, MortgageKeyPrefix, mortgageID,
+		fmt.Sprintf("Giải chấp thửa đất %s", mortgage.LandParcelID),
+		map[string]string{"landParcelId": mortgage.LandParcelID, "reason": reason})
+}
+
+// QueryMortgageByID - Truy vấn hợp đồng thế chấp theo mã
+func (s *LandRegistryChaincode) QueryMortgageByID(ctx contractapi.TransactionContextInterface, mortgageID string) (*Mortgage, error) {
+	mortgage, err := getMortgage(ctx, mortgageID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMortgageAccess(ctx, mortgage); err != nil {
+		return nil, err
+	}
+	return mortgage, nil
+}
+
+// QueryMortgagesByLand - Truy vấn lịch sử thế chấp của thửa đất
+func (s *LandRegistryChaincode) QueryMortgagesByLand(ctx contractapi.TransactionContextInterface, landParcelID string) ([]*Mortgage, error) {
+	mortgages, err := getMortgagesByLand(ctx, landParcelID)
+	if err != nil {
+		return nil, err
+	}
+	result := []*Mortgage{}
+	for _, mortgage := range mortgages {
+		if checkMortgageAccess(ctx, mortgage) == nil {
+			result = append(result, mortgage)
+		}
+	}
+	return result, nil
+}
+
+// checkMortgageAccess Org3 chỉ xem thế chấp của mình, tổ chức tín dụng chỉ xem thế chấp do mình nhận
+func checkMortgageAccess(ctx contractapi.TransactionContextInterface, mortgage *Mortgage) error {
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return err
+	}
+	switch mspID {
+	case "Org1MSP", "Org2MSP":
+		return nil
+	case "Org3MSP":
+		userID, err := GetCallerID(ctx)
+		if err != nil {
+			return err
+		}
+		if !containsString(mortgageConsentParties(mortgage), userID) {
+			return fmt.Errorf("người dùng %s không có quyền xem thế chấp %s", userID, mortgage.MortgageID)
+		}
+		return nil
+	default:
+		if mortgage.LenderMSP != mspID {
+			return fmt.Errorf("tổ chức %s không có quyền xem thế chấp %s", mspID, mortgage.MortgageID)
+		}
+		return nil
+	}
+}
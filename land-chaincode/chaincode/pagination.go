@@ -0,0 +1,264 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Giới hạn kích thước trang cho các truy vấn phân trang
+const (
+	DefaultPageSize int32 = 50
+	MaxPageSize     int32 = 500
+)
+
+// Các trường được phép sắp xếp theo loại thực thể (mỗi trường có index tương ứng trong META-INF)
+// Thửa đất, giao dịch và tài liệu cùng nằm trong một state database nên dùng chung index createdAt, updatedAt
+var (
+	landSortFields        = []string{"area", "createdAt", "updatedAt"}
+	transactionSortFields = []string{"createdAt", "updatedAt"}
+	documentSortFields    = []string{"createdAt", "updatedAt"}
+)
+
+// LandPage một trang kết quả truy vấn thửa đất
+type LandPage struct {
+	Records      []*Land `json:"records"`      // Danh sách thửa đất
+	Bookmark     string  `json:"bookmark"`     // Bookmark cho trang tiếp theo
+	FetchedCount int32   `json:"fetchedCount"` // Số bản ghi trong trang
+}
+
+// TransactionPage một trang kết quả truy vấn giao dịch
+type TransactionPage struct {
+	Records      []*Transaction `json:"records"`      // Danh sách giao dịch
+	Bookmark     string         `json:"bookmark"`     // Bookmark cho trang tiếp theo
+	FetchedCount int32          `json:"fetchedCount"` // Số bản ghi trong trang
+}
+
+// DocumentPage một trang kết quả truy vấn tài liệu
+type DocumentPage struct {
+	Records      []*Document `json:"records"`      // Danh sách tài liệu
+	Bookmark     string      `json:"bookmark"`     // Bookmark cho trang tiếp theo
+	FetchedCount int32       `json:"fetchedCount"` // Số bản ghi trong trang
+}
+
+// normalizePageSize áp dụng kích thước trang mặc định và giới hạn tối đa
+func normalizePageSize(pageSize int32) int32 {
+	if pageSize <= 0 {
+		return DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		return MaxPageSize
+	}
+	return pageSize
+}
+
+// buildPaginatedQueryString tạo chuỗi truy vấn Mango kèm điều kiện sắp xếp
+// sortOrder nhận "asc" hoặc "desc" (mặc định "asc"), sortField phải nằm trong danh sách cho phép
+func buildPaginatedQueryString(selector map[string]interface{}, sortField, sortOrder string, allowedSortFields []string) (string, error) {
+	query := map[string]interface{}{
+		"selector": selector,
+	}
+	if sortField != "" {
+		if !containsString(allowedSortFields, sortField) {
+			return "", fmt.Errorf("không hỗ trợ sắp xếp theo trường %s (cho phép: %v)", sortField, allowedSortFields)
+		}
+		if sortOrder == "" {
+			sortOrder = "asc"
+		}
+		if sortOrder != "asc" && sortOrder != "desc" {
+			return "", fmt.Errorf("thứ tự sắp xếp %s không hợp lệ (asc hoặc desc)", sortOrder)
+		}
+		// CouchDB chỉ dùng được index để sắp xếp khi selector có tham chiếu đến trường sắp xếp
+		if _, exists := selector[sortField]; !exists {
+			selector[sortField] = map[string]interface{}{"$gt": nil}
+		}
+		query["sort"] = []map[string]string{{sortField: sortOrder}}
+	}
+	queryBytes, err := json.Marshal(query)
+	if err != nil {
+		return "", fmt.Errorf("lỗi khi tạo truy vấn: %v", err)
+	}
+	return string(queryBytes), nil
+}
+
+// parseFiltersJSON giải mã bộ lọc dạng JSON (chuỗi rỗng nghĩa là không lọc)
+func parseFiltersJSON(filtersJSON string) (map[string]string, error) {
+	filters := make(map[string]string)
+	if filtersJSON != "" {
+		if err := json.Unmarshal([]byte(filtersJSON), &filters); err != nil {
+			return nil, fmt.Errorf("lỗi khi parse filters: %v", err)
+		}
+	}
+	return filters, nil
+}
+
+// getQueryResultWithPagination thực hiện truy vấn Mango có phân trang
+// Lưu ý: Fabric chỉ hỗ trợ phân trang trong lời gọi truy vấn (evaluate), không dùng được khi submit
+func getQueryResultWithPagination(ctx contractapi.TransactionContextInterface, queryString string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	log.Printf("Paginated Query String: %s (pageSize=%d, bookmark=%s)", queryString, pageSize, bookmark)
+	resultsIterator, metadata, err := ctx.GetStub().GetQueryResultWithPagination(queryString, normalizePageSize(pageSize), bookmark)
+	if err != nil {
+		return nil, nil, fmt.Errorf("lỗi khi thực hiện truy vấn phân trang: %v", err)
+	}
+	return resultsIterator, metadata, nil
+}
+
+// ========================================
+// LAND PAGINATED QUERIES
+// ========================================
+
+// QueryAllLandsPaginated - Truy vấn tất cả thửa đất theo trang (chỉ Org1, Org2)
+func (s *LandRegistryChaincode) QueryAllLandsPaginated(ctx contractapi.TransactionContextInterface, sortField, sortOrder string, pageSize int32, bookmark string) (*LandPage, error) {
+	if err := CheckOrganization(ctx, []string{"Org1MSP", "Org2MSP"}); err != nil {
+		return nil, err
+	}
+	return s.QueryLandsByKeywordPaginated(ctx, "", "", sortField, sortOrder, pageSize, bookmark)
+}
+
+// QueryLandsByKeywordPaginated - Truy vấn thửa đất theo từ khóa với bộ lọc, sắp xếp và phân trang
+func (s *LandRegistryChaincode) QueryLandsByKeywordPaginated(ctx contractapi.TransactionContextInterface, keyword, filtersJSON, sortField, sortOrder string, pageSize int32, bookmark string) (*LandPage, error) {
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := parseFiltersJSON(filtersJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Selector đã giới hạn Org3 chỉ thấy thửa đất của mình
+	selector := buildSelectorForLands(keyword, filters, userID, mspID)
+	queryString, err := buildPaginatedQueryString(selector, sortField, sortOrder, landSortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadata, err := getQueryResultWithPagination(ctx, queryString, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	lands, err := s.collectLands(ctx, resultsIterator)
+	if err != nil {
+		return nil, err
+	}
+	return &LandPage{
+		Records:      lands,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+	}, nil
+}
+
+// ========================================
+// TRANSACTION PAGINATED QUERIES
+// ========================================
+
+// QueryAllTransactionsPaginated - Truy vấn tất cả giao dịch theo trang (chỉ Org1, Org2)
+func (s *LandRegistryChaincode) QueryAllTransactionsPaginated(ctx contractapi.TransactionContextInterface, sortField, sortOrder string, pageSize int32, bookmark string) (*TransactionPage, error) {
+	if err := CheckOrganization(ctx, []string{"Org1MSP", "Org2MSP"}); err != nil {
+		return nil, err
+	}
+	return s.QueryTransactionsByKeywordPaginated(ctx, "", "", sortField, sortOrder, pageSize, bookmark)
+}
+
+// QueryTransactionsByKeywordPaginated - Truy vấn giao dịch theo từ khóa với bộ lọc, sắp xếp và phân trang
+func (s *LandRegistryChaincode) QueryTransactionsByKeywordPaginated(ctx contractapi.TransactionContextInterface, keyword, filtersJSON, sortField, sortOrder string, pageSize int32, bookmark string) (*TransactionPage, error) {
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := parseFiltersJSON(filtersJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Selector đã giới hạn Org3 chỉ thấy giao dịch mình tham gia
+	selector := buildSelectorForTransactions(keyword, filters, userID, mspID)
+	queryString, err := buildPaginatedQueryString(selector, sortField, sortOrder, transactionSortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadata, err := getQueryResultWithPagination(ctx, queryString, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	txs, err := s.collectTransactions(ctx, resultsIterator)
+	if err != nil {
+		return nil, err
+	}
+	return &TransactionPage{
+		Records:      txs,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+	}, nil
+}
+
+// ========================================
+// DOCUMENT PAGINATED QUERIES
+// ========================================
+
+// QueryAllDocumentsPaginated - Truy vấn tất cả tài liệu theo trang (chỉ Org1, Org2)
+func (s *LandRegistryChaincode) QueryAllDocumentsPaginated(ctx contractapi.TransactionContextInterface, sortField, sortOrder string, pageSize int32, bookmark string) (*DocumentPage, error) {
+	if err := CheckOrganization(ctx, []string{"Org1MSP", "Org2MSP"}); err != nil {
+		return nil, err
+	}
+	return s.QueryDocumentsByKeywordPaginated(ctx, "", "", sortField, sortOrder, pageSize, bookmark)
+}
+
+// QueryDocumentsByKeywordPaginated - Truy vấn tài liệu theo từ khóa với bộ lọc, sắp xếp và phân trang
+func (s *LandRegistryChaincode) QueryDocumentsByKeywordPaginated(ctx contractapi.TransactionContextInterface, keyword, filtersJSON, sortField, sortOrder string, pageSize int32, bookmark string) (*DocumentPage, error) {
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := parseFiltersJSON(filtersJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Selector đã giới hạn Org3 chỉ thấy tài liệu mình tải lên
+	selector := buildSelectorForDocuments(keyword, filters, userID, mspID)
+	queryString, err := buildPaginatedQueryString(selector, sortField, sortOrder, documentSortFields)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, metadata, err := getQueryResultWithPagination(ctx, queryString, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	documents, err := s.collectDocuments(ctx, resultsIterator)
+	if err != nil {
+		return nil, err
+	}
+	if documents == nil {
+		documents = []*Document{}
+	}
+	return &DocumentPage{
+		Records:      documents,
+		Bookmark:     metadata.GetBookmark(),
+		FetchedCount: metadata.GetFetchedRecordsCount(),
+	}, nil
+}
@@ -0,0 +1,528 @@
+package chaincode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Private data collection (khai báo trong collections_config.json), chỉ peer Org1/Org2 lưu trữ
+const (
+	PersonalDataCollection       = "personalDataCollection"       // CCCD, họ tên, liên hệ và salt
+	TransactionPrivateCollection = "transactionPrivateCollection" // Thông tin tài chính của giao dịch (giá kê khai)
+)
+
+// personalDataSaltKey khóa lưu salt dùng để băm CCCD trong PersonalDataCollection
+const personalDataSaltKey = "SALT"
+
+// minSaltLength độ dài tối thiểu của salt (bytes)
+const minSaltLength = 32
+
+// subjectRefFields các trường JSON chứa định danh cá nhân, dùng khi chuyển dữ liệu cũ sang mã định danh băm
+var subjectRefFields = map[string]bool{
+	"ownerId": true, "fromOwnerId": true, "toOwnerId": true, "recipientId": true, "userId": true,
+	"uploadedBy": true, "verifiedBy": true, "actorId": true, "holderId": true, "issuedBy": true,
+	"revokedBy": true, "claimantId": true, "respondentId": true, "newOwnerId": true, "openedBy": true,
+	"resolvedBy": true, "updatedBy": true, "registeredBy": true, "confirmedBy": true, "releasedBy": true,
+}
+
+// isRawCCCD kiểm tra chuỗi có phải CCCD chưa băm (12 chữ số)
+func isRawCCCD(s string) bool {
+	return len(s) == 12 && isNumeric(s)
+}
+
+// computeSubjectRef băm CCCD với salt, kết quả là mã định danh công khai của chủ thể dữ liệu
+func computeSubjectRef(salt []byte, cccd string) string {
+	hash := sha256.New()
+	hash.Write(salt)
+	hash.Write([]byte(cccd))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// shortSubjectRef rút gọn mã định danh để ghép vào mã giao dịch
+func shortSubjectRef(ref string) string {
+	if len(ref) > 12 {
+		return ref[:12]
+	}
+	return ref
+}
+
+// getPersonalDataSalt đọc salt từ private data (chỉ đọc được trên peer Org1/Org2)
+func getPersonalDataSalt(ctx contractapi.TransactionContextInterface) ([]byte, error) {
+	salt, err := ctx.GetStub().GetPrivateData(PersonalDataCollection, personalDataSaltKey)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi đọc salt dữ liệu cá nhân: %v", err)
+	}
+	if len(salt) == 0 {
+		return nil, fmt.Errorf("salt dữ liệu cá nhân chưa được khởi tạo (SetPersonalDataSalt)")
+	}
+	return salt, nil
+}
+
+// SubjectRef tính mã định danh băm của CCCD
+func SubjectRef(ctx contractapi.TransactionContextInterface, cccd string) (string, error) {
+	salt, err := getPersonalDataSalt(ctx)
+	if err != nil {
+		return "", err
+	}
+	return computeSubjectRef(salt, cccd), nil
+}
+
+// resolveSubjectRef chuyển CCCD thành mã định danh băm, giữ nguyên nếu đầu vào đã là mã định danh
+func resolveSubjectRef(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	id = strings.TrimSpace(id)
+	if !isRawCCCD(id) {
+		return id, nil
+	}
+	return SubjectRef(ctx, id)
+}
+
+// getTransientValue đọc giá trị từ transient map (không ghi vào block)
+func getTransientValue(ctx contractapi.TransactionContextInterface, key string, required bool) (string, error) {
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", fmt.Errorf("lỗi khi đọc transient map: %v", err)
+	}
+	value := strings.TrimSpace(string(transientMap[key]))
+	if value == "" && required {
+		return "", fmt.Errorf("thiếu trường %s trong transient map", key)
+	}
+	return value, nil
+}
+
+// personalDataKey tạo khóa của hồ sơ cá nhân trong PersonalDataCollection
+func personalDataKey(ctx contractapi.TransactionContextInterface, subjectRef string) (string, error) {
+	return createEntityKey(ctx, PersonalDataKeyPrefix, subjectRef)
+}
+
+// getPersonalData đọc hồ sơ cá nhân (trả về nil nếu chưa có)
+func getPersonalData(ctx contractapi.TransactionContextInterface, subjectRef string) (*PersonalData, error) {
+	key, err := personalDataKey(ctx, subjectRef)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetPrivateData(PersonalDataCollection, key)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi đọc dữ liệu cá nhân: %v", err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	var person PersonalData
+	if err := json.Unmarshal(data, &person); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã dữ liệu cá nhân: %v", err)
+	}
+	return &person, nil
+}
+
+// putPersonalData lưu hồ sơ cá nhân vào PersonalDataCollection
+func putPersonalData(ctx contractapi.TransactionContextInterface, person *PersonalData) error {
+	key, err := personalDataKey(ctx, person.SubjectRef)
+	if err != nil {
+		return err
+	}
+	personJSON, err := json.Marshal(person)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa dữ liệu cá nhân: %v", err)
+	}
+	if err := ctx.GetStub().PutPrivateData(PersonalDataCollection, key, personJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu dữ liệu cá nhân: %v", err)
+	}
+	return nil
+}
+
+// ensurePersonalData trả về mã định danh của CCCD, tạo hồ sơ cá nhân tối thiểu nếu chưa có
+func ensurePersonalData(ctx contractapi.TransactionContextInterface, cccd, updatedBy string, txTime time.Time) (string, error) {
+	if !isRawCCCD(cccd) {
+		return "", fmt.Errorf("CCCD %s không hợp lệ (12 chữ số)", cccd)
+	}
+	ref, err := SubjectRef(ctx, cccd)
+	if err != nil {
+		return "", err
+	}
+	existing, err := getPersonalData(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return ref, nil
+	}
+	person := &PersonalData{SubjectRef: ref, CCCD: cccd, UpdatedBy: updatedBy, UpdatedAt: txTime}
+	if err := putPersonalData(ctx, person); err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// transientSubjectRef đọc CCCD từ transient map và trả về mã định danh băm tương ứng
+func transientSubjectRef(ctx contractapi.TransactionContextInterface, key, updatedBy string, txTime time.Time, required bool) (string, error) {
+	cccd, err := getTransientValue(ctx, key, required)
+	if err != nil || cccd == "" {
+		return "", err
+	}
+	return ensurePersonalData(ctx, cccd, updatedBy, txTime)
+}
+
+// checkSubjectAccess chỉ cho phép Org1, Org2 hoặc chính chủ thể dữ liệu đọc dữ liệu cá nhân
+func checkSubjectAccess(ctx contractapi.TransactionContextInterface, subjectRefs ...string) error {
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return err
+	}
+	if mspID == "Org1MSP" || mspID == "Org2MSP" {
+		return nil
+	}
+	callerRef, err := GetCallerID(ctx)
+	if err != nil {
+		return err
+	}
+	if containsString(subjectRefs, callerRef) {
+		return nil
+	}
+	return fmt.Errorf("người dùng %s không có quyền xem dữ liệu cá nhân này", callerRef)
+}
+
+// transactionPrivateKey tạo khóa thông tin riêng của giao dịch trong TransactionPrivateCollection
+func transactionPrivateKey(ctx contractapi.TransactionContextInterface, txID string) (string, error) {
+	return createEntityKey(ctx, TransactionPrivateKeyPrefix, txID)
+}
+
+// putTransactionPrivateDetails lưu thông tin riêng của giao dịch
+func putTransactionPrivateDetails(ctx contractapi.TransactionContextInterface, details *TransactionPrivateDetails) error {
+	key, err := transactionPrivateKey(ctx, details.TxID)
+	if err != nil {
+		return err
+	}
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa thông tin riêng của giao dịch: %v", err)
+	}
+	if err := ctx.GetStub().PutPrivateData(TransactionPrivateCollection, key, detailsJSON); err != nil {
+		return fmt.Errorf("lỗi khi lưu thông tin riêng của giao dịch %s: %v", details.TxID, err)
+	}
+	return nil
+}
+
+// getTransactionPrivateDetails đọc thông tin riêng của giao dịch (trả về nil nếu không có)
+func getTransactionPrivateDetails(ctx contractapi.TransactionContextInterface, txID string) (*TransactionPrivateDetails, error) {
+	key, err := transactionPrivateKey(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ctx.GetStub().GetPrivateData(TransactionPrivateCollection, key)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi đọc thông tin riêng của giao dịch %s: %v", txID, err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	var details TransactionPrivateDetails
+	if err := json.Unmarshal(data, &details); err != nil {
+		return nil, fmt.Errorf("lỗi khi giải mã thông tin riêng của giao dịch: %v", err)
+	}
+	return &details, nil
+}
+
+// ========================================
+// PERSONAL DATA FUNCTIONS
+// ========================================
+
+// SetPersonalDataSalt - Khởi tạo salt băm CCCD từ transient map (khóa "salt"), chỉ Org1 và chỉ một lần
+// Thay salt sẽ làm mất liên kết giữa dữ liệu công khai và dữ liệu cá nhân nên không được phép
+func (s *LandRegistryChaincode) SetPersonalDataSalt(ctx contractapi.TransactionContextInterface) error {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetPrivateData(PersonalDataCollection, personalDataSaltKey)
+	if err != nil {
+		return fmt.Errorf("lỗi khi đọc salt dữ liệu cá nhân: %v", err)
+	}
+	if len(existing) > 0 {
+		return fmt.Errorf("salt dữ liệu cá nhân đã được khởi tạo")
+	}
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("lỗi khi đọc transient map: %v", err)
+	}
+	salt := transientMap["salt"]
+	if len(salt) < minSaltLength {
+		return fmt.Errorf("salt phải có ít nhất %d bytes", minSaltLength)
+	}
+	if err := ctx.GetStub().PutPrivateData(PersonalDataCollection, personalDataSaltKey, salt); err != nil {
+		return fmt.Errorf("lỗi khi lưu salt dữ liệu cá nhân: %v", err)
+	}
+
+	// Salt vừa ghi chưa đọc lại được trong cùng giao dịch, tính mã định danh trực tiếp
+	cccd, err := GetCallerCCCD(ctx)
+	if err != nil {
+		return err
+	}
+	return RecordAuditLog(ctx, "SET_PERSONAL_DATA_SALT", computeSubjectRef(salt, cccd), "", "", "Khởi tạo salt dữ liệu cá nhân", nil)
+}
+
+// RegisterPersonalData - Đăng ký/cập nhật hồ sơ cá nhân từ transient map (khóa "personalData")
+// Org1, Org2 được đăng ký cho mọi người; công dân chỉ được cập nhật hồ sơ của chính mình
+func (s *LandRegistryChaincode) RegisterPersonalData(ctx contractapi.TransactionContextInterface) (string, error) {
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return "", err
+	}
+	value, err := getTransientValue(ctx, "personalData", true)
+	if err != nil {
+		return "", err
+	}
+	var input PersonalData
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&input); err != nil {
+		return "", fmt.Errorf("lỗi khi giải mã dữ liệu cá nhân: %v", err)
+	}
+	if !isRawCCCD(input.CCCD) {
+		return "", fmt.Errorf("CCCD %s không hợp lệ (12 chữ số)", input.CCCD)
+	}
+	ref, err := SubjectRef(ctx, input.CCCD)
+	if err != nil {
+		return "", err
+	}
+	if err := checkSubjectAccess(ctx, ref); err != nil {
+		return "", err
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return "", fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	person := &PersonalData{
+		SubjectRef: ref,
+		CCCD:       input.CCCD,
+		FullName:   strings.TrimSpace(input.FullName),
+		Phone:      strings.TrimSpace(input.Phone),
+		Email:      strings.TrimSpace(input.Email),
+		Address:    strings.TrimSpace(input.Address),
+		UpdatedBy:  userID,
+		UpdatedAt:  txTime,
+	}
+	if err := putPersonalData(ctx, person); err != nil {
+		return "", err
+	}
+	if err := RecordAuditLog(ctx, "REGISTER_PERSONAL_DATA", userID, PersonalDataKeyPrefix, ref, "Cập nhật dữ liệu cá nhân", nil); err != nil {
+		return "", err
+	}
+	return ref, nil
+}
+
+// GetPersonalData - Truy vấn hồ sơ cá nhân theo mã định danh hoặc CCCD (Org1, Org2 hoặc chính chủ)
+func (s *LandRegistryChaincode) GetPersonalData(ctx contractapi.TransactionContextInterface, subjectRef string) (*PersonalData, error) {
+	ref, err := resolveSubjectRef(ctx, subjectRef)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSubjectAccess(ctx, ref); err != nil {
+		return nil, err
+	}
+	person, err := getPersonalData(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	if person == nil {
+		return nil, fmt.Errorf("không tìm thấy dữ liệu cá nhân của %s", ref)
+	}
+	return person, nil
+}
+
+// GetMySubjectRef - Truy vấn mã định danh băm của người gọi
+func (s *LandRegistryChaincode) GetMySubjectRef(ctx contractapi.TransactionContextInterface) (string, error) {
+	return GetCallerID(ctx)
+}
+
+// GetTransactionPrivateDetails - Truy vấn thông tin riêng của giao dịch (Org1, Org2 hoặc các bên của giao dịch)
+func (s *LandRegistryChaincode) GetTransactionPrivateDetails(ctx contractapi.TransactionContextInterface, txID string) (*TransactionPrivateDetails, error) {
+	tx, err := GetTransaction(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSubjectAccess(ctx, tx.FromOwnerID, tx.ToOwnerID); err != nil {
+		return nil, err
+	}
+	details, err := getTransactionPrivateDetails(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	if details == nil {
+		return nil, fmt.Errorf("giao dịch %s không có thông tin riêng", txID)
+	}
+	return details, nil
+}
+
+// PersonalDataMigrationResult kết quả của một lô chuyển CCCD sang mã định danh băm
+type PersonalDataMigrationResult struct {
+	Namespace string `json:"namespace"` // Namespace được xử lý
+	Scanned   int    `json:"scanned"`   // Số bản ghi đã đọc trong lô này
+	Migrated  int    `json:"migrated"`  // Số bản ghi đã cập nhật trong lô này
+	NextKey   string `json:"nextKey"`   // Mã thực thể bắt đầu cho lô tiếp theo (rỗng nếu đã xong)
+	Done      bool   `json:"done"`      // Đã xử lý hết namespace
+}
+
+// MigratePersonalData - Chuyển CCCD còn lưu công khai trong một namespace sang mã định danh băm (chỉ Org1)
+// Giá kê khai của giao dịch chuyển nhượng được chuyển sang TransactionPrivateCollection
+// Xử lý tối đa batchSize bản ghi mỗi lần (0 = mặc định 500), gọi lặp lại với startKey = nextKey cho đến khi done = true
+// (truy vấn phân trang của Fabric chỉ dùng được trong giao dịch chỉ đọc nên lô sau duyệt lại từ đầu namespace đến startKey)
+func (s *LandRegistryChaincode) MigratePersonalData(ctx contractapi.TransactionContextInterface, namespace, startKey string, batchSize int) (*PersonalDataMigrationResult, error) {
+	if err := CheckOrganization(ctx, []string{"Org1MSP"}); err != nil {
+		return nil, err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch namespace {
+	case LandKeyPrefix, DocumentKeyPrefix, DocumentVersionKeyPrefix, TransactionKeyPrefix, CertificateKeyPrefix,
+		MortgageKeyPrefix, DisputeKeyPrefix, PurposeKeyPrefix, DossierPolicyKeyPrefix, ConfigKeyPrefix, AuditLogKeyPrefix:
+	default:
+		return nil, fmt.Errorf("namespace %s không được hỗ trợ", namespace)
+	}
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	startCompositeKey := ""
+	if strings.TrimSpace(startKey) != "" {
+		if startCompositeKey, err = createEntityKey(ctx, namespace, startKey); err != nil {
+			return nil, err
+		}
+	}
+	txTime, err := GetTxTimestampAsTime(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi lấy timestamp: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(namespace, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn namespace %s: %v", namespace, err)
+	}
+	defer resultsIterator.Close()
+
+	result := &PersonalDataMigrationResult{Namespace: namespace, Done: true}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi đọc bản ghi: %v", err)
+		}
+		if response.Key < startCompositeKey {
+			continue
+		}
+		if result.Scanned >= batchSize {
+			_, attributes, err := ctx.GetStub().SplitCompositeKey(response.Key)
+			if err != nil || len(attributes) == 0 {
+				return nil, fmt.Errorf("lỗi khi tách khóa %s: %v", response.Key, err)
+			}
+			result.NextKey = attributes[0]
+			result.Done = false
+			break
+		}
+		result.Scanned++
+
+		var record map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(response.Value))
+		decoder.UseNumber()
+		if err := decoder.Decode(&record); err != nil {
+			// Bản ghi không phải JSON object (ví dụ chỉ mục) thì bỏ qua
+			continue
+		}
+		changed, err := pseudonymizeRecord(ctx, record, userID, txTime)
+		if err != nil {
+			return nil, err
+		}
+		if namespace == TransactionKeyPrefix {
+			moved, err := moveDeclaredPriceToPrivate(ctx, record, userID, txTime)
+			if err != nil {
+				return nil, err
+			}
+			changed = changed || moved
+		}
+		if !changed {
+			continue
+		}
+		recordJSON, err := json.Marshal(record)
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi mã hóa bản ghi: %v", err)
+		}
+		if err := ctx.GetStub().PutState(response.Key, recordJSON); err != nil {
+			return nil, fmt.Errorf("lỗi khi cập nhật bản ghi: %v", err)
+		}
+		result.Migrated++
+	}
+
+	if result.Migrated > 0 {
+		if err := RecordAuditLog(ctx, "MIGRATE_PERSONAL_DATA", userID, namespace, "",
+			fmt.Sprintf("Chuyển %d bản ghi trong %s sang mã định danh băm", result.Migrated, namespace), map[string]string{"startKey": startKey, "nextKey": result.NextKey}); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// pseudonymizeRecord thay CCCD trong các trường định danh (kể cả lồng nhau) bằng mã định danh băm
+func pseudonymizeRecord(ctx contractapi.TransactionContextInterface, value interface{}, updatedBy string, txTime time.Time) (bool, error) {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, child := range v {
+			if str, ok := child.(string); ok && subjectRefFields[field] && isRawCCCD(str) {
+				ref, err := ensurePersonalData(ctx, str, updatedBy, txTime)
+				if err != nil {
+					return changed, err
+				}
+				v[field] = ref
+				changed = true
+				continue
+			}
+			childChanged, err := pseudonymizeRecord(ctx, child, updatedBy, txTime)
+			if err != nil {
+				return changed, err
+			}
+			changed = changed || childChanged
+		}
+	case []interface{}:
+		for _, child := range v {
+			childChanged, err := pseudonymizeRecord(ctx, child, updatedBy, txTime)
+			if err != nil {
+				return changed, err
+			}
+			changed = changed || childChanged
+		}
+	}
+	return changed, nil
+}
+
+// moveDeclaredPriceToPrivate chuyển giá kê khai công khai của giao dịch chuyển nhượng sang private data
+func moveDeclaredPriceToPrivate(ctx contractapi.TransactionContextInterface, record map[string]interface{}, updatedBy string, txTime time.Time) (bool, error) {
+	payload, ok := record["payload"].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+	transfer, ok := payload["transfer"].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+	price, ok := transfer["declaredPrice"].(json.Number)
+	if !ok {
+		return false, nil
+	}
+	delete(transfer, "declaredPrice")
+	priceFloat, err := price.Float64()
+	if err != nil || priceFloat == 0 {
+		return true, nil
+	}
+	txID, _ := record["txId"].(string)
+	details := &TransactionPrivateDetails{TxID: txID, DeclaredPrice: priceFloat, UpdatedBy: updatedBy, UpdatedAt: txTime}
+	if err := putTransactionPrivateDetails(ctx, details); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
@@ -22,7 +23,7 @@ func (s *LandRegistryChaincode) QueryLandByID(ctx contractapi.TransactionContext
 		return nil, err
 	}
 
-	data, err := ctx.GetStub().GetState(landID)
+	data, err := GetLandState(ctx, landID)
 	if err != nil {
 		return nil, fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", landID, err)
 	}
@@ -44,7 +45,7 @@ func (s *LandRegistryChaincode) QueryLandByID(ctx contractapi.TransactionContext
 	if err != nil {
 		return nil, err
 	}
-	if mspID == "Org3MSP" && land.OwnerID != userID {
+	if mspID == "Org3MSP" && !IsLandOwner(&land, userID) {
 		return nil, fmt.Errorf("người dùng %s không có quyền truy cập thửa đất %s", userID, landID)
 	}
 
@@ -68,14 +69,26 @@ func (s *LandRegistryChaincode) QueryLandsByOwner(ctx contractapi.TransactionCon
 	if err != nil {
 		return nil, err
 	}
+	// Chấp nhận CCCD hoặc mã định danh, so khớp theo mã định danh
+	ownerID, err = resolveSubjectRef(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Org3MSP chỉ có thể truy vấn thửa đất của chính họ
 	if mspID == "Org3MSP" && ownerID != userID {
 		return nil, fmt.Errorf("người dùng %s không có quyền truy vấn thửa đất của %s", userID, ownerID)
 	}
 
-	// Tạo truy vấn tìm kiếm theo ownerId
-	queryString := fmt.Sprintf(`{"selector":{"ownerId":"%s","id":{"$exists":true},"landUsePurpose":{"$exists":true}}}`, ownerID)
+	// Tạo truy vấn tìm kiếm theo chủ sử dụng hoặc đồng sở hữu
+	selector := landOwnerSelector(ownerID)
+	selector["id"] = map[string]interface{}{"$exists": true}
+	selector["landUsePurpose"] = map[string]interface{}{"$exists": true}
+	queryBytes, err := json.Marshal(map[string]interface{}{"selector": selector})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi tạo truy vấn: %v", err)
+	}
+	queryString := string(queryBytes)
 
 	lands, err := s.getQueryResultForLands(ctx, queryString)
 	if err != nil {
@@ -83,7 +96,7 @@ func (s *LandRegistryChaincode) QueryLandsByOwner(ctx contractapi.TransactionCon
 	}
 
 	logDetails := fmt.Sprintf("Truy vấn thửa đất theo chủ sử dụng %s", ownerID)
-	if err := RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "QUERY_LANDS_BY_OWNER", userID, logDetails); err != nil {
+	if err := RecordAuditLog(ctx, "QUERY_LANDS_BY_OWNER", userID, "", "", logDetails, map[string]string{"ownerId": ownerID}); err != nil {
 		fmt.Printf("Lỗi khi ghi log giao dịch: %v\n", err)
 	}
 
@@ -124,7 +137,7 @@ func (s *LandRegistryChaincode) QueryLandsByKeyword(ctx contractapi.TransactionC
 	if mspID == "Org3MSP" {
 		filteredLands := []*Land{}
 		for _, land := range lands {
-			if land.OwnerID == userID {
+			if IsLandOwner(land, userID) {
 				filteredLands = append(filteredLands, land)
 			}
 		}
@@ -141,8 +154,13 @@ func (s *LandRegistryChaincode) QueryAllLands(ctx contractapi.TransactionContext
 		return nil, err
 	}
 
-	queryString := `{"selector":{"id":{"$exists":true},"landUsePurpose":{"$exists":true}}}`
-	lands, err := s.getQueryResultForLands(ctx, queryString)
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(LandKeyPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn thửa đất: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	lands, err := s.collectLands(ctx, resultsIterator)
 	if err != nil {
 		return nil, err
 	}
@@ -169,12 +187,16 @@ func (s *LandRegistryChaincode) GetLandHistory(ctx contractapi.TransactionContex
 		if err != nil {
 			return nil, fmt.Errorf("lỗi khi kiểm tra quyền sở hữu thửa đất %s: %v", landID, err)
 		}
-		if land.OwnerID != userID {
+		if !IsLandOwner(land, userID) {
 			return nil, fmt.Errorf("người dùng %s không có quyền truy cập lịch sử thửa đất %s", userID, landID)
 		}
 	}
 
-	resultsIterator, err := ctx.GetStub().GetHistoryForKey(landID)
+	landKey, err := LandKey(ctx, landID)
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(landKey)
 	if err != nil {
 		return nil, fmt.Errorf("lỗi khi truy vấn lịch sử thửa đất %s: %v", landID, err)
 	}
@@ -211,7 +233,7 @@ func (s *LandRegistryChaincode) GetLandHistory(ctx contractapi.TransactionContex
 	}
 
 	logDetails := fmt.Sprintf("Truy vấn lịch sử thửa đất %s", landID)
-	if err := RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "GET_LAND_HISTORY", userID, logDetails); err != nil {
+	if err := RecordAuditLog(ctx, "GET_LAND_HISTORY", userID, LandKeyPrefix, landID, logDetails, nil); err != nil {
 		fmt.Printf("Lỗi khi ghi log giao dịch: %v\n", err)
 	}
 
@@ -224,7 +246,7 @@ func (s *LandRegistryChaincode) GetLandHistory(ctx contractapi.TransactionContex
 
 // GetDocument - Lấy thông tin tài liệu theo ID
 func (s *LandRegistryChaincode) GetDocument(ctx contractapi.TransactionContextInterface, docID string) (*Document, error) {
-	data, err := ctx.GetStub().GetState(docID)
+	data, err := GetDocumentState(ctx, docID)
 	if err != nil {
 		return nil, fmt.Errorf("lỗi khi truy vấn tài liệu %s: %v", docID, err)
 	}
@@ -468,8 +490,13 @@ func (s *LandRegistryChaincode) QueryAllDocuments(ctx contractapi.TransactionCon
 		return nil, err
 	}
 
-	queryString := `{"selector":{"docID":{"$exists":true,"$ne":""},"type":{"$exists":true,"$nin":["","LOG"]},"ipfsHash":{"$exists":true,"$ne":""}}}`
-	documents, err := s.getQueryResultForDocuments(ctx, queryString)
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(DocumentKeyPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn tài liệu: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	documents, err := s.collectDocuments(ctx, resultsIterator)
 	if err != nil {
 		return nil, err
 	}
@@ -502,7 +529,11 @@ func (s *LandRegistryChaincode) QueryDocumentHistory(ctx contractapi.Transaction
     }
 
     // Lấy lịch sử thay đổi của tài liệu sử dụng GetHistoryForKey
-    resultsIterator, err := ctx.GetStub().GetHistoryForKey(docID)
+    docKey, err := DocumentKey(ctx, docID)
+    if err != nil {
+        return nil, err
+    }
+    resultsIterator, err := ctx.GetStub().GetHistoryForKey(docKey)
     if err != nil {
         return nil, fmt.Errorf("lỗi khi lấy lịch sử tài liệu: %v", err)
     }
@@ -558,7 +589,7 @@ func (s *LandRegistryChaincode) QueryDocumentHistory(ctx contractapi.Transaction
 
     // Ghi log giao dịch
     logDetails := fmt.Sprintf("Truy vấn lịch sử tài liệu %s", docID)
-    if err := RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "QUERY_DOCUMENT_HISTORY", userID, logDetails); err != nil {
+    if err := RecordAuditLog(ctx, "QUERY_DOCUMENT_HISTORY", userID, DocumentKeyPrefix, docID, logDetails, nil); err != nil {
         log.Printf("Lỗi khi ghi log giao dịch: %v", err)
     }
 
@@ -587,7 +618,7 @@ func (s *LandRegistryChaincode) QueryTransactionByID(ctx contractapi.Transaction
 	if err != nil {
 		return nil, err
 	}
-	if mspID == "Org3MSP" && tx.FromOwnerID != userID && tx.ToOwnerID != userID {
+	if mspID == "Org3MSP" && !isTransactionParty(tx, userID) {
 		return nil, fmt.Errorf("người dùng %s không có quyền truy cập giao dịch %s", userID, txID)
 	}
 
@@ -611,14 +642,19 @@ func (s *LandRegistryChaincode) QueryTransactionsByOwner(ctx contractapi.Transac
 	if err != nil {
 		return nil, err
 	}
+	// Chấp nhận CCCD hoặc mã định danh, so khớp theo mã định danh
+	ownerID, err = resolveSubjectRef(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Org3MSP chỉ có thể truy vấn giao dịch của chính họ
 	if mspID == "Org3MSP" && ownerID != userID {
 		return nil, fmt.Errorf("người dùng %s không có quyền truy vấn giao dịch của %s", userID, ownerID)
 	}
 
-	// Tạo truy vấn tìm kiếm giao dịch mà user tham gia (loại bỏ LOG entries)
-	queryString := fmt.Sprintf(`{"selector":{"$or":[{"fromOwnerId":"%s"},{"toOwnerId":"%s"}],"txId":{"$exists":true},"type":{"$exists":true,"$ne":"LOG"}}}`, ownerID, ownerID)
+	// Tạo truy vấn tìm kiếm giao dịch mà user tham gia
+	queryString := fmt.Sprintf(`{"selector":{"$or":[{"fromOwnerId":"%s"},{"toOwnerId":"%s"},{"requiredConsents":{"$elemMatch":{"$eq":"%s"}}}],"txId":{"$exists":true},"type":{"$exists":true}}}`, ownerID, ownerID, ownerID)
 
 	transactions, err := s.getQueryResultForTransactions(ctx, queryString)
 	if err != nil {
@@ -626,7 +662,7 @@ func (s *LandRegistryChaincode) QueryTransactionsByOwner(ctx contractapi.Transac
 	}
 
 	logDetails := fmt.Sprintf("Truy vấn giao dịch theo chủ sử dụng %s", ownerID)
-	if err := RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "QUERY_TRANSACTIONS_BY_OWNER", userID, logDetails); err != nil {
+	if err := RecordAuditLog(ctx, "QUERY_TRANSACTIONS_BY_OWNER", userID, "", "", logDetails, map[string]string{"ownerId": ownerID}); err != nil {
 		fmt.Printf("Lỗi khi ghi log giao dịch: %v\n", err)
 	}
 
@@ -646,8 +682,8 @@ func (s *LandRegistryChaincode) QueryTransactionsByStatus(ctx contractapi.Transa
 		return nil, err
 	}
 
-	// Tạo truy vấn tìm kiếm theo trạng thái (loại bỏ LOG entries)
-	queryString := fmt.Sprintf(`{"selector":{"status":"%s","txId":{"$exists":true},"type":{"$exists":true,"$ne":"LOG"}}}`, status)
+	// Tạo truy vấn tìm kiếm theo trạng thái
+	queryString := fmt.Sprintf(`{"selector":{"status":"%s","txId":{"$exists":true},"type":{"$exists":true}}}`, status)
 
 	transactions, err := s.getQueryResultForTransactions(ctx, queryString)
 	if err != nil {
@@ -655,7 +691,7 @@ func (s *LandRegistryChaincode) QueryTransactionsByStatus(ctx contractapi.Transa
 	}
 
 	logDetails := fmt.Sprintf("Truy vấn giao dịch theo trạng thái %s", status)
-	if err := RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "QUERY_TRANSACTIONS_BY_STATUS", userID, logDetails); err != nil {
+	if err := RecordAuditLog(ctx, "QUERY_TRANSACTIONS_BY_STATUS", userID, "", "", logDetails, map[string]string{"status": status}); err != nil {
 		fmt.Printf("Lỗi khi ghi log giao dịch: %v\n", err)
 	}
 
@@ -695,7 +731,7 @@ func (s *LandRegistryChaincode) QueryTransactionsByKeyword(ctx contractapi.Trans
 	if mspID == "Org3MSP" {
 		filteredTxs := []*Transaction{}
 		for _, tx := range txs {
-			if tx.FromOwnerID == userID || tx.ToOwnerID == userID {
+			if isTransactionParty(tx, userID) {
 				filteredTxs = append(filteredTxs, tx)
 			}
 		}
@@ -712,8 +748,13 @@ func (s *LandRegistryChaincode) QueryAllTransactions(ctx contractapi.Transaction
 		return nil, err
 	}
 
-	queryString := `{"selector":{"txId":{"$exists":true},"type":{"$exists":true,"$ne":"LOG"}}}`
-	transactions, err := s.getQueryResultForTransactions(ctx, queryString)
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(TransactionKeyPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn giao dịch: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	transactions, err := s.collectTransactions(ctx, resultsIterator)
 	if err != nil {
 		return nil, err
 	}
@@ -740,12 +781,16 @@ func (s *LandRegistryChaincode) GetTransactionHistory(ctx contractapi.Transactio
 		if err != nil {
 			return nil, fmt.Errorf("lỗi khi kiểm tra quyền truy cập giao dịch %s: %v", txID, err)
 		}
-		if tx.FromOwnerID != userID && tx.ToOwnerID != userID {
+		if !isTransactionParty(tx, userID) {
 			return nil, fmt.Errorf("người dùng %s không có quyền truy cập lịch sử giao dịch %s", userID, txID)
 		}
 	}
 
-	resultsIterator, err := ctx.GetStub().GetHistoryForKey(txID)
+	txKey, err := TransactionKey(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(txKey)
 	if err != nil {
 		return nil, fmt.Errorf("lỗi khi truy vấn lịch sử giao dịch %s: %v", txID, err)
 	}
@@ -782,7 +827,7 @@ func (s *LandRegistryChaincode) GetTransactionHistory(ctx contractapi.Transactio
 	}
 
 	logDetails := fmt.Sprintf("Truy vấn lịch sử giao dịch %s", txID)
-	if err := RecordTransactionLog(ctx, ctx.GetStub().GetTxID(), "GET_TRANSACTION_HISTORY", userID, logDetails); err != nil {
+	if err := RecordAuditLog(ctx, "GET_TRANSACTION_HISTORY", userID, TransactionKeyPrefix, txID, logDetails, nil); err != nil {
 		fmt.Printf("Lỗi khi ghi log giao dịch: %v\n", err)
 	}
 
@@ -846,6 +891,14 @@ func (s *LandRegistryChaincode) QueryForKeyword(ctx contractapi.TransactionConte
 
 // buildQueryStringForLands - Tạo chuỗi truy vấn Mango cho thửa đất
 func buildQueryStringForLands(keyword string, filters map[string]string, userID, mspID string) string {
+	queryBytes, _ := json.Marshal(map[string]interface{}{
+		"selector": buildSelectorForLands(keyword, filters, userID, mspID),
+	})
+	return string(queryBytes)
+}
+
+// buildSelectorForLands - Tạo selector Mango cho thửa đất theo từ khóa, bộ lọc và quyền truy cập
+func buildSelectorForLands(keyword string, filters map[string]string, userID, mspID string) map[string]interface{} {
 	selector := map[string]interface{}{}
 
 	// Thửa đất có các trường id, ownerId, landUsePurpose. legalStatus
@@ -882,24 +935,38 @@ func buildQueryStringForLands(keyword string, filters map[string]string, userID,
 		selector[key] = value
 	}
 
-	// Áp dụng kiểm soát truy cập theo tổ chức
+	// Áp dụng kiểm soát truy cập theo tổ chức (chủ sử dụng hoặc đồng sở hữu)
 	if mspID == "Org3MSP" {
-		selector["ownerId"] = userID
+		ownerCondition := landOwnerSelector(userID)
+		if existingOr, hasOr := selector["$or"]; hasOr {
+			selector["$and"] = []map[string]interface{}{
+				{"$or": existingOr},
+				ownerCondition,
+			}
+			delete(selector, "$or")
+		} else {
+			selector["$or"] = ownerCondition["$or"]
+		}
 	}
 
+	return selector
+}
+
+// buildQueryStringForTransactions - Tạo chuỗi truy vấn Mango cho giao dịch
+func buildQueryStringForTransactions(keyword string, filters map[string]string, userID, mspID string) string {
 	queryBytes, _ := json.Marshal(map[string]interface{}{
-		"selector": selector,
+		"selector": buildSelectorForTransactions(keyword, filters, userID, mspID),
 	})
 	return string(queryBytes)
 }
 
-// buildQueryStringForTransactions - Tạo chuỗi truy vấn Mango cho giao dịch
-func buildQueryStringForTransactions(keyword string, filters map[string]string, userID, mspID string) string {
+// buildSelectorForTransactions - Tạo selector Mango cho giao dịch theo từ khóa, bộ lọc và quyền truy cập
+func buildSelectorForTransactions(keyword string, filters map[string]string, userID, mspID string) map[string]interface{} {
 	selector := map[string]interface{}{}
 
-	// Giao dịch có các trường txId, type (loại bỏ LOG entries)
+	// Giao dịch có các trường txId, type
 	selector["txId"] = map[string]interface{}{"$exists": true}
-	selector["type"] = map[string]interface{}{"$exists": true, "$ne": "LOG"}
+	selector["type"] = map[string]interface{}{"$exists": true}
 
 	if keyword != "" {
 		// Create search conditions - use contains-style regex with escaping
@@ -911,7 +978,7 @@ func buildQueryStringForTransactions(keyword string, filters map[string]string,
 			{"details": map[string]interface{}{"$regex": pattern}},
 			{"fromOwnerId": map[string]interface{}{"$regex": pattern}},
 			{"toOwnerId": map[string]interface{}{"$regex": pattern}},
-			{"userID": map[string]interface{}{"$regex": pattern}},
+			{"userId": map[string]interface{}{"$regex": pattern}},
 		}
 	}
 
@@ -929,6 +996,7 @@ func buildQueryStringForTransactions(keyword string, filters map[string]string,
 				{"$or": []map[string]interface{}{
 					{"fromOwnerId": userID},
 					{"toOwnerId": userID},
+					{"requiredConsents": map[string]interface{}{"$elemMatch": map[string]interface{}{"$eq": userID}}},
 				}},
 			}
 			delete(selector, "$or")
@@ -936,23 +1004,29 @@ func buildQueryStringForTransactions(keyword string, filters map[string]string,
 			selector["$or"] = []map[string]interface{}{
 				{"fromOwnerId": userID},
 				{"toOwnerId": userID},
+				{"requiredConsents": map[string]interface{}{"$elemMatch": map[string]interface{}{"$eq": userID}}},
 			}
 		}
 	}
 
+	return selector
+}
+
+// buildQueryStringForDocuments - Tạo chuỗi truy vấn Mango cho tài liệu
+func buildQueryStringForDocuments(keyword string, filters map[string]string, userID, mspID string) string {
 	queryBytes, _ := json.Marshal(map[string]interface{}{
-		"selector": selector,
+		"selector": buildSelectorForDocuments(keyword, filters, userID, mspID),
 	})
 	return string(queryBytes)
 }
 
-// buildQueryStringForDocuments - Tạo query string cho tài liệu
-func buildQueryStringForDocuments(keyword string, filters map[string]string, userID, mspID string) string {
+// buildSelectorForDocuments - Tạo selector Mango cho tài liệu theo từ khóa, bộ lọc và quyền truy cập
+func buildSelectorForDocuments(keyword string, filters map[string]string, userID, mspID string) map[string]interface{} {
 	selector := map[string]interface{}{}
 
-	// Luôn lọc ra các bản ghi trống và LOG entries
+	// Luôn lọc ra các bản ghi trống
 	selector["docID"] = map[string]interface{}{"$exists": true, "$ne": ""}
-	selector["type"] = map[string]interface{}{"$exists": true, "$nin": []string{"", "LOG"}}
+	selector["type"] = map[string]interface{}{"$exists": true, "$ne": ""}
 	selector["ipfsHash"] = map[string]interface{}{"$exists": true, "$ne": ""}
 
 	// Áp dụng các bộ lọc bổ sung
@@ -991,10 +1065,7 @@ func buildQueryStringForDocuments(keyword string, filters map[string]string, use
 		selector["$or"] = searchConditions
 	}
 
-	queryBytes, _ := json.Marshal(map[string]interface{}{
-		"selector": selector,
-	})
-	return string(queryBytes)
+	return selector
 }
 
 // escapeRegex escapes special regex characters to safely build a contains pattern
@@ -1026,6 +1097,11 @@ func (s *LandRegistryChaincode) getQueryResultForLands(ctx contractapi.Transacti
 	}
 	defer resultsIterator.Close()
 
+	return s.collectLands(ctx, resultsIterator)
+}
+
+// collectLands - Đọc các thửa đất từ iterator, bỏ qua bản ghi ngoài namespace LAND
+func (s *LandRegistryChaincode) collectLands(ctx contractapi.TransactionContextInterface, resultsIterator shim.StateQueryIteratorInterface) ([]*Land, error) {
 	results := []*Land{}
 
     for resultsIterator.HasNext() {
@@ -1033,6 +1109,9 @@ func (s *LandRegistryChaincode) getQueryResultForLands(ctx contractapi.Transacti
 		if err != nil {
 			return nil, err
 		}
+		if KeyNamespace(ctx, response.Key) != LandKeyPrefix {
+			continue
+		}
 		var land *Land
 
 		err = json.Unmarshal(response.Value, &land)
@@ -1057,6 +1136,11 @@ func (s *LandRegistryChaincode) getQueryResultForTransactions(ctx contractapi.Tr
 	}
 	defer resultsIterator.Close()
 
+	return s.collectTransactions(ctx, resultsIterator)
+}
+
+// collectTransactions - Đọc các giao dịch từ iterator, bỏ qua bản ghi ngoài namespace TX
+func (s *LandRegistryChaincode) collectTransactions(ctx contractapi.TransactionContextInterface, resultsIterator shim.StateQueryIteratorInterface) ([]*Transaction, error) {
 	results := []*Transaction{}
 
 	for resultsIterator.HasNext() {
@@ -1064,6 +1148,9 @@ func (s *LandRegistryChaincode) getQueryResultForTransactions(ctx contractapi.Tr
 		if err != nil {
 			return nil, err
 		}
+		if KeyNamespace(ctx, response.Key) != TransactionKeyPrefix {
+			continue
+		}
 		var tx *Transaction
 
 		err = json.Unmarshal(response.Value, &tx)
@@ -1071,11 +1158,6 @@ func (s *LandRegistryChaincode) getQueryResultForTransactions(ctx contractapi.Tr
 			return nil, fmt.Errorf("failed to unmarshal transaction JSON: %v", err)
 		}
 
-		// Skip LOG entries - additional safety check
-		if tx.Type == "LOG" {
-			continue
-		}
-
 		// Normalize null arrays to empty arrays for backward compatibility
 		if tx.DocumentIDs == nil {
 			tx.DocumentIDs = []string{}
@@ -1097,12 +1179,20 @@ func (s *LandRegistryChaincode) getQueryResultForDocuments(ctx contractapi.Trans
 	}
 	defer resultsIterator.Close()
 
+	return s.collectDocuments(ctx, resultsIterator)
+}
+
+// collectDocuments - Đọc các tài liệu từ iterator, bỏ qua bản ghi ngoài namespace DOC
+func (s *LandRegistryChaincode) collectDocuments(ctx contractapi.TransactionContextInterface, resultsIterator shim.StateQueryIteratorInterface) ([]*Document, error) {
 	var documents []*Document
 	for resultsIterator.HasNext() {
 		queryResult, err := resultsIterator.Next()
 		if err != nil {
 			return nil, fmt.Errorf("lỗi khi đọc kết quả truy vấn: %v", err)
 		}
+		if KeyNamespace(ctx, queryResult.Key) != DocumentKeyPrefix {
+			continue
+		}
 
 		var doc Document
 		if err := json.Unmarshal(queryResult.Value, &doc); err != nil {
@@ -1110,8 +1200,8 @@ func (s *LandRegistryChaincode) getQueryResultForDocuments(ctx contractapi.Trans
 			continue
 		}
 		
-		// Lọc ra các bản ghi trống và LOG entries
-		if doc.DocID == "" || doc.Type == "" || doc.Type == "LOG" || doc.IPFSHash == "" {
+		// Lọc ra các bản ghi trống
+		if doc.DocID == "" || doc.Type == "" || doc.IPFSHash == "" {
 			continue
 		}
 		
@@ -1128,15 +1218,16 @@ func (s *LandRegistryChaincode) canUserAccessTransactionDocument(ctx contractapi
 		"selector": {
 			"$and": [
 				{"txId": {"$exists": true}},
-				{"type": {"$exists": true, "$ne": "LOG"}},
+				{"type": {"$exists": true}},
 				{"documentIds": {"$elemMatch": {"$eq": "%s"}}},
 				{"$or": [
 					{"fromOwnerId": "%s"},
-					{"toOwnerId": "%s"}
+					{"toOwnerId": "%s"},
+					{"requiredConsents": {"$elemMatch": {"$eq": "%s"}}}
 				]}
 			]
 		}
-	}`, docID, userID, userID)
+	}`, docID, userID, userID, userID)
 	
 	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
 	if err != nil {
@@ -1158,10 +1249,10 @@ func (s *LandRegistryChaincode) canUserAccessLandDocument(ctx contractapi.Transa
 				{"id": {"$exists": true}},
 				{"landUsePurpose": {"$exists": true}},
 				{"documentIds": {"$elemMatch": {"$eq": "%s"}}},
-				{"ownerId": "%s"}
+				{"$or": [{"ownerId": "%s"}, {"coOwners": {"$elemMatch": {"ownerId": "%s"}}}]}
 			]
 		}
-	}`, docID, userID)
+	}`, docID, userID, userID)
 	
 	resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
 	if err != nil {
@@ -0,0 +1,240 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// SpatialTileSize kích thước cạnh ô lưới (m) của chỉ mục không gian
+// Không được thay đổi sau khi đã có dữ liệu vì khóa chỉ mục được tính theo kích thước ô
+const SpatialTileSize = 100.0
+
+// maxSpatialTiles số ô lưới tối đa của một thửa đất hoặc một vùng truy vấn
+const maxSpatialTiles = 2500
+
+// tileRange dải ô lưới phủ một khung bao
+type tileRange struct {
+	MinX, MinY, MaxX, MaxY int64
+}
+
+// count số ô lưới trong dải
+func (r tileRange) count() int64 {
+	return (r.MaxX - r.MinX + 1) * (r.MaxY - r.MinY + 1)
+}
+
+// contains kiểm tra ô lưới nằm trong dải
+func (r tileRange) contains(x, y int64) bool {
+	return x >= r.MinX && x <= r.MaxX && y >= r.MinY && y <= r.MaxY
+}
+
+// bboxTileRange dải ô lưới phủ khung bao
+func bboxTileRange(b *BoundingBox) tileRange {
+	return tileRange{
+		MinX: int64(math.Floor(b.MinX / SpatialTileSize)),
+		MinY: int64(math.Floor(b.MinY / SpatialTileSize)),
+		MaxX: int64(math.Floor(b.MaxX / SpatialTileSize)),
+		MaxY: int64(math.Floor(b.MaxY / SpatialTileSize)),
+	}
+}
+
+// bboxUnion khung bao nhỏ nhất chứa cả hai khung bao
+func bboxUnion(a, b *BoundingBox) *BoundingBox {
+	return &BoundingBox{
+		MinX: math.Min(a.MinX, b.MinX),
+		MinY: math.Min(a.MinY, b.MinY),
+		MaxX: math.Max(a.MaxX, b.MaxX),
+		MaxY: math.Max(a.MaxY, b.MaxY),
+	}
+}
+
+// bboxIntersects kiểm tra hai khung bao giao nhau (kể cả chạm cạnh)
+func bboxIntersects(a, b *BoundingBox) bool {
+	return a.MinX <= b.MaxX && b.MinX <= a.MaxX && a.MinY <= b.MaxY && b.MinY <= a.MaxY
+}
+
+// bboxDistance khoảng cách từ điểm (x, y) đến khung bao (0 nếu điểm nằm trong khung)
+func bboxDistance(b *BoundingBox, x, y float64) float64 {
+	dx := math.Max(math.Max(b.MinX-x, 0), x-b.MaxX)
+	dy := math.Max(math.Max(b.MinY-y, 0), y-b.MaxY)
+	return math.Hypot(dx, dy)
+}
+
+// landTileKey tạo khóa chỉ mục ô lưới của thửa đất
+func landTileKey(ctx contractapi.TransactionContextInterface, x, y int64, landID string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(LandTileIndex, []string{strconv.FormatInt(x, 10), strconv.FormatInt(y, 10), landID})
+	if err != nil {
+		return "", fmt.Errorf("lỗi khi tạo chỉ mục không gian cho thửa đất %s: %v", landID, err)
+	}
+	return key, nil
+}
+
+// updateLandSpatialIndex cập nhật chỉ mục ô lưới khi khung bao của thửa đất thay đổi (oldBBox/newBBox nil nếu không có)
+func updateLandSpatialIndex(ctx contractapi.TransactionContextInterface, landID string, oldBBox, newBBox *BoundingBox) error {
+	var oldRange, newRange *tileRange
+	if oldBBox != nil {
+		r := bboxTileRange(oldBBox)
+		oldRange = &r
+	}
+	if newBBox != nil {
+		r := bboxTileRange(newBBox)
+		if r.count() > maxSpatialTiles {
+			return fmt.Errorf("khung bao của thửa đất %s phủ quá %d ô lưới", landID, maxSpatialTiles)
+		}
+		newRange = &r
+	}
+	if oldRange != nil {
+		for x := oldRange.MinX; x <= oldRange.MaxX; x++ {
+			for y := oldRange.MinY; y <= oldRange.MaxY; y++ {
+				if newRange != nil && newRange.contains(x, y) {
+					continue
+				}
+				key, err := landTileKey(ctx, x, y, landID)
+				if err != nil {
+					return err
+				}
+				if err := ctx.GetStub().DelState(key); err != nil {
+					return fmt.Errorf("lỗi khi xóa chỉ mục không gian của thửa đất %s: %v", landID, err)
+				}
+			}
+		}
+	}
+	if newRange != nil {
+		for x := newRange.MinX; x <= newRange.MaxX; x++ {
+			for y := newRange.MinY; y <= newRange.MaxY; y++ {
+				if oldRange != nil && oldRange.contains(x, y) {
+					continue
+				}
+				key, err := landTileKey(ctx, x, y, landID)
+				if err != nil {
+					return err
+				}
+				if err := ctx.GetStub().PutState(key, []byte{0x00}); err != nil {
+					return fmt.Errorf("lỗi khi lưu chỉ mục không gian của thửa đất %s: %v", landID, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// queryLandsByTiles liệt kê thửa đất có khung bao giao với vùng truy vấn, dựa trên chỉ mục ô lưới
+// Org3MSP chỉ nhận được các thửa đất của chính mình
+func queryLandsByTiles(ctx contractapi.TransactionContextInterface, area *BoundingBox) ([]*Land, error) {
+	if area.MinX > area.MaxX || area.MinY > area.MaxY {
+		return nil, fmt.Errorf("vùng truy vấn không hợp lệ (min phải nhỏ hơn hoặc bằng max)")
+	}
+	tiles := bboxTileRange(area)
+	if tiles.count() > maxSpatialTiles {
+		return nil, fmt.Errorf("vùng truy vấn quá lớn (tối đa %d ô lưới %.0fm x %.0fm)", maxSpatialTiles, SpatialTileSize, SpatialTileSize)
+	}
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	lands := []*Land{}
+	for x := tiles.MinX; x <= tiles.MaxX; x++ {
+		for y := tiles.MinY; y <= tiles.MaxY; y++ {
+			resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(LandTileIndex, []string{strconv.FormatInt(x, 10), strconv.FormatInt(y, 10)})
+			if err != nil {
+				return nil, fmt.Errorf("lỗi khi truy vấn chỉ mục không gian: %v", err)
+			}
+			for resultsIterator.HasNext() {
+				response, err := resultsIterator.Next()
+				if err != nil {
+					resultsIterator.Close()
+					return nil, fmt.Errorf("lỗi khi đọc chỉ mục không gian: %v", err)
+				}
+				_, attributes, err := ctx.GetStub().SplitCompositeKey(response.Key)
+				if err != nil || len(attributes) < 3 || seen[attributes[2]] {
+					continue
+				}
+				landID := attributes[2]
+				seen[landID] = true
+				data, err := GetLandState(ctx, landID)
+				if err != nil {
+					resultsIterator.Close()
+					return nil, fmt.Errorf("lỗi khi truy vấn thửa đất %s: %v", landID, err)
+				}
+				if data == nil {
+					continue
+				}
+				var land Land
+				if err := json.Unmarshal(data, &land); err != nil {
+					resultsIterator.Close()
+					return nil, fmt.Errorf("lỗi khi giải mã thửa đất %s: %v", landID, err)
+				}
+				if land.BBox == nil || !bboxIntersects(land.BBox, area) {
+					continue
+				}
+				if mspID == "Org3MSP" && !IsLandOwner(&land, userID) {
+					continue
+				}
+				if land.DocumentIDs == nil {
+					land.DocumentIDs = []string{}
+				}
+				lands = append(lands, &land)
+			}
+			resultsIterator.Close()
+		}
+	}
+	sort.Slice(lands, func(i, j int) bool { return lands[i].ID < lands[j].ID })
+	return lands, nil
+}
+
+// QueryLandsInBoundingBox - Truy vấn các thửa đất có khung bao giao với vùng chữ nhật (tọa độ VN-2000, mét)
+func (s *LandRegistryChaincode) QueryLandsInBoundingBox(ctx contractapi.TransactionContextInterface, minX, minY, maxX, maxY string) ([]*Land, error) {
+	values := []float64{}
+	for _, value := range []string{minX, minY, maxX, maxY} {
+		v, err := parseFloat(value)
+		if err != nil {
+			return nil, fmt.Errorf("lỗi khi chuyển đổi tọa độ: %v", err)
+		}
+		values = append(values, v)
+	}
+	return queryLandsByTiles(ctx, &BoundingBox{MinX: values[0], MinY: values[1], MaxX: values[2], MaxY: values[3]})
+}
+
+// QueryLandsNearPoint - Truy vấn các thửa đất nằm trong bán kính (m) quanh điểm (x, y), sắp xếp theo khoảng cách tăng dần
+// Khoảng cách được tính đến khung bao của thửa đất
+func (s *LandRegistryChaincode) QueryLandsNearPoint(ctx contractapi.TransactionContextInterface, x, y, radius string) ([]*Land, error) {
+	px, err := parseFloat(x)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi chuyển đổi tọa độ x: %v", err)
+	}
+	py, err := parseFloat(y)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi chuyển đổi tọa độ y: %v", err)
+	}
+	r, err := parseFloat(radius)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi chuyển đổi bán kính: %v", err)
+	}
+	if r < 0 {
+		return nil, fmt.Errorf("bán kính không được âm")
+	}
+	candidates, err := queryLandsByTiles(ctx, &BoundingBox{MinX: px - r, MinY: py - r, MaxX: px + r, MaxY: py + r})
+	if err != nil {
+		return nil, err
+	}
+	lands := []*Land{}
+	for _, land := range candidates {
+		if bboxDistance(land.BBox, px, py) <= r {
+			lands = append(lands, land)
+		}
+	}
+	sort.SliceStable(lands, func(i, j int) bool {
+		return bboxDistance(lands[i].BBox, px, py) < bboxDistance(lands[j].BBox, px, py)
+	})
+	return lands, nil
+}
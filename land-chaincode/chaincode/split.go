@@ -0,0 +1,180 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// addIssue ghi nhận một vấn đề vào báo cáo thẩm định tách thửa
+func (r *SplitValidationReport) addIssue(parcelID, code, message string) {
+	r.Issues = append(r.Issues, SplitValidationIssue{ParcelID: parcelID, Code: code, Message: message})
+	r.Valid = false
+}
+
+// splitValidationError chuyển báo cáo không hợp lệ thành lỗi, kèm toàn bộ báo cáo dạng JSON để ứng dụng hiển thị
+func splitValidationError(report *SplitValidationReport) error {
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("lỗi khi mã hóa báo cáo thẩm định tách thửa: %v", err)
+	}
+	return fmt.Errorf("kết quả tách thửa không hợp lệ (%d vấn đề): %s", len(report.Issues), reportJSON)
+}
+
+// splitParcelsFromInput lấy danh sách thửa đất mới từ tham số, hoặc từ đề xuất trong yêu cầu tách thửa nếu tham số trống
+// Mỗi thửa trong tham số có thể kèm "geometry" (GeoJSON) để kiểm tra diện tích
+func splitParcelsFromInput(tx *Transaction, newParcelsStr string) ([]parcelInput, error) {
+	var newParcels []parcelInput
+	if strings.TrimSpace(newParcelsStr) != "" {
+		// Các trường ngoài parcelInput (chủ sử dụng, hợp đồng thuê, GeometryHash...) trong dữ liệu đầu vào bị bỏ qua
+		if err := json.Unmarshal([]byte(newParcelsStr), &newParcels); err != nil {
+			return nil, fmt.Errorf("lỗi khi giải mã danh sách thửa đất mới: %v", err)
+		}
+		return newParcels, nil
+	}
+	if tx.Payload != nil && tx.Payload.Split != nil {
+		// Sử dụng các thửa đất đề xuất trong yêu cầu tách thửa
+		for _, parcel := range tx.Payload.Split.ProposedParcels {
+			newParcels = append(newParcels, parcelInput{
+				ID:          parcel.ID,
+				Area:        parcel.Area,
+				DocumentIDs: []string{},
+				GeometryCID: parcel.GeometryCID,
+			})
+		}
+	}
+	return newParcels, nil
+}
+
+// validateSplitParcels thẩm định toàn bộ kết quả tách thửa trước khi ghi sổ cái
+// Mọi vấn đề được ghi nhận vào báo cáo thay vì dừng ở lỗi đầu tiên; lỗi trả về chỉ là lỗi đọc sổ cái
+// Thửa kèm hình học hợp lệ được gán GeometryHash và khung bao để lưu ở bước ghi sổ cái
+func validateSplitParcels(ctx contractapi.TransactionContextInterface, tx *Transaction, originalLand *Land, newParcels []parcelInput) (*SplitValidationReport, error) {
+	config, err := GetChaincodeConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report := &SplitValidationReport{
+		TxID:          tx.TxID,
+		LandParcelID:  originalLand.ID,
+		ParcelIDs:     []string{},
+		OriginalArea:  originalLand.Area,
+		AreaTolerance: config.AreaTolerance,
+		Valid:         true,
+		Issues:        []SplitValidationIssue{},
+	}
+
+	// Kiểm tra thửa đất gốc
+	if originalLand.ID != tx.LandParcelID {
+		report.addIssue(originalLand.ID, "LAND_MISMATCH", fmt.Sprintf("thửa đất %s không phải thửa đất của yêu cầu tách thửa (%s)", originalLand.ID, tx.LandParcelID))
+	}
+	if !IsLandOwner(originalLand, tx.FromOwnerID) {
+		report.addIssue(originalLand.ID, "OWNER_MISMATCH", fmt.Sprintf("người dùng %s không sở hữu thửa đất %s", tx.FromOwnerID, originalLand.ID))
+	}
+	if err := VerifyNoOpenDispute(ctx, originalLand.ID); err != nil {
+		report.addIssue(originalLand.ID, "OPEN_DISPUTE", err.Error())
+	}
+	if err := VerifyNoActiveMortgage(ctx, originalLand.ID); err != nil {
+		report.addIssue(originalLand.ID, "ACTIVE_MORTGAGE", err.Error())
+	}
+	// Hợp đồng thuê gắn với thửa đất gốc sẽ không còn đúng sau khi tách thửa
+	if err := VerifyNoActiveLease(ctx, originalLand.ID); err != nil {
+		report.addIssue(originalLand.ID, "ACTIVE_LEASE", err.Error())
+	}
+
+	// Kiểm tra từng thửa đất mới
+	if len(newParcels) == 0 {
+		report.addIssue("", "EMPTY_PARCEL_LIST", "danh sách thửa đất mới trống")
+	} else if len(newParcels) < 2 {
+		report.addIssue("", "TOO_FEW_PARCELS", "tách thửa phải tạo ra ít nhất 2 thửa đất")
+	}
+	seen := map[string]bool{}
+	for i, parcel := range newParcels {
+		parcelID := strings.TrimSpace(parcel.ID)
+		if parcelID == "" {
+			report.addIssue("", "MISSING_ID", fmt.Sprintf("thửa đất mới thứ %d không có mã thửa", i+1))
+		} else {
+			report.ParcelIDs = append(report.ParcelIDs, parcelID)
+			if seen[parcelID] {
+				report.addIssue(parcelID, "DUPLICATE_ID", fmt.Sprintf("mã thửa %s bị trùng trong danh sách thửa đất mới", parcelID))
+			}
+			seen[parcelID] = true
+			// Chỉ thửa đất gốc được phép giữ lại mã thửa đã tồn tại
+			if parcelID != originalLand.ID {
+				exists, err := CheckLandExists(ctx, parcelID)
+				if err != nil {
+					return nil, err
+				}
+				if exists {
+					report.addIssue(parcelID, "ID_EXISTS", fmt.Sprintf("thửa đất %s đã tồn tại", parcelID))
+				}
+			}
+		}
+
+		if parcel.Area <= 0 {
+			report.addIssue(parcelID, "INVALID_AREA", fmt.Sprintf("diện tích thửa đất %s phải lớn hơn 0", parcelID))
+		} else {
+			report.TotalArea += parcel.Area
+			if len(parcel.Geometry) > 0 {
+				geometry, err := checkDeclaredArea(ctx, parcel.Geometry, parcelID, parcel.Area)
+				if err != nil {
+					report.addIssue(parcelID, "GEOMETRY_MISMATCH", err.Error())
+				} else {
+					newParcels[i].GeometryHash = geometry.Hash
+					newParcels[i].BBox = geometry.BBox
+				}
+			}
+		}
+		if parcel.GeometryCID != "" {
+			if err := ValidateIPFSHash(parcel.GeometryCID); err != nil {
+				report.addIssue(parcelID, "INVALID_GEOMETRY", fmt.Sprintf("geometry CID không hợp lệ: %v", err))
+			}
+		}
+		for _, docID := range parcel.DocumentIDs {
+			doc, err := GetDocument(ctx, docID)
+			if err != nil {
+				// Bỏ qua tài liệu không tồn tại, như ValidateLand
+				continue
+			}
+			if doc.IPFSHash != "" {
+				if err := ValidateIPFSHash(doc.IPFSHash); err != nil {
+					report.addIssue(parcelID, "INVALID_DOCUMENT", fmt.Sprintf("tài liệu %s có hash IPFS không hợp lệ: %v", docID, err))
+				}
+			}
+		}
+	}
+
+	// Bảo toàn diện tích: tổng diện tích sau tách phải bằng diện tích thửa gốc trong phạm vi sai số
+	if len(newParcels) > 0 && math.Abs(report.TotalArea-originalLand.Area) > config.AreaTolerance {
+		report.addIssue("", "AREA_MISMATCH", fmt.Sprintf("tổng diện tích các thửa mới (%.2f m²) chênh lệch với diện tích thửa gốc (%.2f m²) vượt quá sai số cho phép (%.2f m²)",
+			report.TotalArea, originalLand.Area, config.AreaTolerance))
+	}
+	return report, nil
+}
+
+// ValidateSplitTransaction - Thẩm định trước kết quả tách thửa, trả về báo cáo liệt kê toàn bộ vấn đề (không ghi sổ cái)
+// Tham số giống ApproveSplitTransaction; newParcelsStr trống thì dùng các thửa đề xuất trong yêu cầu
+func (s *LandRegistryChaincode) ValidateSplitTransaction(ctx contractapi.TransactionContextInterface, txID, landID, newParcelsStr string) (*SplitValidationReport, error) {
+	if err := CheckOrganization(ctx, []string{"Org1MSP", "Org2MSP"}); err != nil {
+		return nil, err
+	}
+	tx, err := GetTransaction(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.Type != "SPLIT" {
+		return nil, fmt.Errorf("giao dịch %s không phải là tách thửa", txID)
+	}
+	originalLand, err := s.QueryLandByID(ctx, landID)
+	if err != nil {
+		return nil, fmt.Errorf("lỗi khi truy vấn thửa đất gốc %s: %v", landID, err)
+	}
+	newParcels, err := splitParcelsFromInput(tx, newParcelsStr)
+	if err != nil {
+		return nil, err
+	}
+	return validateSplitParcels(ctx, tx, originalLand, newParcels)
+}
@@ -0,0 +1,151 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// newMockContext tạo transaction context trên MockStub, đã mở sẵn một giao dịch để ghi state
+func newMockContext(t *testing.T) *contractapi.TransactionContext {
+	t.Helper()
+	stub := shimtest.NewMockStub("land", nil)
+	stub.MockTransactionStart("test-tx")
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	return ctx
+}
+
+// putTestLand ghi thửa đất vào MockStub
+func putTestLand(t *testing.T, ctx contractapi.TransactionContextInterface, land *Land) {
+	t.Helper()
+	data, err := json.Marshal(land)
+	if err != nil {
+		t.Fatalf("marshal land: %v", err)
+	}
+	if err := PutLandState(ctx, land.ID, data); err != nil {
+		t.Fatalf("put land %s: %v", land.ID, err)
+	}
+}
+
+func TestValidateSplitParcels(t *testing.T) {
+	tests := []struct {
+		name    string
+		parcels []parcelInput
+		leases  []*Lease
+		issues  []string
+	}{
+		{
+			name:    "valid split",
+			parcels: []parcelInput{{ID: "L1", Area: 60}, {ID: "L2", Area: 40}},
+		},
+		{
+			name:    "original parcel id kept",
+			parcels: []parcelInput{{ID: " L1 ", Area: 50}, {ID: "L3", Area: 50.05}},
+		},
+		{
+			name:    "area mismatch",
+			parcels: []parcelInput{{ID: "L2", Area: 60}, {ID: "L3", Area: 30}},
+			issues:  []string{"AREA_MISMATCH"},
+		},
+		{
+			name:    "duplicate id",
+			parcels: []parcelInput{{ID: "L2", Area: 50}, {ID: "L2", Area: 50}},
+			issues:  []string{"DUPLICATE_ID"},
+		},
+		{
+			name:    "id exists",
+			parcels: []parcelInput{{ID: "L2", Area: 50}, {ID: "L9", Area: 50}},
+			issues:  []string{"ID_EXISTS"},
+		},
+		{
+			name:    "active lease",
+			parcels: []parcelInput{{ID: "L2", Area: 50}, {ID: "L3", Area: 50}},
+			leases:  []*Lease{{LeaseID: "LEASE1", LandParcelID: "L1", LesseeID: "lessee", Status: LeaseStatusActive, EndDate: time.Now().AddDate(1, 0, 0)}},
+			issues:  []string{"ACTIVE_LEASE"},
+		},
+		{
+			name:    "expired or terminated lease",
+			parcels: []parcelInput{{ID: "L2", Area: 50}, {ID: "L3", Area: 50}},
+			leases: []*Lease{
+				{LeaseID: "LEASE1", LandParcelID: "L1", LesseeID: "lessee", Status: LeaseStatusActive, EndDate: time.Now().AddDate(-1, 0, 0)},
+				{LeaseID: "LEASE2", LandParcelID: "L1", LesseeID: "lessee", Status: LeaseStatusTerminated, EndDate: time.Now().AddDate(1, 0, 0)},
+			},
+		},
+		{
+			name:    "every issue reported",
+			parcels: []parcelInput{{ID: "L9", Area: 50}, {ID: "L9", Area: 10}},
+			issues:  []string{"ID_EXISTS", "DUPLICATE_ID", "ID_EXISTS", "AREA_MISMATCH"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newMockContext(t)
+			original := &Land{ID: "L1", OwnerID: "owner", Area: 100}
+			putTestLand(t, ctx, original)
+			putTestLand(t, ctx, &Land{ID: "L9", OwnerID: "other", Area: 20})
+			for _, lease := range tt.leases {
+				if err := putLease(ctx, lease); err != nil {
+					t.Fatalf("putLease: %v", err)
+				}
+			}
+			tx := &Transaction{TxID: "T1", Type: "SPLIT", LandParcelID: "L1", FromOwnerID: "owner"}
+
+			report, err := validateSplitParcels(ctx, tx, original, tt.parcels)
+			if err != nil {
+				t.Fatalf("validateSplitParcels: %v", err)
+			}
+			codes := []string{}
+			for _, issue := range report.Issues {
+				codes = append(codes, issue.Code)
+			}
+			if len(codes) != len(tt.issues) {
+				t.Fatalf("issues = %v, want %v", codes, tt.issues)
+			}
+			for i := range codes {
+				if codes[i] != tt.issues[i] {
+					t.Fatalf("issues = %v, want %v", codes, tt.issues)
+				}
+			}
+			if report.Valid != (len(tt.issues) == 0) {
+				t.Errorf("valid = %v with issues %v", report.Valid, codes)
+			}
+		})
+	}
+}
+
+func TestSplitParcelsFromInputIgnoresLandFields(t *testing.T) {
+	input := `[{"id":"L2","area":50,"geometryCid":"cid","documentIds":["D1"],
+		"ownerId":"attacker","leaseIds":["LEASE1"],"coOwners":[{"ownerId":"x","share":1}],
+		"certificateId":"GCN1","geometryHash":"forged","bbox":{"minX":0,"minY":0,"maxX":1,"maxY":1}}]`
+	parcels, err := splitParcelsFromInput(&Transaction{Type: "SPLIT"}, input)
+	if err != nil {
+		t.Fatalf("splitParcelsFromInput: %v", err)
+	}
+	if len(parcels) != 1 {
+		t.Fatalf("got %d parcels, want 1", len(parcels))
+	}
+	parcel := parcels[0]
+	if parcel.ID != "L2" || parcel.Area != 50 || parcel.GeometryCID != "cid" || len(parcel.DocumentIDs) != 1 {
+		t.Errorf("whitelisted fields not parsed: %+v", parcel)
+	}
+	if parcel.GeometryHash != "" || parcel.BBox != nil {
+		t.Errorf("geometry hash and bbox must only be computed from geometry: %+v", parcel)
+	}
+}
+
+func TestSplitParcelsFromInputUsesProposal(t *testing.T) {
+	tx := &Transaction{Type: "SPLIT", Payload: &TransactionPayload{Split: &SplitPayload{
+		ProposedParcels: []ProposedParcel{{ID: "L2", Area: 40}, {ID: "L3", Area: 60}},
+	}}}
+	parcels, err := splitParcelsFromInput(tx, " ")
+	if err != nil {
+		t.Fatalf("splitParcelsFromInput: %v", err)
+	}
+	if len(parcels) != 2 || parcels[0].ID != "L2" || parcels[1].Area != 60 {
+		t.Errorf("proposed parcels not used: %+v", parcels)
+	}
+}
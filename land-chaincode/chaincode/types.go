@@ -1,55 +1,344 @@
 package chaincode
 
-import "time"
-
-
+import (
+	"encoding/json"
+	"time"
+)
 
 // Land định nghĩa thông tin thửa đất và giấy chứng nhận
 type Land struct {
-	ID             string    `json:"id"`                  // Mã thửa đất
-	OwnerID        string    `json:"ownerId"`             // CCCD của chủ sử dụng
-	Area           float64   `json:"area"`                // Diện tích (m²)
-	Location       string    `json:"location"`            // Vị trí
-	LandUsePurpose string    `json:"landUsePurpose"`      // Mục đích sử dụng
-	LegalStatus    string    `json:"legalStatus"`         // Trạng thái pháp lý
-	CertificateID  string    `json:"certificateId"`       // Mã giấy chứng nhận
-	IssueDate      time.Time `json:"issueDate,omitempty"` // Ngày cấp giấy chứng nhận
-	LegalInfo      string    `json:"legalInfo"`           // Thông tin pháp lý
-	DocumentIDs       []string  `json:"documentIds"`         // Danh sách ID tài liệu liên quan (chỉ verified documents)
-	GeometryCID       string    `json:"geometryCid"`         // IPFS CID của geometry data
-	CreatedAt         time.Time `json:"createdAt"`           // Thời gian tạo
-	UpdatedAt         time.Time `json:"updatedAt"`           // Thời gian cập nhật
+	ID             string        `json:"id"`                     // Mã thửa đất
+	OwnerID        string        `json:"ownerId"`                // Mã định danh băm của chủ sử dụng (xem PersonalData)
+	Area           float64       `json:"area"`                   // Diện tích (m²)
+	Location       string        `json:"location"`               // Vị trí
+	LandUsePurpose string        `json:"landUsePurpose"`         // Mục đích sử dụng
+	LegalStatus    string        `json:"legalStatus"`            // Trạng thái pháp lý
+	CertificateID  string        `json:"certificateId"`          // Mã giấy chứng nhận
+	IssueDate      time.Time     `json:"issueDate,omitempty"`    // Ngày cấp giấy chứng nhận
+	LegalInfo      string        `json:"legalInfo"`              // Thông tin pháp lý
+	DocumentIDs    []string      `json:"documentIds"`            // Danh sách ID tài liệu liên quan (chỉ verified documents)
+	GeometryCID    string        `json:"geometryCid"`            // IPFS CID của geometry data
+	GeometryHash   string        `json:"geometryHash,omitempty"` // SHA-256 của geometry dạng chuẩn (xem ParseParcelGeometry), dùng đối chiếu nội dung CID
+	BBox           *BoundingBox  `json:"bbox,omitempty"`         // Khung bao của geometry (tọa độ VN-2000), dùng cho chỉ mục không gian
+	AreaOverride   string        `json:"areaOverride,omitempty"` // Lý do Org1 chấp nhận diện tích kê khai lệch với hình học (chờ đo đạc lại), xem RegisterLandGeometry
+	CoOwners       []LandCoOwner `json:"coOwners,omitempty"`     // Danh sách đồng sở hữu và phần sở hữu (trống nếu OwnerID là chủ sử dụng duy nhất)
+	LeaseIDs       []string      `json:"leaseIds,omitempty"`     // Các hợp đồng thuê, thuê lại đang hiệu lực trên thửa đất
+	CreatedAt      time.Time     `json:"createdAt"`              // Thời gian tạo
+	UpdatedAt      time.Time     `json:"updatedAt"`              // Thời gian cập nhật
+}
+
+// LandCoOwner một người đồng sở hữu thửa đất
+type LandCoOwner struct {
+	OwnerID string  `json:"ownerId"` // Mã định danh người đồng sở hữu
+	Share   float64 `json:"share"`   // Phần sở hữu (0 < share <= 1, tổng các phần bằng 1)
+}
+
+// OwnerConsent ý kiến của một người đồng sở hữu đối với giao dịch hoặc thế chấp
+type OwnerConsent struct {
+	OwnerID  string    `json:"ownerId"`          // Mã định danh người đồng sở hữu
+	Accepted bool      `json:"accepted"`         // Đồng ý hay không
+	Reason   string    `json:"reason,omitempty"` // Lý do (khi không đồng ý)
+	At       time.Time `json:"at"`               // Thời gian cho ý kiến
 }
 
 // Document định nghĩa tài liệu độc lập
 type Document struct {
-	DocID       string    `json:"docID"`       // Mã tài liệu
-	Type        string    `json:"type"`        // Loại tài liệu (CERTIFICATE, CONTRACT, MAP, FORM, TAX_DOCUMENT, TECHNICAL_DOC, LEGAL_DOC, OTHER)
-	Title       string    `json:"title"`       // Tiêu đề tài liệu
-	Description string    `json:"description"` // Mô tả tài liệu
-	IPFSHash    string    `json:"ipfsHash"`    // Hash tài liệu trên IPFS
-	FileSize    int64     `json:"fileSize"`    // Kích thước file (bytes)
-	FileType    string    `json:"fileType"`    // Loại file (PDF, JPG, PNG, etc.)
-	UploadedBy  string    `json:"uploadedBy"`  // CCCD người upload
-	Status      string    `json:"status"`      // Trạng thái: "PENDING", "VERIFIED", "REJECTED"
-	VerifiedBy  string    `json:"verifiedBy"`  // CCCD người xác thực/từ chối
-	VerifiedAt  time.Time `json:"verifiedAt"`  // Thời gian xác thực/từ chối
-	CreatedAt   time.Time `json:"createdAt"`   // Thời gian tạo
-	UpdatedAt   time.Time `json:"updatedAt"`   // Thời gian cập nhật
+	DocID               string           `json:"docID"`                         // Mã tài liệu
+	Type                string           `json:"type"`                          // Loại tài liệu (CERTIFICATE, CONTRACT, MAP, FORM, TAX_DOCUMENT, TECHNICAL_DOC, LEGAL_DOC, OTHER)
+	SubType             string           `json:"subType"`                       // Mã thành phần hồ sơ (MAU_09_DK, GCN, ...), xem danh mục documentSubTypes
+	Title               string           `json:"title"`                         // Tiêu đề tài liệu
+	Description         string           `json:"description"`                   // Mô tả tài liệu
+	IPFSHash            string           `json:"ipfsHash"`                      // Hash tài liệu trên IPFS
+	ContentSHA256       string           `json:"contentSha256"`                 // SHA-256 (hex) nội dung file, dùng để chứng minh file tải về là bản gốc
+	FileSize            int64            `json:"fileSize"`                      // Kích thước file (bytes)
+	FileType            string           `json:"fileType"`                      // Loại file (PDF, JPG, PNG, etc.)
+	UploadedBy          string           `json:"uploadedBy"`                    // Mã định danh người upload
+	Status              string           `json:"status"`                        // Trạng thái: "PENDING", "VERIFIED", "REJECTED"
+	VerifiedBy          string           `json:"verifiedBy"`                    // Mã định danh người xác thực/từ chối
+	VerifiedAt          time.Time        `json:"verifiedAt"`                    // Thời gian xác thực/từ chối
+	IntegrityVerifiedAt time.Time        `json:"integrityVerifiedAt,omitempty"` // Thời gian Org2 xác thực nội dung khớp SHA-256 đã ghi nhận
+	CreatedAt           time.Time        `json:"createdAt"`                     // Thời gian tạo
+	UpdatedAt           time.Time        `json:"updatedAt"`                     // Thời gian cập nhật
+	Version             int              `json:"version"`                       // Phiên bản nội dung hiện hành (bắt đầu từ 1)
+	RevisedAt           time.Time        `json:"revisedAt,omitempty"`           // Thời gian thay nội dung gần nhất
+	RevisionNote        string           `json:"revisionNote,omitempty"`        // Ghi chú của lần thay nội dung gần nhất
+	Reviews             []DocumentReview `json:"reviews,omitempty"`             // Lịch sử thẩm định: xác thực, từ chối, nộp lại
+}
+
+// DocumentReview một bước trong lịch sử thẩm định tài liệu
+type DocumentReview struct {
+	Action     string    `json:"action"`               // VERIFY, REJECT, RESUBMIT, REVISE
+	ReasonCode string    `json:"reasonCode,omitempty"` // Mã lý do từ chối (khi REJECT)
+	Note       string    `json:"note,omitempty"`       // Lý do từ chối hoặc giải trình của người nộp
+	ActorID    string    `json:"actorId"`              // Mã định danh người thực hiện
+	Version    int       `json:"version"`              // Phiên bản tài liệu tại thời điểm thực hiện
+	At         time.Time `json:"at"`                   // Thời gian thực hiện
+}
+
+// DocumentVersion bản lưu nội dung cũ của tài liệu trước mỗi lần thay nội dung
+type DocumentVersion struct {
+	DocID               string    `json:"docID"`                         // Mã tài liệu
+	Version             int       `json:"version"`                       // Số phiên bản
+	IPFSHash            string    `json:"ipfsHash"`                      // Hash IPFS của phiên bản
+	ContentSHA256       string    `json:"contentSha256"`                 // SHA-256 nội dung của phiên bản
+	FileSize            int64     `json:"fileSize"`                      // Kích thước file (bytes)
+	FileType            string    `json:"fileType"`                      // Loại file
+	UploadedBy          string    `json:"uploadedBy"`                    // Mã định danh người tải lên phiên bản
+	UploadedAt          time.Time `json:"uploadedAt"`                    // Thời gian tải lên phiên bản
+	Note                string    `json:"note,omitempty"`                // Ghi chú khi tải lên phiên bản
+	Status              string    `json:"status"`                        // Trạng thái thẩm định của phiên bản khi bị thay thế
+	VerifiedBy          string    `json:"verifiedBy,omitempty"`          // Mã định danh người xác thực/từ chối phiên bản
+	VerifiedAt          time.Time `json:"verifiedAt,omitempty"`          // Thời gian xác thực/từ chối phiên bản
+	IntegrityVerifiedAt time.Time `json:"integrityVerifiedAt,omitempty"` // Thời gian xác thực nội dung của phiên bản
+}
+
+// Certificate định nghĩa giấy chứng nhận quyền sử dụng đất (GCN)
+type Certificate struct {
+	CertificateID string    `json:"certificateId"`       // Mã giấy chứng nhận
+	SerialNumber  string    `json:"serialNumber"`        // Số seri phát hành (duy nhất)
+	LandParcelID  string    `json:"landParcelId"`        // Mã thửa đất được cấp
+	HolderID      string    `json:"holderId"`            // Mã định danh người được cấp
+	IssueDate     time.Time `json:"issueDate"`           // Ngày cấp
+	IssuedBy      string    `json:"issuedBy"`            // Mã định danh cán bộ cấp
+	PdfCID        string    `json:"pdfCid"`              // IPFS CID của bản PDF
+	LegalInfo     string    `json:"legalInfo"`           // Thông tin pháp lý ghi trên GCN
+	Status        string    `json:"status"`              // Trạng thái: ACTIVE, REVOKED, SUPERSEDED
+	RevokeReason  string    `json:"revokeReason"`        // Lý do thu hồi hoặc thay thế
+	RevokedBy     string    `json:"revokedBy"`           // Mã định danh người thu hồi
+	RevokedAt     time.Time `json:"revokedAt,omitempty"` // Thời gian thu hồi
+	SupersededBy  string    `json:"supersededBy"`        // Mã GCN thay thế (khi SUPERSEDED)
+	CreatedAt     time.Time `json:"createdAt"`           // Thời gian tạo
+	UpdatedAt     time.Time `json:"updatedAt"`           // Thời gian cập nhật
+}
+
+// Mortgage định nghĩa hợp đồng thế chấp quyền sử dụng đất
+type Mortgage struct {
+	MortgageID          string         `json:"mortgageId"`                 // Mã hợp đồng thế chấp
+	LandParcelID        string         `json:"landParcelId"`               // Mã thửa đất thế chấp
+	OwnerID             string         `json:"ownerId"`                    // Mã định danh bên thế chấp (chủ sử dụng)
+	LenderMSP           string         `json:"lenderMsp"`                  // MSP của tổ chức nhận thế chấp
+	LenderName          string         `json:"lenderName"`                 // Tên tổ chức nhận thế chấp
+	RegisteredBy        string         `json:"registeredBy"`               // Mã cán bộ ngân hàng đăng ký
+	SecuredAmount       float64        `json:"securedAmount"`              // Số tiền được bảo đảm (VNĐ)
+	ContractDocID       string         `json:"contractDocId"`              // Mã tài liệu hợp đồng thế chấp
+	StartDate           time.Time      `json:"startDate"`                  // Ngày bắt đầu hiệu lực
+	EndDate             time.Time      `json:"endDate"`                    // Ngày hết hạn
+	Status              string         `json:"status"`                     // Trạng thái: PENDING_CONSENT, ACTIVE, DECLINED, RELEASED
+	PreviousLegalStatus string         `json:"previousLegalStatus"`        // Trạng thái pháp lý của thửa đất trước khi thế chấp
+	ConfirmedAt         time.Time      `json:"confirmedAt,omitempty"`      // Thời gian chủ sử dụng đồng ý
+	RequiredConsents    []string       `json:"requiredConsents,omitempty"` // Mã định danh các đồng sở hữu phải đồng ý
+	Consents            []OwnerConsent `json:"consents,omitempty"`         // Ý kiến đã ghi nhận của các đồng sở hữu
+	ReleasedBy          string         `json:"releasedBy"`                 // Mã cán bộ giải chấp
+	ReleasedAt          time.Time      `json:"releasedAt,omitempty"`       // Thời gian giải chấp
+	ReleaseReason       string         `json:"releaseReason"`              // Lý do giải chấp / từ chối
+	CreatedAt           time.Time      `json:"createdAt"`                  // Thời gian tạo
+	UpdatedAt           time.Time      `json:"updatedAt"`                  // Thời gian cập nhật
+}
+
+// Lease định nghĩa hợp đồng cho thuê, cho thuê lại quyền sử dụng đất
+type Lease struct {
+	LeaseID           string         `json:"leaseId"`                 // Mã hợp đồng thuê
+	LandParcelID      string         `json:"landParcelId"`            // Mã thửa đất cho thuê
+	ParentLeaseID     string         `json:"parentLeaseId,omitempty"` // Mã hợp đồng thuê gốc (chỉ có khi cho thuê lại)
+	LessorID          string         `json:"lessorId"`                // Mã định danh bên cho thuê (chủ sử dụng, hoặc bên thuê gốc khi cho thuê lại)
+	LesseeID          string         `json:"lesseeId"`                // Mã định danh bên thuê
+	RentAmount        float64        `json:"rentAmount"`              // Tiền thuê mỗi kỳ thanh toán (VNĐ)
+	RentPaymentPeriod string         `json:"rentPaymentPeriod"`       // Kỳ thanh toán: MONTHLY, QUARTERLY, YEARLY, LUMP_SUM
+	RentTerms         string         `json:"rentTerms"`               // Điều khoản khác về tiền thuê
+	ContractDocID     string         `json:"contractDocId"`           // Mã tài liệu hợp đồng thuê
+	StartDate         time.Time      `json:"startDate"`               // Ngày bắt đầu thuê
+	EndDate           time.Time      `json:"endDate"`                 // Ngày kết thúc thuê
+	Status            string         `json:"status"`                  // Trạng thái: PENDING_CONFIRMATION, CONFIRMED, ACTIVE, DECLINED, TERMINATED
+	RequiredConsents  []string       `json:"requiredConsents"`        // Mã định danh các bên phải xác nhận (chủ sử dụng, bên cho thuê lại, bên thuê)
+	Consents          []OwnerConsent `json:"consents,omitempty"`      // Xác nhận đã ghi nhận của các bên
+	CreatedBy         string         `json:"createdBy"`               // Mã định danh người đăng ký
+	RecordedBy        string         `json:"recordedBy"`              // Mã cán bộ Org1 ghi nhận hợp đồng
+	RecordedAt        time.Time      `json:"recordedAt,omitempty"`    // Thời gian ghi nhận
+	TerminatedBy      string         `json:"terminatedBy"`            // Mã cán bộ chấm dứt hợp đồng
+	TerminatedAt      time.Time      `json:"terminatedAt,omitempty"`  // Thời gian chấm dứt
+	TerminationReason string         `json:"terminationReason"`       // Lý do chấm dứt / từ chối
+	CreatedAt         time.Time      `json:"createdAt"`               // Thời gian tạo
+	UpdatedAt         time.Time      `json:"updatedAt"`               // Thời gian cập nhật
+}
+
+// ParcelLock khóa thửa đất bởi giao dịch đang xử lý, ngăn các yêu cầu xung đột trên cùng thửa
+type ParcelLock struct {
+	LandParcelID string    `json:"landParcelId"`       // Mã thửa đất
+	TxID         string    `json:"txId"`               // Mã giao dịch giữ khóa (trống nếu thửa đất không bị khóa)
+	TxType       string    `json:"txType,omitempty"`   // Loại giao dịch giữ khóa
+	LockedBy     string    `json:"lockedBy,omitempty"` // Mã định danh người tạo yêu cầu
+	LockedAt     time.Time `json:"lockedAt,omitempty"` // Thời gian khóa
+}
+
+// Dispute định nghĩa vụ tranh chấp đất đai
+type Dispute struct {
+	DisputeID           string    `json:"disputeId"`            // Mã vụ tranh chấp
+	LandParcelID        string    `json:"landParcelId"`         // Mã thửa đất tranh chấp
+	ClaimantID          string    `json:"claimantId"`           // Mã định danh người khởi kiện
+	RespondentID        string    `json:"respondentId"`         // Mã định danh chủ sử dụng tại thời điểm mở vụ
+	Description         string    `json:"description"`          // Nội dung tranh chấp
+	EvidenceDocIDs      []string  `json:"evidenceDocIds"`       // Danh sách tài liệu chứng cứ
+	Status              string    `json:"status"`               // Trạng thái: OPEN, RESOLVED
+	Outcome             string    `json:"outcome"`              // Kết quả: UPHELD, DISMISSED, SETTLED
+	ResolutionDocID     string    `json:"resolutionDocId"`      // Tài liệu quyết định giải quyết
+	ResolutionNote      string    `json:"resolutionNote"`       // Ghi chú kết quả giải quyết
+	NewOwnerID          string    `json:"newOwnerId"`           // Mã định danh chủ sử dụng mới (nếu thay đổi)
+	PreviousLegalStatus string    `json:"previousLegalStatus"`  // Trạng thái pháp lý trước khi tranh chấp
+	OpenedBy            string    `json:"openedBy"`             // Mã định danh cán bộ tiếp nhận
+	ResolvedBy          string    `json:"resolvedBy"`           // Mã định danh cán bộ giải quyết
+	ResolvedAt          time.Time `json:"resolvedAt,omitempty"` // Thời gian giải quyết
+	CreatedAt           time.Time `json:"createdAt"`            // Thời gian tạo
+	UpdatedAt           time.Time `json:"updatedAt"`            // Thời gian cập nhật
+}
+
+// LandUsePurpose định nghĩa một mã mục đích sử dụng đất trong danh mục
+type LandUsePurpose struct {
+	Code      string    `json:"code"`      // Mã mục đích sử dụng (LUC, ONT, ...)
+	Name      string    `json:"name"`      // Tên tiếng Việt
+	Category  string    `json:"category"`  // Nhóm đất: AGRICULTURAL, NON_AGRICULTURAL, UNUSED
+	Active    bool      `json:"active"`    // Còn được sử dụng cho đăng ký mới
+	UpdatedBy string    `json:"updatedBy"` // Mã định danh người cập nhật gần nhất
+	CreatedAt time.Time `json:"createdAt"` // Thời gian tạo
+	UpdatedAt time.Time `json:"updatedAt"` // Thời gian cập nhật
+}
+
+// PersonalData hồ sơ cá nhân của chủ thể dữ liệu, lưu trong PersonalDataCollection
+// Dữ liệu công khai chỉ lưu SubjectRef = SHA-256(salt || CCCD)
+type PersonalData struct {
+	SubjectRef string    `json:"subjectRef"` // Mã định danh băm dùng trên dữ liệu công khai
+	CCCD       string    `json:"cccd"`       // Số căn cước công dân
+	FullName   string    `json:"fullName"`   // Họ và tên
+	Phone      string    `json:"phone"`      // Số điện thoại
+	Email      string    `json:"email"`      // Thư điện tử
+	Address    string    `json:"address"`    // Địa chỉ liên hệ
+	UpdatedBy  string    `json:"updatedBy"`  // Mã định danh người cập nhật
+	UpdatedAt  time.Time `json:"updatedAt"`  // Thời gian cập nhật
 }
 
 // Transaction định nghĩa giao dịch biến động đất đai
 type Transaction struct {
+	TxID             string              `json:"txId"`                       // Mã giao dịch
+	Type             string              `json:"type"`                       // Loại giao dịch (TRANSFER, SPLIT, MERGE, CHANGE_PURPOSE, REISSUE, INHERITANCE, GIFT)
+	LandParcelID     string              `json:"landParcelId"`               // Mã thửa đất chính
+	ParcelIDs        []string            `json:"parcelIds"`                  // Danh sách mã thửa đất (cho trường hợp hợp thửa/tách thửa)
+	FromOwnerID      string              `json:"fromOwnerId"`                // Mã định danh người chuyển nhượng
+	ToOwnerID        string              `json:"toOwnerId"`                  // Mã định danh người nhận chuyển nhượng
+	Status           string              `json:"status"`                     // Trạng thái (PENDING, CONFIRMED, FORWARDED, VERIFIED, SUPPLEMENT_REQUESTED, APPROVED, REJECTED, WITHDRAWN, EXPIRED)
+	Details          string              `json:"details"`                    // Ghi chú dễ đọc cho người dùng (không dùng để trích xuất tham số)
+	Payload          *TransactionPayload `json:"payload,omitempty"`          // Dữ liệu có cấu trúc theo loại giao dịch
+	UserID           string              `json:"userId"`                     // Mã định danh người thực hiện giao dịch
+	DocumentIDs      []string            `json:"documentIds"`                // Danh sách ID tài liệu liên quan
+	RequiredConsents []string            `json:"requiredConsents,omitempty"` // Mã định danh các đồng sở hữu khác phải đồng ý
+	Consents         []OwnerConsent      `json:"consents,omitempty"`         // Ý kiến đã ghi nhận của các đồng sở hữu
+	StageStatus      string              `json:"stageStatus,omitempty"`      // Trạng thái mà thời hạn xử lý hiện tại áp dụng
+	StageEnteredAt   time.Time           `json:"stageEnteredAt,omitempty"`   // Thời điểm giao dịch chuyển sang trạng thái hiện tại
+	StageDueAt       time.Time           `json:"stageDueAt,omitempty"`       // Hạn xử lý của trạng thái hiện tại (trống nếu không giới hạn)
+	CreatedAt        time.Time           `json:"createdAt"`                  // Thời gian tạo
+	UpdatedAt        time.Time           `json:"updatedAt"`                  // Thời gian cập nhật
+}
+
+// TransactionPayload dữ liệu có cấu trúc của giao dịch, chỉ một trường tương ứng với loại giao dịch được thiết lập
+type TransactionPayload struct {
+	Transfer      *TransferPayload      `json:"transfer,omitempty"`      // TRANSFER
+	Split         *SplitPayload         `json:"split,omitempty"`         // SPLIT
+	Merge         *MergePayload         `json:"merge,omitempty"`         // MERGE
+	ChangePurpose *ChangePurposePayload `json:"changePurpose,omitempty"` // CHANGE_PURPOSE
+	Reissue       *ReissuePayload       `json:"reissue,omitempty"`       // REISSUE
+	Inheritance   *InheritancePayload   `json:"inheritance,omitempty"`   // INHERITANCE
+	Gift          *GiftPayload          `json:"gift,omitempty"`          // GIFT
+}
+
+// TransactionWithdrawnEvent nội dung sự kiện TRANSACTION_WITHDRAWN gửi cho các bên liên quan
+type TransactionWithdrawnEvent struct {
 	TxID         string    `json:"txId"`         // Mã giao dịch
-	Type         string    `json:"type"`         // Loại giao dịch (TRANSFER, SPLIT, MERGE, CHANGE_PURPOSE, REISSUE)
-	LandParcelID string    `json:"landParcelId"` // Mã thửa đất chính
-	ParcelIDs    []string  `json:"parcelIds"`    // Danh sách mã thửa đất (cho trường hợp hợp thửa/tách thửa)
-	FromOwnerID  string    `json:"fromOwnerId"`  // CCCD người chuyển nhượng
-	ToOwnerID    string    `json:"toOwnerId"`    // CCCD người nhận chuyển nhượng
-	Status       string    `json:"status"`       // Trạng thái (PENDING, CONFIRMED, FORWARDED, VERIFIED, SUPPLEMENT_REQUESTED, APPROVED, REJECTED)
-	Details      string    `json:"details"`      // Chi tiết giao dịch
-	UserID       string    `json:"userId"`       // CCCD người thực hiện giao dịch
-	DocumentIDs  []string  `json:"documentIds"`  // Danh sách ID tài liệu liên quan
-	CreatedAt    time.Time `json:"createdAt"`    // Thời gian tạo
-	UpdatedAt    time.Time `json:"updatedAt"`    // Thời gian cập nhật
+	Type         string    `json:"type"`         // Loại giao dịch
+	LandParcelID string    `json:"landParcelId"` // Mã thửa đất
+	WithdrawnBy  string    `json:"withdrawnBy"`  // Mã định danh người rút hồ sơ
+	Reason       string    `json:"reason"`       // Lý do rút hồ sơ
+	NotifyIDs    []string  `json:"notifyIds"`    // Mã định danh các bên cần được thông báo
+	WithdrawnAt  time.Time `json:"withdrawnAt"`  // Thời gian rút hồ sơ
+}
+
+// TransferPayload dữ liệu giao dịch chuyển nhượng
+type TransferPayload struct {
+	RecipientID string `json:"recipientId"` // Mã định danh người nhận chuyển nhượng (giá kê khai lưu trong TransactionPrivateDetails)
+}
+
+// TransactionPrivateDetails thông tin riêng của giao dịch, lưu trong TransactionPrivateCollection
+type TransactionPrivateDetails struct {
+	TxID          string    `json:"txId"`          // Mã giao dịch
+	DeclaredPrice float64   `json:"declaredPrice"` // Giá chuyển nhượng kê khai (VNĐ)
+	UpdatedBy     string    `json:"updatedBy"`     // Mã định danh người cập nhật
+	UpdatedAt     time.Time `json:"updatedAt"`     // Thời gian cập nhật
+}
+
+// ProposedParcel thửa đất dự kiến sau khi tách
+type ProposedParcel struct {
+	ID          string  `json:"id"`                    // Mã thửa đất dự kiến
+	Area        float64 `json:"area"`                  // Diện tích dự kiến (m²)
+	GeometryCID string  `json:"geometryCid,omitempty"` // IPFS CID của geometry (nếu có)
+}
+
+// BoundingBox khung bao hình chữ nhật của thửa đất theo tọa độ phẳng VN-2000 (mét)
+type BoundingBox struct {
+	MinX float64 `json:"minX"`
+	MinY float64 `json:"minY"`
+	MaxX float64 `json:"maxX"`
+	MaxY float64 `json:"maxY"`
+}
+
+// SplitPayload dữ liệu giao dịch tách thửa
+type SplitPayload struct {
+	ProposedParcels []ProposedParcel `json:"proposedParcels,omitempty"` // Các thửa đất đề xuất
+}
+
+// SplitValidationIssue một vấn đề phát hiện khi thẩm định kết quả tách thửa
+type SplitValidationIssue struct {
+	ParcelID string `json:"parcelId,omitempty"` // Thửa đất liên quan (trống nếu là vấn đề chung)
+	Code     string `json:"code"`               // Mã vấn đề (DUPLICATE_ID, ID_EXISTS, AREA_MISMATCH, ...)
+	Message  string `json:"message"`            // Mô tả chi tiết
+}
+
+// SplitValidationReport báo cáo thẩm định kết quả tách thửa, liệt kê toàn bộ vấn đề phát hiện được
+type SplitValidationReport struct {
+	TxID          string                 `json:"txId"`          // Mã giao dịch tách thửa
+	LandParcelID  string                 `json:"landParcelId"`  // Thửa đất gốc
+	ParcelIDs     []string               `json:"parcelIds"`     // Các thửa đất sau tách
+	OriginalArea  float64                `json:"originalArea"`  // Diện tích thửa gốc (m²)
+	TotalArea     float64                `json:"totalArea"`     // Tổng diện tích các thửa sau tách (m²)
+	AreaTolerance float64                `json:"areaTolerance"` // Sai số diện tích cho phép (m²)
+	Valid         bool                   `json:"valid"`         // true nếu không có vấn đề nào
+	Issues        []SplitValidationIssue `json:"issues"`        // Danh sách vấn đề
+}
+
+// MergePayload dữ liệu giao dịch hợp thửa
+type MergePayload struct {
+	SelectedLandID   string                     `json:"selectedLandId"`             // Thửa đất được giữ lại làm thửa hợp nhất
+	TargetPurpose    string                     `json:"targetPurpose,omitempty"`    // Mục đích sử dụng của thửa hợp nhất
+	PurposeChange    bool                       `json:"purposeChange,omitempty"`    // Hợp thửa kèm chuyển mục đích sử dụng của ít nhất một thửa
+	ParcelGeometries map[string]json.RawMessage `json:"parcelGeometries,omitempty"` // GeoJSON của từng thửa gốc, dùng kiểm tra liền kề
+}
+
+// ChangePurposePayload dữ liệu giao dịch thay đổi mục đích sử dụng
+type ChangePurposePayload struct {
+	CurrentPurpose string `json:"currentPurpose"` // Mục đích sử dụng hiện tại
+	NewPurpose     string `json:"newPurpose"`     // Mục đích sử dụng mới
+}
+
+// ReissuePayload dữ liệu giao dịch cấp lại giấy chứng nhận
+type ReissuePayload struct {
+	Reason string `json:"reason"` // Lý do cấp lại
+}
+
+// InheritancePayload dữ liệu giao dịch thừa kế quyền sử dụng đất
+type InheritancePayload struct {
+	DeceasedOwnerID string        `json:"deceasedOwnerId"` // Mã định danh người để lại di sản
+	Heirs           []LandCoOwner `json:"heirs"`           // Người thừa kế và phần được hưởng trong phần sở hữu của người để lại di sản
+}
+
+// GiftPayload dữ liệu giao dịch tặng cho quyền sử dụng đất
+type GiftPayload struct {
+	RecipientID  string `json:"recipientId"`  // Mã định danh người nhận tặng cho
+	Relationship string `json:"relationship"` // Quan hệ giữa bên tặng cho và bên nhận (mã trong danh mục quan hệ)
+	TaxExempt    bool   `json:"taxExempt"`    // Đề nghị miễn thuế thu nhập cá nhân, lệ phí trước bạ
 }
@@ -26,14 +26,6 @@ var validDocumentTypes = map[string]bool{
 	"OTHER":         true, // Khác
 }
 
-var requiredDocuments = map[string][]string{
-	"TRANSFER":       {"Đơn đăng ký biến động đất đai, tài sản gắn liền với đất theo Mẫu số 09/ĐK", "Hợp đồng chuyển nhượng quyền sử dụng đất", "Giấy chứng nhận quyền sử dụng đất", "Mảnh trích đo bản đồ địa chính thửa đất", "Bản kê khai nộp thuế"},
-	"SPLIT":          {"Đơn đề nghị tách thửa đất, hợp thửa đất theo Mẫu số 21 ban hành kèm theo Nghị định số 151/2025/NĐ-CP", "Giấy chứng nhận quyền sử dụng đất", "Bản vẽ tách thửa đất, hợp thửa đất lập theo Mẫu số 22 ban hành kèm theo Nghị định số 151/2025/NĐ-CP"},
-	"MERGE":          {"Đơn đề nghị tách thửa đất, hợp thửa đất theo Mẫu số 21 ban hành kèm theo Nghị định số 151/2025/NĐ-CP", "Giấy chứng nhận quyền sử dụng đất", "Bản vẽ tách thửa đất, hợp thửa đất lập theo Mẫu số 22 ban hành kèm theo Nghị định số 151/2025/NĐ-CP"},
-	"CHANGE_PURPOSE": {"Đơn đăng ký biến động đất đai, tài sản gắn liền với đất theo Mẫu số 09/ĐK", "Giấy chứng nhận quyền sử dụng đất"},
-	"REISSUE":        {"Đơn đăng ký biến động đất đai, tài sản gắn liền với đất theo Mẫu số 18 ban hành kèm theo Nghị định số 151/2025/NĐ-CP", "Giấy chứng nhận quyền sử dụng đất", "Mảnh trích đo bản đồ địa chính thửa đất"},
-}
-
 // NormalizeIPFSHash chuẩn hóa input về CID thuần (không prefix ipfs:// hay /ipfs/ hoặc URL gateway)
 func NormalizeIPFSHash(input string) string {
     s := strings.TrimSpace(input)
@@ -67,6 +59,20 @@ func ValidateIPFSHash(hash string) error {
     return nil
 }
 
+// NormalizeSHA256 chuẩn hóa digest SHA-256 về dạng hex chữ thường
+func NormalizeSHA256(digest string) string {
+	return strings.ToLower(strings.TrimSpace(digest))
+}
+
+// ValidateSHA256 kiểm tra digest SHA-256 dạng hex (64 ký tự)
+func ValidateSHA256(digest string) error {
+	matched, _ := regexp.MatchString(`^[0-9a-f]{64}$`, NormalizeSHA256(digest))
+	if !matched {
+		return fmt.Errorf("SHA-256 %s không đúng định dạng (64 ký tự hex)", digest)
+	}
+	return nil
+}
+
 // GetTxTimestampAsTime lấy timestamp của transaction và chuyển đổi thành time.Time ở múi giờ Việt Nam (+07:00)
 func GetTxTimestampAsTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
 	timestamp, err := ctx.GetStub().GetTxTimestamp()
@@ -80,6 +86,22 @@ func GetTxTimestampAsTime(ctx contractapi.TransactionContextInterface) (time.Tim
 	return time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).In(loc), nil
 }
 
+// parseDate chuyển chuỗi ngày (RFC3339 hoặc YYYY-MM-DD theo giờ Việt Nam) thành time.Time
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	loc, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("lỗi khi tải múi giờ: %v", err)
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ngày %s không đúng định dạng (YYYY-MM-DD hoặc RFC3339)", value)
+	}
+	return t, nil
+}
+
 // ValidateLand kiểm tra tính hợp lệ của thửa đất
 func ValidateLand(ctx contractapi.TransactionContextInterface, land Land, isUpdate bool) error {
 	if land.ID == "" {
@@ -94,7 +116,7 @@ func ValidateLand(ctx contractapi.TransactionContextInterface, land Land, isUpda
 	if land.Location == "" {
 		return fmt.Errorf("vị trí thửa đất không được để trống")
 	}
-	// Removed landUsePurpose and legalStatus validation as requested
+	// Mục đích sử dụng được kiểm tra theo danh mục tại hàm gọi (xem ValidateLandUsePurpose)
 
 	// Validate IPFS documents if any
 	for _, docID := range land.DocumentIDs {
@@ -138,11 +160,9 @@ func ValidateDocumentType(docType string) error {
 	return nil
 }
 
-// ValidateLandUsePurpose function removed as requested
-
-// VerifyLandOwnership kiểm tra quyền sở hữu thửa đất
+// VerifyLandOwnership kiểm tra quyền sở hữu thửa đất (chủ sử dụng hoặc đồng sở hữu)
 func VerifyLandOwnership(ctx contractapi.TransactionContextInterface, landID, ownerID string) error {
-	data, err := ctx.GetStub().GetState(landID)
+	data, err := GetLandState(ctx, landID)
 	if err != nil {
 		return fmt.Errorf("lỗi khi kiểm tra thửa đất %s: %v", landID, err)
 	}
@@ -153,7 +173,7 @@ func VerifyLandOwnership(ctx contractapi.TransactionContextInterface, landID, ow
 	if err := json.Unmarshal(data, &land); err != nil {
 		return fmt.Errorf("lỗi khi giải mã thửa đất: %v", err)
 	}
-	if land.OwnerID != ownerID {
+	if !IsLandOwner(&land, ownerID) {
 		return fmt.Errorf("người dùng %s không sở hữu thửa đất %s", ownerID, landID)
 	}
 	return nil
@@ -161,7 +181,7 @@ func VerifyLandOwnership(ctx contractapi.TransactionContextInterface, landID, ow
 
 // VerifyLandLegalStatus kiểm tra trạng thái pháp lý của thửa đất
 func VerifyLandLegalStatus(ctx contractapi.TransactionContextInterface, landID string, restrictedStatuses []string) error {
-	data, err := ctx.GetStub().GetState(landID)
+	data, err := GetLandState(ctx, landID)
 	if err != nil {
 		return fmt.Errorf("lỗi khi kiểm tra thửa đất %s: %v", landID, err)
 	}
@@ -180,216 +200,9 @@ func VerifyLandLegalStatus(ctx contractapi.TransactionContextInterface, landID s
 	return nil
 }
 
-// CheckRequiredDocuments kiểm tra tài liệu bắt buộc theo loại giao dịch
-func CheckRequiredDocuments(ctx contractapi.TransactionContextInterface, txID, txType string) ([]string, error) {
-	requiredDocs, exists := requiredDocuments[txType]
-	if !exists {
-		return nil, fmt.Errorf("loại giao dịch %s không hợp lệ", txType)
-	}
-
-	// Get transaction to check its documents
-	tx, err := GetTransaction(ctx, txID)
-	if err != nil {
-		return nil, fmt.Errorf("lỗi khi truy vấn giao dịch %s: %v", txID, err)
-	}
-
-	// Nếu không có tài liệu nào được tải lên
-	if len(tx.DocumentIDs) == 0 {
-		return requiredDocs, nil
-	}
-
-	// Tạo query để tìm metadata của các tài liệu
-	var foundDocTypes []string
-	for _, docID := range tx.DocumentIDs {
-		if docID == "" {
-			continue
-		}
-
-		// Get document to check IPFS hash
-		doc, err := GetDocument(ctx, docID)
-		if err != nil {
-			continue
-		}
-
-		hash := doc.IPFSHash
-		if hash == "" {
-			continue
-		}
-
-		// Query để tìm document metadata theo hash
-		queryString := fmt.Sprintf(`{
-			"selector": {
-				"hash": "%s",
-				"txId": "%s"
-			}
-		}`, hash, txID)
-
-		resultsIterator, err := ctx.GetStub().GetQueryResult(queryString)
-		if err != nil {
-			// Nếu không tìm thấy metadata, bỏ qua hash này
-			continue
-		}
-		defer resultsIterator.Close()
-
-		for resultsIterator.HasNext() {
-			queryResponse, err := resultsIterator.Next()
-			if err != nil {
-				continue
-			}
-
-			var docData map[string]interface{}
-			if err := json.Unmarshal(queryResponse.Value, &docData); err != nil {
-				continue
-			}
-
-			// Kiểm tra xem có trường description hoặc name không
-			description := ""
-			if desc, ok := docData["description"].(string); ok {
-				description = desc
-			} else if name, ok := docData["name"].(string); ok {
-				description = name
-			} else if docType, ok := docData["type"].(string); ok {
-				description = docType
-			}
-
-			if description != "" {
-				foundDocTypes = append(foundDocTypes, description)
-			}
-		}
-	}
-
-	// Nếu không tìm thấy metadata, sử dụng logic đơn giản
-	if len(foundDocTypes) == 0 {
-		// Giả định rằng số lượng tài liệu tải lên tương ứng với yêu cầu
-		if len(tx.DocumentIDs) >= len(requiredDocs) {
-			return []string{}, nil // Đủ tài liệu
-		}
-		return requiredDocs, nil // Thiếu tài liệu
-	}
-
-	// Kiểm tra từng loại tài liệu bắt buộc
-	var missingDocs []string
-	for _, requiredDoc := range requiredDocs {
-		found := false
-
-		for _, foundDoc := range foundDocTypes {
-			// So sánh không phân biệt hoa thường và bỏ qua khoảng trắng
-			if matchDocument(requiredDoc, foundDoc) {
-				found = true
-				break
-			}
-		}
-
-		if !found {
-			missingDocs = append(missingDocs, requiredDoc)
-		}
-	}
-
-	return missingDocs, nil
-}
-
-// matchDocument kiểm tra xem tài liệu có khớp với yêu cầu không
-func matchDocument(required, found string) bool {
-	// Chuẩn hóa chuỗi: chuyển thành chữ thường và loại bỏ khoảng trắng
-	normalizeString := func(s string) string {
-		return strings.ToLower(strings.TrimSpace(s))
-	}
-
-	requiredNorm := normalizeString(required)
-	foundNorm := normalizeString(found)
-
-	// Kiểm tra khớp chính xác
-	if requiredNorm == foundNorm {
-		return true
-	}
-
-	// Kiểm tra chứa từ khóa chính
-	keywords := map[string][]string{
-		"hợp đồng chuyển nhượng":                {"hợp đồng", "chuyển nhượng", "hop dong", "chuyen nhuong"},
-		"giấy chứng nhận quyền sử dụng đất":                  {"giấy chứng nhận", "gcn", "chứng nhận", "quyền sử dụng", "certificate"},
-		"giấy tờ tùy thân":                      {"cccd", "cmnd", "tùy thân", "căn cước", "chứng minh"},
-		"đơn xin tách thửa":                     {"đơn", "tách", "thửa", "application", "split"},
-		"sơ đồ thửa đất":                        {"sơ đồ", "bản vẽ", "survey", "diagram"},
-		"đơn xin hợp thửa":                      {"hợp", "gộp", "merge", "nhập"},
-		"đơn xin thay đổi mục đích sử dụng đất": {"thay đổi", "mục đích", "chuyển đổi", "change"},
-		"đơn xin cấp lại gcn":                   {"cấp lại", "tái cấp", "reissue"},
-	}
-
-	// Tìm từ khóa tương ứng với tài liệu yêu cầu
-	var matchKeywords []string
-	for key, words := range keywords {
-		if strings.Contains(requiredNorm, key) || strings.Contains(key, requiredNorm) {
-			matchKeywords = words
-			break
-		}
-	}
-
-	// Kiểm tra xem tài liệu tìm thấy có chứa từ khóa không
-	if len(matchKeywords) > 0 {
-		for _, keyword := range matchKeywords {
-			if strings.Contains(foundNorm, keyword) {
-				return true
-			}
-		}
-	}
-
-	// Kiểm tra từ khóa chung
-	commonKeywords := []string{"đơn", "giấy", "chứng nhận", "hợp đồng", "sơ đồ"}
-	requiredHasCommon := false
-	foundHasCommon := false
-
-	for _, keyword := range commonKeywords {
-		if strings.Contains(requiredNorm, keyword) {
-			requiredHasCommon = true
-		}
-		if strings.Contains(foundNorm, keyword) {
-			foundHasCommon = true
-		}
-	}
-
-	// Nếu cả hai đều có từ khóa chung và có độ tương đồng cao
-	if requiredHasCommon && foundHasCommon {
-		return calculateSimilarity(requiredNorm, foundNorm) > 0.6
-	}
-
-	return false
-}
-
-// calculateSimilarity tính độ tương đồng giữa hai chuỗi
-func calculateSimilarity(s1, s2 string) float64 {
-	if s1 == s2 {
-		return 1.0
-	}
-
-	words1 := strings.Fields(s1)
-	words2 := strings.Fields(s2)
-
-	if len(words1) == 0 || len(words2) == 0 {
-		return 0.0
-	}
-
-	commonWords := 0
-	for _, w1 := range words1 {
-		for _, w2 := range words2 {
-			if w1 == w2 {
-				commonWords++
-				break
-			}
-		}
-	}
-
-	// Jaccard similarity
-	totalWords := len(words1) + len(words2) - commonWords
-	if totalWords == 0 {
-		return 0.0
-	}
-
-	return float64(commonWords) / float64(totalWords)
-}
-
 // GetTransaction lấy và giải mã giao dịch từ ledger
 func GetTransaction(ctx contractapi.TransactionContextInterface, txID string) (*Transaction, error) {
-	data, err := ctx.GetStub().GetState(txID)
+	data, err := GetTransactionState(ctx, txID)
 	if err != nil {
 		return nil, fmt.Errorf("lỗi khi truy vấn giao dịch %s: %v", txID, err)
 	}
@@ -414,7 +227,7 @@ func GetTransaction(ctx contractapi.TransactionContextInterface, txID string) (*
 
 // GetDocument lấy và giải mã tài liệu từ ledger (phiên bản đơn giản cho utils)
 func GetDocument(ctx contractapi.TransactionContextInterface, docID string) (*Document, error) {
-	data, err := ctx.GetStub().GetState(docID)
+	data, err := GetDocumentState(ctx, docID)
 	if err != nil {
 		return nil, fmt.Errorf("lỗi khi truy vấn tài liệu %s: %v", docID, err)
 	}
@@ -430,7 +243,7 @@ func GetDocument(ctx contractapi.TransactionContextInterface, docID string) (*Do
 
 // CheckLandExists kiểm tra sự tồn tại của thửa đất
 func CheckLandExists(ctx contractapi.TransactionContextInterface, landID string) (bool, error) {
-	data, err := ctx.GetStub().GetState(landID)
+	data, err := GetLandState(ctx, landID)
 	if err != nil {
 		return false, fmt.Errorf("lỗi khi kiểm tra thửa đất %s: %v", landID, err)
 	}
@@ -439,15 +252,24 @@ func CheckLandExists(ctx contractapi.TransactionContextInterface, landID string)
 
 // CheckTransactionExists kiểm tra sự tồn tại của giao dịch
 func CheckTransactionExists(ctx contractapi.TransactionContextInterface, txID string) (bool, error) {
-	data, err := ctx.GetStub().GetState(txID)
+	data, err := GetTransactionState(ctx, txID)
 	if err != nil {
 		return false, fmt.Errorf("lỗi khi kiểm tra giao dịch %s: %v", txID, err)
 	}
 	return data != nil, nil
 }
 
-// GetCallerID lấy CCCD của người gọi từ certificate attributes
+// GetCallerID lấy mã định danh băm của người gọi, dùng cho mọi dữ liệu công khai
 func GetCallerID(ctx contractapi.TransactionContextInterface) (string, error) {
+	cccd, err := GetCallerCCCD(ctx)
+	if err != nil {
+		return "", err
+	}
+	return SubjectRef(ctx, cccd)
+}
+
+// GetCallerCCCD lấy CCCD của người gọi từ certificate attributes (không ghi ra dữ liệu công khai)
+func GetCallerCCCD(ctx contractapi.TransactionContextInterface) (string, error) {
 	// Thử lấy CCCD từ certificate attributes trước
 	cccd, found, err := cid.GetAttributeValue(ctx.GetStub(), "cccd")
 	if err == nil && found {
@@ -501,6 +323,16 @@ func isNumeric(s string) bool {
 	return true
 }
 
+// containsString kiểm tra chuỗi có nằm trong danh sách không
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
 // GetCallerOrgMSP lấy MSP ID của tổ chức người gọi
 func GetCallerOrgMSP(ctx contractapi.TransactionContextInterface) (string, error) {
 	mspID, err := cid.GetMSPID(ctx.GetStub())
@@ -510,33 +342,6 @@ func GetCallerOrgMSP(ctx contractapi.TransactionContextInterface) (string, error
 	return mspID, nil
 }
 
-// RecordTransactionLog ghi nhật ký giao dịch
-func RecordTransactionLog(ctx contractapi.TransactionContextInterface, txID, action, userID, details string) error {
-	txTime, err := GetTxTimestampAsTime(ctx)
-	if err != nil {
-		return fmt.Errorf("lỗi khi lấy timestamp: %v", err)
-	}
-
-	// Create a transaction log entry
-	logTx := Transaction{
-		TxID:      fmt.Sprintf("LOG_%s_%s_%d", txID, action, txTime.UnixNano()),
-		Type:      "LOG",
-		UserID:    userID,
-		Details:   details,
-		CreatedAt: txTime,
-		UpdatedAt: txTime,
-	}
-
-	logJSON, err := json.Marshal(logTx)
-	if err != nil {
-		return fmt.Errorf("lỗi khi mã hóa nhật ký giao dịch: %v", err)
-	}
-	if err := ctx.GetStub().PutState(logTx.TxID, logJSON); err != nil {
-		return fmt.Errorf("lỗi khi lưu nhật ký giao dịch %s: %v", logTx.TxID, err)
-	}
-	return nil
-}
-
 // CheckOrganization kiểm tra quyền truy cập dựa trên tổ chức
 func CheckOrganization(ctx contractapi.TransactionContextInterface, allowedOrgs []string) error {
 	mspID, err := GetCallerOrgMSP(ctx)
@@ -618,10 +423,10 @@ func SetDocumentRejected(doc *Document, rejectedBy string, rejectedAt time.Time)
 }
 
 // SetDocumentPending - Đặt trạng thái tài liệu thành chờ xác thực
-func SetDocumentPending(doc *Document) {
+func SetDocumentPending(doc *Document, updatedAt time.Time) {
 	doc.Status = "PENDING"
 	doc.VerifiedBy = ""
 	doc.VerifiedAt = time.Time{}
-	doc.UpdatedAt = time.Now()
+	doc.UpdatedAt = updatedAt
 }
 
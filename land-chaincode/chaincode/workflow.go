@@ -0,0 +1,264 @@
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Trạng thái giao dịch
+const (
+	TxStatusPending             = "PENDING"
+	TxStatusConfirmed           = "CONFIRMED"
+	TxStatusVerified            = "VERIFIED"
+	TxStatusSupplementRequested = "SUPPLEMENT_REQUESTED"
+	TxStatusApproved            = "APPROVED"
+	TxStatusRejected            = "REJECTED"
+	TxStatusWithdrawn           = "WITHDRAWN" // Người tạo yêu cầu rút hồ sơ (trạng thái kết thúc)
+	TxStatusExpired             = "EXPIRED"   // Quá hạn xác nhận, bị hủy tự động (trạng thái kết thúc)
+)
+
+// Hành động trong quy trình xử lý giao dịch
+const (
+	ActionConfirm           = "CONFIRM"            // Người nhận chấp nhận chuyển nhượng
+	ActionDecline           = "DECLINE"            // Người nhận từ chối chuyển nhượng
+	ActionVerify            = "VERIFY"             // Org2 thẩm định đạt yêu cầu
+	ActionRequestSupplement = "REQUEST_SUPPLEMENT" // Org2 yêu cầu bổ sung hồ sơ
+	ActionRejectDossier     = "REJECT_DOSSIER"     // Org2 từ chối hồ sơ
+	ActionSubmitSupplement  = "SUBMIT_SUPPLEMENT"  // Công dân nộp tài liệu bổ sung
+	ActionApprove           = "APPROVE"            // Org1 phê duyệt
+	ActionReject            = "REJECT"             // Org1 từ chối
+	ActionConsent           = "CONSENT"            // Đồng sở hữu đồng ý (không đổi trạng thái)
+	ActionWithholdConsent   = "WITHHOLD_CONSENT"   // Đồng sở hữu không đồng ý
+	ActionWithdraw          = "WITHDRAW"           // Người tạo yêu cầu rút hồ sơ
+	ActionExpire            = "EXPIRE"             // Hủy tự động khi quá hạn xác nhận (SweepExpiredTransactions)
+)
+
+// Bên tham gia giao dịch được phép thực hiện hành động (áp dụng cho Org3)
+const (
+	PartyRequester   = "REQUESTER"   // Người tạo yêu cầu (FromOwnerID)
+	PartyRecipient   = "RECIPIENT"   // Người nhận (ToOwnerID)
+	PartyParticipant = "PARTICIPANT" // Người tạo yêu cầu hoặc người nhận
+	PartyCoOwner     = "CO_OWNER"    // Đồng sở hữu chưa cho ý kiến (RequiredConsents)
+	PartyInitiator   = "INITIATOR"   // Người đã tạo yêu cầu (UserID)
+)
+
+// WorkflowTransition định nghĩa một bước chuyển trạng thái hợp lệ
+type WorkflowTransition struct {
+	Action   string   `json:"action"`          // Hành động
+	From     string   `json:"from"`            // Trạng thái nguồn
+	To       string   `json:"to"`              // Trạng thái đích
+	Orgs     []string `json:"orgs"`            // Các MSP được phép thực hiện
+	Party    string   `json:"party,omitempty"` // Ràng buộc bên tham gia (cho Org3; INITIATOR áp dụng cho mọi tổ chức)
+	Function string   `json:"function"`        // Hàm chaincode tương ứng
+}
+
+// approveFunctions hàm phê duyệt của Org1 theo loại giao dịch
+var approveFunctions = map[string]string{
+	"TRANSFER":       "ApproveTransferTransaction",
+	"SPLIT":          "ApproveSplitTransaction",
+	"MERGE":          "ApproveMergeTransaction",
+	"CHANGE_PURPOSE": "ApproveChangePurposeTransaction",
+	"REISSUE":        "ApproveReissueTransaction",
+	"INHERITANCE":    "ApproveInheritanceTransaction",
+	"GIFT":           "ApproveGiftTransaction",
+}
+
+// requestCreatorOrgs các MSP được tạo yêu cầu theo loại giao dịch (mặc định chỉ Org3), cũng là các MSP được rút yêu cầu
+var requestCreatorOrgs = map[string][]string{
+	"INHERITANCE": {"Org2MSP", "Org3MSP"},
+}
+
+// creatorOrgs các MSP được tạo yêu cầu của loại giao dịch
+func creatorOrgs(txType string) []string {
+	if orgs, exists := requestCreatorOrgs[txType]; exists {
+		return orgs
+	}
+	return []string{"Org3MSP"}
+}
+
+// recipientConfirmTxTypes các loại giao dịch cần người nhận xác nhận (ConfirmTransfer) trước khi Org2 thẩm định
+var recipientConfirmTxTypes = map[string]bool{
+	"TRANSFER": true,
+	"GIFT":     true,
+}
+
+// workflowTransitions bảng chuyển trạng thái theo loại giao dịch
+var workflowTransitions = buildWorkflowTransitions()
+
+// buildWorkflowTransitions khai báo bảng chuyển trạng thái cho tất cả loại giao dịch
+func buildWorkflowTransitions() map[string][]WorkflowTransition {
+	table := map[string][]WorkflowTransition{}
+	for txType, approveFunction := range approveFunctions {
+		// Giao dịch chuyển nhượng, tặng cho cần người nhận xác nhận trước khi Org2 thẩm định
+		reviewFrom := TxStatusPending
+		if recipientConfirmTxTypes[txType] {
+			reviewFrom = TxStatusConfirmed
+		}
+
+		var transitions []WorkflowTransition
+		if recipientConfirmTxTypes[txType] {
+			transitions = append(transitions,
+				WorkflowTransition{Action: ActionConfirm, From: TxStatusPending, To: TxStatusConfirmed, Orgs: []string{"Org3MSP"}, Party: PartyRecipient, Function: "ConfirmTransfer"},
+				WorkflowTransition{Action: ActionDecline, From: TxStatusPending, To: TxStatusRejected, Orgs: []string{"Org3MSP"}, Party: PartyRecipient, Function: "ConfirmTransfer"},
+			)
+		}
+		// Đồng sở hữu cho ý kiến trước khi Org2 thẩm định
+		if consentRequiredTxTypes[txType] {
+			consentFrom := []string{TxStatusPending, TxStatusSupplementRequested}
+			if recipientConfirmTxTypes[txType] {
+				consentFrom = append(consentFrom, TxStatusConfirmed)
+			}
+			for _, from := range consentFrom {
+				transitions = append(transitions,
+					WorkflowTransition{Action: ActionConsent, From: from, To: from, Orgs: []string{"Org3MSP"}, Party: PartyCoOwner, Function: "ConsentToTransaction"},
+					WorkflowTransition{Action: ActionWithholdConsent, From: from, To: TxStatusRejected, Orgs: []string{"Org3MSP"}, Party: PartyCoOwner, Function: "ConsentToTransaction"},
+				)
+			}
+		}
+		transitions = append(transitions,
+			WorkflowTransition{Action: ActionVerify, From: reviewFrom, To: TxStatusVerified, Orgs: []string{"Org2MSP"}, Function: "ProcessTransaction"},
+			WorkflowTransition{Action: ActionRequestSupplement, From: reviewFrom, To: TxStatusSupplementRequested, Orgs: []string{"Org2MSP"}, Function: "ProcessTransaction"},
+			WorkflowTransition{Action: ActionRejectDossier, From: reviewFrom, To: TxStatusRejected, Orgs: []string{"Org2MSP"}, Function: "ProcessTransaction"},
+			// Sau khi bổ sung, hồ sơ quay lại bước thẩm định của Org2
+			WorkflowTransition{Action: ActionSubmitSupplement, From: TxStatusSupplementRequested, To: reviewFrom, Orgs: []string{"Org3MSP"}, Party: PartyParticipant, Function: "LinkDocumentToTransaction"},
+			WorkflowTransition{Action: ActionApprove, From: TxStatusVerified, To: TxStatusApproved, Orgs: []string{"Org1MSP"}, Function: approveFunction},
+			WorkflowTransition{Action: ActionReject, From: TxStatusVerified, To: TxStatusRejected, Orgs: []string{"Org1MSP"}, Function: "RejectTransaction"},
+		)
+		// Người tạo yêu cầu được rút hồ sơ khi hồ sơ chưa được Org2 thẩm định
+		withdrawFrom := []string{TxStatusPending, TxStatusSupplementRequested}
+		if recipientConfirmTxTypes[txType] {
+			withdrawFrom = append(withdrawFrom, TxStatusConfirmed)
+		}
+		for _, from := range withdrawFrom {
+			transitions = append(transitions,
+				WorkflowTransition{Action: ActionWithdraw, From: from, To: TxStatusWithdrawn, Orgs: creatorOrgs(txType), Party: PartyInitiator, Function: "WithdrawTransaction"},
+			)
+		}
+		// Người nhận không xác nhận trong thời hạn thì giao dịch bị hủy tự động
+		if recipientConfirmTxTypes[txType] {
+			transitions = append(transitions,
+				WorkflowTransition{Action: ActionExpire, From: TxStatusPending, To: TxStatusExpired, Orgs: []string{"Org1MSP", "Org2MSP"}, Function: "SweepExpiredTransactions"},
+			)
+		}
+		table[txType] = transitions
+	}
+	return table
+}
+
+// isPartyAllowed kiểm tra người gọi có đúng vai trò bên tham gia mà bước chuyển yêu cầu không
+func isPartyAllowed(tx *Transaction, party, userID string) bool {
+	switch party {
+	case "":
+		return true
+	case PartyRequester:
+		return tx.FromOwnerID == userID
+	case PartyRecipient:
+		return tx.ToOwnerID == userID
+	case PartyParticipant:
+		return tx.FromOwnerID == userID || tx.ToOwnerID == userID
+	case PartyCoOwner:
+		return containsString(tx.RequiredConsents, userID) && !hasGivenConsent(tx.Consents, userID)
+	case PartyInitiator:
+		return tx.UserID == userID
+	default:
+		return false
+	}
+}
+
+// partyApplies ràng buộc bên tham gia chỉ áp dụng cho Org3, riêng người tạo yêu cầu áp dụng cho mọi tổ chức
+// (yêu cầu do cán bộ Org2 tạo chỉ được rút bởi chính cán bộ đó)
+func partyApplies(mspID, party string) bool {
+	return mspID == "Org3MSP" || party == PartyInitiator
+}
+
+// ResolveTransition tìm và kiểm tra bước chuyển trạng thái cho hành động của người gọi
+func ResolveTransition(ctx contractapi.TransactionContextInterface, tx *Transaction, action string) (*WorkflowTransition, error) {
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transitions, exists := workflowTransitions[tx.Type]
+	if !exists {
+		return nil, fmt.Errorf("loại giao dịch %s không có quy trình xử lý", tx.Type)
+	}
+	for i := range transitions {
+		transition := transitions[i]
+		if transition.Action != action || transition.From != tx.Status {
+			continue
+		}
+		if !containsString(transition.Orgs, mspID) {
+			return nil, fmt.Errorf("tổ chức %s không được phép thực hiện %s với giao dịch %s", mspID, action, tx.TxID)
+		}
+		if partyApplies(mspID, transition.Party) && !isPartyAllowed(tx, transition.Party, userID) {
+			return nil, fmt.Errorf("người dùng %s không được phép thực hiện %s với giao dịch %s", userID, action, tx.TxID)
+		}
+		// Giao dịch chỉ được thẩm định và phê duyệt khi tất cả đồng sở hữu đã đồng ý
+		if action == ActionVerify || action == ActionApprove {
+			if err := VerifyOwnerConsents(tx); err != nil {
+				return nil, err
+			}
+		}
+		return &transition, nil
+	}
+	return nil, fmt.Errorf("giao dịch %s (%s) không thể thực hiện %s từ trạng thái %s", tx.TxID, tx.Type, action, tx.Status)
+}
+
+// ApplyTransition kiểm tra và áp dụng bước chuyển trạng thái cho giao dịch
+func ApplyTransition(ctx contractapi.TransactionContextInterface, tx *Transaction, action string) error {
+	transition, err := ResolveTransition(ctx, tx, action)
+	if err != nil {
+		return err
+	}
+	tx.Status = transition.To
+	return enterTransactionStage(ctx, tx)
+}
+
+// enterTransactionStage cập nhật thời hạn xử lý khi giao dịch chuyển trạng thái
+// và giải phóng khóa thửa đất khi giao dịch kết thúc (phê duyệt, từ chối, rút hồ sơ, hết hạn)
+func enterTransactionStage(ctx contractapi.TransactionContextInterface, tx *Transaction) error {
+	if err := scheduleStageDeadline(ctx, tx); err != nil {
+		return err
+	}
+	if containsString(openTxStatuses, tx.Status) {
+		return nil
+	}
+	return releaseParcelLocks(ctx, tx)
+}
+
+// GetAllowedActions - Truy vấn các hành động người gọi được phép thực hiện với giao dịch
+func (s *LandRegistryChaincode) GetAllowedActions(ctx contractapi.TransactionContextInterface, txID string) ([]WorkflowTransition, error) {
+	tx, err := s.QueryTransactionByID(ctx, txID)
+	if err != nil {
+		return nil, err
+	}
+	mspID, err := GetCallerOrgMSP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := GetCallerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := []WorkflowTransition{}
+	for _, transition := range workflowTransitions[tx.Type] {
+		// Hủy do quá hạn do hệ thống thực hiện, không phải hành động trên từng giao dịch
+		if transition.Action == ActionExpire {
+			continue
+		}
+		if transition.From != tx.Status || !containsString(transition.Orgs, mspID) {
+			continue
+		}
+		if partyApplies(mspID, transition.Party) && !isPartyAllowed(tx, transition.Party, userID) {
+			continue
+		}
+		allowed = append(allowed, transition)
+	}
+	return allowed, nil
+}
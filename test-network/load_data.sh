@@ -11,8 +11,18 @@ export CORE_PEER_ADDRESS=localhost:7051
 export CORE_PEER_TLS_ROOTCERT_FILE=${PWD}/organizations/peerOrganizations/org1.example.com/tlsca/tlsca.org1.example.com-cert.pem
 export CORE_PEER_TLS_ENABLED=true
 
+ORDERER_ARGS="-o localhost:7050 --ordererTLSHostnameOverride orderer.example.com --tls --cafile $PWD/organizations/ordererOrganizations/example.com/tlsca/tlsca.example.com-cert.pem"
+# Dữ liệu cá nhân chỉ được lưu trên peer của Org1 và Org2
+PEER_ARGS="--peerAddresses localhost:7051 --tlsRootCertFiles $PWD/organizations/peerOrganizations/org1.example.com/tlsca/tlsca.org1.example.com-cert.pem --peerAddresses localhost:9051 --tlsRootCertFiles $PWD/organizations/peerOrganizations/org2.example.com/tlsca/tlsca.org2.example.com-cert.pem"
+
+# Set personal data salt (once, 32 random bytes passed base64-encoded via transient map)
+echo "Setting personal data salt..."
+SALT=$(openssl rand -base64 32)
+peer chaincode invoke $ORDERER_ARGS -C mychannel -n land-cc $PEER_ARGS -c '{"function":"SetPersonalDataSalt","Args":[]}' --transient "{\"salt\":\"$SALT\"}"
+sleep 3
+
 # Load All Data
 echo "Loading all geometry data..."
-peer chaincode invoke -o localhost:7050 --ordererTLSHostnameOverride orderer.example.com --tls --cafile $PWD/organizations/ordererOrganizations/example.com/tlsca/tlsca.example.com-cert.pem -C mychannel -n land-cc --peerAddresses localhost:7051 --tlsRootCertFiles $PWD/organizations/peerOrganizations/org1.example.com/tlsca/tlsca.org1.example.com-cert.pem --peerAddresses localhost:9051 --tlsRootCertFiles $PWD/organizations/peerOrganizations/org2.example.com/tlsca/tlsca.org2.example.com-cert.pem --peerAddresses localhost:11051 --tlsRootCertFiles $PWD/organizations/peerOrganizations/org3.example.com/tlsca/tlsca.org3.example.com-cert.pem -c '{"function":"LoadAllData","Args":[]}'
+peer chaincode invoke $ORDERER_ARGS -C mychannel -n land-cc $PEER_ARGS -c '{"function":"LoadAllData","Args":[]}'
 
 echo "Data loading complete!"